@@ -53,7 +53,7 @@ func main() {
 
 	// Репозиторий, сервис, хендлеры
 	fileRepo := repository.NewFileRepository(db)
-	fileService := service.NewFileService(fileRepo, minioClient)
+	fileService := service.NewFileService(fileRepo, minioClient, cfg.FileGrantSecret)
 	r := router.NewRouter(fileService, cfg.JWTSecret)
 
 	// HTTP сервер
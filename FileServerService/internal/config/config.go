@@ -41,6 +41,9 @@ type Config struct {
 
 	JWTSecret string
 
+	// FileGrantSecret — общий с appointment_service секрет разрешений на чтение чужих файлов
+	FileGrantSecret string
+
 	// Опциональные таймауты
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
@@ -85,7 +88,8 @@ func Load() *Config {
 			UseSSL:    useSSL,
 		},
 
-		JWTSecret: getEnv("JWT_SECRET", "4324pkh23sk4jh342alhdlfl2sdjf"),
+		JWTSecret:       getEnv("JWT_SECRET", "4324pkh23sk4jh342alhdlfl2sdjf"),
+		FileGrantSecret: getEnv("FILE_GRANT_SECRET", ""),
 
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
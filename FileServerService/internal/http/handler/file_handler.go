@@ -3,6 +3,7 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -110,9 +111,8 @@ func (h *FileHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, err := h.Service.Get(r.Context(), id.String())
-	if err != nil {
-		http.Error(w, "file not found", http.StatusNotFound)
+	file, ok := h.readableFile(w, r, id.String())
+	if !ok {
 		return
 	}
 
@@ -195,10 +195,8 @@ func (h *FileHandler) PublicDownload(w http.ResponseWriter, r *http.Request) {
 func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	// Получаем файл по UUID
-	file, err := h.Service.Get(r.Context(), id)
-	if err != nil || file == nil {
-		http.Error(w, "file not found", http.StatusNotFound)
+	// Получаем файл по UUID с проверкой доступа
+	if _, ok := h.readableFile(w, r, id); !ok {
 		return
 	}
 
@@ -223,9 +221,8 @@ func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
 // Preview позволяет показать предварительный просмотр, например, изображений.
 func (h *FileHandler) Preview(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	file, err := h.Service.Get(r.Context(), id)
-	if err != nil || file == nil {
-		http.Error(w, "file not found", http.StatusNotFound)
+	file, ok := h.readableFile(w, r, id)
+	if !ok {
 		return
 	}
 
@@ -246,6 +243,30 @@ func (h *FileHandler) Preview(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// readableFile возвращает файл, если текущий пользователь может его читать: файл публичный,
+// свой или открыт разрешением из параметра grant (файлы, прикрепленные к записи на прием).
+// Иначе пишет ошибку в ответ.
+func (h *FileHandler) readableFile(w http.ResponseWriter, r *http.Request, id string) (*model.File, bool) {
+	userIDStr, _ := r.Context().Value(middleware.UserIDKey).(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	file, err := h.Service.GetForUser(r.Context(), id, userID, r.URL.Query().Get("grant"))
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "access forbidden", http.StatusForbidden)
+		} else {
+			http.Error(w, "file not found", http.StatusNotFound)
+		}
+		return nil, false
+	}
+
+	return file, true
+}
+
 // TogglePublic переключает флаг публичности файла.
 func (h *FileHandler) TogglePublic(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fileserver/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// grantAudience — получатель разрешений на чтение, выданных другими сервисами.
+const grantAudience = "fileserver"
+
+// grantClaims — разрешение читать чужой файл, подписанное общим секретом FILE_GRANT_SECRET.
+// Выдается appointment_service участнику приема на файлы, прикрепленные к записи.
+type grantClaims struct {
+	FileID    string `json:"file_id"`
+	OwnerID   string `json:"owner_id"`
+	GranteeID string `json:"grantee_id"`
+	jwt.RegisteredClaims
+}
+
+// GetForUser возвращает файл, если пользователь может его читать: файл публичный,
+// принадлежит пользователю или передано действующее разрешение (grant) на этот файл.
+func (s *fileService) GetForUser(ctx context.Context, id string, userID uuid.UUID, grant string) (*model.File, error) {
+	file, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if file.IsPublic || file.UserID == userID {
+		return file, nil
+	}
+
+	if grant != "" {
+		if err := s.checkGrant(grant, file, userID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrForbidden, err)
+		}
+		return file, nil
+	}
+
+	return nil, ErrForbidden
+}
+
+// checkGrant проверяет подпись и срок разрешения, и что оно выдано именно этому пользователю
+// на этот файл от имени его владельца.
+func (s *fileService) checkGrant(grant string, file *model.File, userID uuid.UUID) error {
+	if len(s.grantSecret) == 0 {
+		return errors.New("file grants are disabled")
+	}
+
+	var claims grantClaims
+	_, err := jwt.ParseWithClaims(grant, &claims, func(token *jwt.Token) (interface{}, error) {
+		return s.grantSecret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(grantAudience),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid grant: %w", err)
+	}
+	// Бессрочных разрешений не бывает: доступ действует, пока прием активен
+	if claims.ExpiresAt == nil {
+		return errors.New("grant has no expiration")
+	}
+
+	if claims.FileID != file.ID.String() || claims.GranteeID != userID.String() {
+		return errors.New("grant is issued for another file or user")
+	}
+	// Прикрепить к записи можно только свой файл: чужой файл разрешение не открывает
+	if claims.OwnerID != file.UserID.String() {
+		return errors.New("grant is not issued by the file owner")
+	}
+
+	return nil
+}
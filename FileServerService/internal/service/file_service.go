@@ -17,6 +17,7 @@ import (
 type FileService interface {
 	Upload(ctx context.Context, file *model.File, fileReader io.Reader, fileSize int64) error
 	Get(ctx context.Context, id string) (*model.File, error)
+	GetForUser(ctx context.Context, id string, userID uuid.UUID, grant string) (*model.File, error)
 	Delete(ctx context.Context, id string) error
 	ListByUser(ctx context.Context, userID string) ([]model.File, error)
 
@@ -30,11 +31,12 @@ type FileService interface {
 type fileService struct {
 	repo        repository.FileRepository
 	minioClient *storage.MinioClient
+	grantSecret []byte // секрет разрешений на чтение чужих файлов; пусто — разрешения не принимаются
 }
 
 // NewFileService создаёт новый экземпляр fileService.
-func NewFileService(repo repository.FileRepository, minioClient *storage.MinioClient) FileService {
-	return &fileService{repo: repo, minioClient: minioClient}
+func NewFileService(repo repository.FileRepository, minioClient *storage.MinioClient, grantSecret string) FileService {
+	return &fileService{repo: repo, minioClient: minioClient, grantSecret: []byte(grantSecret)}
 }
 
 // Upload сохраняет информацию о файле и присваивает ID и время загрузки.
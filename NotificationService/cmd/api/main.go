@@ -90,7 +90,7 @@ func main() {
 	logInfo("Successfully connected to database")
 
 	// 4. Выполняем автомиграции
-	err = db.AutoMigrate(&models.Notification{})
+	err = db.AutoMigrate(&models.Notification{}, &models.UserContact{})
 	if err != nil {
 		logError("Failed to run migrations", map[string]interface{}{
 			"error": err.Error(),
@@ -107,6 +107,17 @@ func main() {
 
 	// 6. Инициализация репозитория и сервиса
 	notifRepo := repository.NewGormNotificationRepository(db)
+	contactRepo := repository.NewGormContactRepository(db)
+
+	// Контакты приходят только из user.created; пользователи, зарегистрированные раньше,
+	// дополняются из таблицы users, иначе уведомления о записях им не доставляются
+	if added, err := contactRepo.Backfill(context.Background()); err != nil {
+		logError("Failed to backfill user contacts", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		logInfo("User contacts backfilled: %d added", added)
+	}
 	notifService := service.NewNotificationService(notifRepo, emailSender, telegramSender, codeGenerator, createServiceLogger())
 
 	// 7. Инициализация Echo и роутера
@@ -117,7 +128,7 @@ func main() {
 	router.SetupRoutes(e, notifService)
 
 	// Initialize and start RabbitMQ consumer
-	consumer, err := messaging.NewConsumer(cfg.RabbitMQ.URL, notifService, createMessagingLogger())
+	consumer, err := messaging.NewConsumer(cfg.RabbitMQ.URL, notifService, contactRepo, createMessagingLogger())
 	if err != nil {
 		logError("Failed to create RabbitMQ consumer", map[string]interface{}{
 			"error": err.Error(),
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserContact — контактные данные пользователя, собираемые из событий user.created.
+// Нужны, чтобы доставлять уведомления по событиям, в которых есть только ID пользователя
+type UserContact struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	Email     string    `gorm:"type:varchar(255);not null" json:"email"`
+	Role      string    `gorm:"type:varchar(50)" json:"role"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName указывает GORM использовать имя таблицы "user_contacts"
+func (UserContact) TableName() string {
+	return "user_contacts"
+}
@@ -28,6 +28,7 @@ const (
 	AppointmentConfirmed   NotificationType = "appointment.confirmed"
 	AppointmentNew         NotificationType = "appointment.new"
 	AppointmentRescheduled NotificationType = "appointment.rescheduled"
+	AppointmentCompleted   NotificationType = "appointment.completed"
 
 	// 👤 User/Profile
 	UserRegistered      NotificationType = "user.registered"
@@ -102,6 +103,13 @@ type AppointmentMetadata struct {
 	DateTime      time.Time `json:"date_time"`
 	Duration      int       `json:"duration_minutes"`
 	Specialty     string    `json:"specialty,omitempty"`
+	// За сколько минут до приема отправлено напоминание
+	ReminderOffset int `json:"reminder_offset_minutes,omitempty"`
+	// Причина отмены и свободные слоты, предложенные взамен
+	CancelReason     string      `json:"cancel_reason,omitempty"`
+	AlternativeSlots []time.Time `json:"alternative_slots,omitempty"`
+	// Часовой пояс врача (IANA), в котором показывается время; пусто - UTC
+	Timezone string `json:"timezone,omitempty"`
 }
 
 // SystemMetadata — метаданные для системных уведомлений
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"NotificationService/internal/domain/models"
+
+	"github.com/google/uuid"
+)
+
+// ContactRepository определяет методы для работы с контактами пользователей
+type ContactRepository interface {
+	Upsert(ctx context.Context, contact *models.UserContact) error
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*models.UserContact, error)
+	// Backfill дополняет контакты пользователями, зарегистрированными до появления user_contacts
+	Backfill(ctx context.Context) (int64, error)
+}
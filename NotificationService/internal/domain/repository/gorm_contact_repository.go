@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"NotificationService/internal/domain/models"
+)
+
+// ErrContactNotFound возвращается, если контакт пользователя неизвестен
+var ErrContactNotFound = errors.New("contact not found")
+
+// GormContactRepository - реализация ContactRepository с использованием GORM
+type GormContactRepository struct {
+	db *gorm.DB
+}
+
+// NewGormContactRepository создает новый репозиторий контактов с GORM
+func NewGormContactRepository(db *gorm.DB) ContactRepository {
+	return &GormContactRepository{db: db}
+}
+
+func (r *GormContactRepository) Upsert(ctx context.Context, contact *models.UserContact) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"email", "role", "updated_at"}),
+		}).
+		Create(contact).Error
+}
+
+func (r *GormContactRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.UserContact, error) {
+	var contact models.UserContact
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&contact).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// Backfill копирует в user_contacts пользователей из таблицы users identity_service
+// (общая база). Уже известные контакты не меняются, повторный запуск ничего не добавляет
+func (r *GormContactRepository) Backfill(ctx context.Context) (int64, error) {
+	var usersTable *string
+	if err := r.db.WithContext(ctx).Raw("SELECT to_regclass('users')::text").Scan(&usersTable).Error; err != nil {
+		return 0, err
+	}
+	// identity_service еще не создал свою таблицу - дополнять нечем
+	if usersTable == nil {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO user_contacts (user_id, email, role, updated_at)
+		SELECT id, email, role, NOW() FROM users
+		WHERE email IS NOT NULL AND email <> ''
+		ON CONFLICT (user_id) DO NOTHING`)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,160 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"NotificationService/internal/domain/models"
+	"NotificationService/internal/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// AppointmentEvent - событие записи, публикуемое appointment_service
+type AppointmentEvent struct {
+	EventType       string     `json:"event_type"`
+	AppointmentID   uuid.UUID  `json:"appointment_id"`
+	DoctorID        uuid.UUID  `json:"doctor_id"`
+	PatientID       *uuid.UUID `json:"patient_id,omitempty"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	Title           string     `json:"title"`
+	Status          string     `json:"status"`
+	AppointmentType string     `json:"appointment_type"`
+	MeetingLink     *string    `json:"meeting_link,omitempty"`
+
+	PreviousAppointmentID *uuid.UUID `json:"previous_appointment_id,omitempty"`
+	PreviousStartTime     *time.Time `json:"previous_start_time,omitempty"`
+	PreviousEndTime       *time.Time `json:"previous_end_time,omitempty"`
+
+	CanceledByRole   string       `json:"canceled_by_role,omitempty"`
+	CancelReason     string       `json:"cancel_reason,omitempty"`
+	AlternativeSlots []SlotOption `json:"alternative_slots,omitempty"`
+
+	ReminderOffsetMinutes int `json:"reminder_offset_minutes,omitempty"`
+
+	// Recipient - кому адресовано событие: "patient", "doctor"; пусто - пациенту и врачу
+	Recipient string `json:"recipient,omitempty"`
+	// Timezone - часовой пояс врача, в котором показывается время приема
+	Timezone string `json:"timezone,omitempty"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Получатели события appointment_service
+const (
+	recipientPatient = "patient"
+	recipientDoctor  = "doctor"
+)
+
+// SlotOption - свободный слот, предложенный пациенту взамен отмененной записи
+type SlotOption struct {
+	ID        uuid.UUID `json:"id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+func (c *Consumer) handleAppointmentBooked(ctx context.Context, body []byte) {
+	// Пациент получает подтверждение, врач - уведомление о новой записи
+	c.handleAppointmentEvent(ctx, body, models.AppointmentBooked, models.AppointmentNew)
+}
+
+func (c *Consumer) handleAppointmentCanceled(ctx context.Context, body []byte) {
+	c.handleAppointmentEvent(ctx, body, models.AppointmentCanceled, models.AppointmentCanceled)
+}
+
+func (c *Consumer) handleAppointmentRescheduled(ctx context.Context, body []byte) {
+	c.handleAppointmentEvent(ctx, body, models.AppointmentRescheduled, models.AppointmentRescheduled)
+}
+
+func (c *Consumer) handleAppointmentCompleted(ctx context.Context, body []byte) {
+	// Прием завершает сам врач, поэтому уведомляется только пациент
+	c.handleAppointmentEvent(ctx, body, models.AppointmentCompleted, "")
+}
+
+func (c *Consumer) handleAppointmentReminder(ctx context.Context, body []byte) {
+	c.handleAppointmentEvent(ctx, body, models.AppointmentReminder, models.AppointmentReminder)
+}
+
+// handleAppointmentEvent создает уведомления для пациента и врача по событию записи;
+// пустой doctorType - врачу уведомление не нужно
+func (c *Consumer) handleAppointmentEvent(ctx context.Context, body []byte, patientType, doctorType models.NotificationType) {
+	var event AppointmentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.logger.Error("failed to unmarshal appointment event", "error", err)
+		return
+	}
+
+	c.logger.Info("processing appointment event",
+		"eventType", event.EventType,
+		"appointmentID", event.AppointmentID.String(),
+		"doctorID", event.DoctorID.String(),
+	)
+
+	metadata := &models.AppointmentMetadata{
+		AppointmentID:  event.AppointmentID,
+		DateTime:       event.StartTime,
+		Duration:       int(event.EndTime.Sub(event.StartTime).Minutes()),
+		ReminderOffset: event.ReminderOffsetMinutes,
+		CancelReason:   event.CancelReason,
+		Timezone:       event.Timezone,
+	}
+	for _, slot := range event.AlternativeSlots {
+		metadata.AlternativeSlots = append(metadata.AlternativeSlots, slot.StartTime)
+	}
+
+	if event.Recipient != recipientDoctor {
+		if event.PatientID != nil {
+			c.notifyAppointmentParticipant(ctx, *event.PatientID, patientType, metadata)
+		} else {
+			c.logger.Sugar().Warnw("appointment event without patient; patient notification skipped",
+				"eventType", event.EventType, "appointmentID", event.AppointmentID.String())
+		}
+	}
+
+	// Врач, отменивший запись сам, уведомление об этом не получает;
+	// напоминания группового занятия врачу приходят отдельным событием - одно на занятие
+	if doctorType == "" || event.CanceledByRole == "doctor" || event.Recipient == recipientPatient {
+		return
+	}
+
+	c.notifyAppointmentParticipant(ctx, event.DoctorID, doctorType, metadata)
+}
+
+// notifyAppointmentParticipant отправляет уведомление участнику записи по его сохраненному контакту
+func (c *Consumer) notifyAppointmentParticipant(ctx context.Context, userID uuid.UUID, notificationType models.NotificationType, metadata *models.AppointmentMetadata) {
+	contact, err := c.contacts.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrContactNotFound) {
+			c.logger.Sugar().Warnw("no contact for user; appointment notification skipped",
+				"userID", userID.String(), "type", string(notificationType))
+			return
+		}
+		c.logger.Error("failed to get user contact", "userID", userID.String(), "error", err)
+		return
+	}
+
+	notification := &models.Notification{
+		Type:        notificationType,
+		Channel:     models.ChannelEmail,
+		RecipientID: userID,
+		Recipient:   contact.Email,
+		// Message will be auto-generated by enrichMessage
+	}
+	if err := notification.SetMetadata(metadata); err != nil {
+		c.logger.Error("failed to set appointment metadata", "userID", userID.String(), "error", err)
+		return
+	}
+
+	if err := c.notificationSvc.Send(ctx, notification); err != nil {
+		c.logger.Error("failed to send appointment notification",
+			"userID", userID.String(), "type", string(notificationType), "error", err)
+		return
+	}
+
+	c.logger.Info("appointment notification sent",
+		"userID", userID.String(), "type", string(notificationType),
+		"appointmentID", metadata.AppointmentID.String())
+}
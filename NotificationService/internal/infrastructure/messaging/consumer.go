@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"NotificationService/internal/domain/models"
+	"NotificationService/internal/domain/repository"
 	"NotificationService/internal/service"
 
 	"github.com/google/uuid"
@@ -28,6 +30,14 @@ type SugarInterface interface {
 	Warnw(msg string, keysAndValues ...interface{})
 }
 
+// HandlerFunc - обработчик сообщения с определенным routing key
+type HandlerFunc func(ctx context.Context, body []byte)
+
+const (
+	exchangeName = "vitalem"
+	queueName    = "notification.user.events"
+)
+
 type UserCreatedEvent struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
@@ -38,10 +48,12 @@ type Consumer struct {
 	conn            *amqp.Connection
 	channel         *amqp.Channel
 	notificationSvc service.NotificationService
+	contacts        repository.ContactRepository
+	handlers        map[string]HandlerFunc
 	logger          LoggerInterface
 }
 
-func NewConsumer(rabbitMQURL string, notificationSvc service.NotificationService, logger LoggerInterface) (*Consumer, error) {
+func NewConsumer(rabbitMQURL string, notificationSvc service.NotificationService, contacts repository.ContactRepository, logger LoggerInterface) (*Consumer, error) {
 	conn, err := amqp.Dial(rabbitMQURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
@@ -55,13 +67,13 @@ func NewConsumer(rabbitMQURL string, notificationSvc service.NotificationService
 
 	// Declare exchange
 	err = channel.ExchangeDeclare(
-		"vitalem", // name
-		"topic",   // type
-		true,      // durable
-		false,     // auto-deleted
-		false,     // internal
-		false,     // no-wait
-		nil,       // arguments
+		exchangeName, // name
+		"topic",      // type
+		true,         // durable
+		false,        // auto-deleted
+		false,        // internal
+		false,        // no-wait
+		nil,          // arguments
 	)
 	if err != nil {
 		channel.Close()
@@ -69,14 +81,14 @@ func NewConsumer(rabbitMQURL string, notificationSvc service.NotificationService
 		return nil, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
-	// Declare queue for user events
-	queue, err := channel.QueueDeclare(
-		"notification.user.events", // name
-		true,                       // durable
-		false,                      // delete when unused
-		false,                      // exclusive
-		false,                      // no-wait
-		nil,                        // arguments
+	// Declare queue for user and appointment events
+	_, err = channel.QueueDeclare(
+		queueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		nil,       // arguments
 	)
 	if err != nil {
 		channel.Close()
@@ -84,49 +96,76 @@ func NewConsumer(rabbitMQURL string, notificationSvc service.NotificationService
 		return nil, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
-	// Bind queue to exchange
-	err = channel.QueueBind(
-		queue.Name,     // queue name
-		"user.created", // routing key
-		"vitalem",      // exchange
+	c := &Consumer{
+		conn:            conn,
+		channel:         channel,
+		notificationSvc: notificationSvc,
+		contacts:        contacts,
+		handlers:        make(map[string]HandlerFunc),
+		logger:          logger,
+	}
+
+	// Register handlers; each routing key is bound to the queue
+	handlers := map[string]HandlerFunc{
+		"user.created":            c.handleUserCreatedEvent,
+		"appointment.booked":      c.handleAppointmentBooked,
+		"appointment.canceled":    c.handleAppointmentCanceled,
+		"appointment.rescheduled": c.handleAppointmentRescheduled,
+		"appointment.completed":   c.handleAppointmentCompleted,
+		"appointment.reminder":    c.handleAppointmentReminder,
+	}
+	for routingKey, handler := range handlers {
+		if err := c.Handle(routingKey, handler); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Handle регистрирует обработчик для routing key и привязывает его к очереди
+func (c *Consumer) Handle(routingKey string, handler HandlerFunc) error {
+	err := c.channel.QueueBind(
+		queueName,    // queue name
+		routingKey,   // routing key
+		exchangeName, // exchange
 		false,
 		nil,
 	)
 	if err != nil {
-		channel.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to bind queue: %w", err)
+		return fmt.Errorf("failed to bind queue to %s: %w", routingKey, err)
 	}
 
-	return &Consumer{
-		conn:            conn,
-		channel:         channel,
-		notificationSvc: notificationSvc,
-		logger:          logger,
-	}, nil
+	c.handlers[routingKey] = handler
+	return nil
 }
 
 func (c *Consumer) StartConsumer(ctx context.Context) error {
 	msgs, err := c.channel.Consume(
-		"notification.user.events", // queue
-		"",                         // consumer
-		true,                       // auto-ack
-		false,                      // exclusive
-		false,                      // no-local
-		false,                      // no-wait
-		nil,                        // args
+		queueName, // queue
+		"",        // consumer
+		true,      // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // args
 	)
 	if err != nil {
 		return fmt.Errorf("failed to register consumer: %w", err)
 	}
 
-	c.logger.Info("Started RabbitMQ consumer for user events")
+	c.logger.Info("Started RabbitMQ consumer", "queue", queueName, "handlers", len(c.handlers))
 
 	go func() {
 		for {
 			select {
-			case msg := <-msgs:
-				c.handleUserCreatedEvent(ctx, msg.Body)
+			case msg, ok := <-msgs:
+				if !ok {
+					c.logger.Info("Consumer delivery channel closed")
+					return
+				}
+				c.dispatch(ctx, msg)
 			case <-ctx.Done():
 				c.logger.Info("Consumer context cancelled")
 				return
@@ -137,6 +176,16 @@ func (c *Consumer) StartConsumer(ctx context.Context) error {
 	return nil
 }
 
+// dispatch передает сообщение обработчику, зарегистрированному для его routing key
+func (c *Consumer) dispatch(ctx context.Context, msg amqp.Delivery) {
+	handler, ok := c.handlers[msg.RoutingKey]
+	if !ok {
+		c.logger.Sugar().Warnw("no handler for routing key; message skipped", "routingKey", msg.RoutingKey)
+		return
+	}
+	handler(ctx, msg.Body)
+}
+
 func (c *Consumer) handleUserCreatedEvent(ctx context.Context, body []byte) {
 	var event UserCreatedEvent
 	if err := json.Unmarshal(body, &event); err != nil {
@@ -153,6 +202,17 @@ func (c *Consumer) handleUserCreatedEvent(ctx context.Context, body []byte) {
 		return
 	}
 
+	// Remember contact so later events (appointments) can be delivered by user ID
+	contact := &models.UserContact{
+		UserID:    userID,
+		Email:     event.Email,
+		Role:      event.Role,
+		UpdatedAt: time.Now(),
+	}
+	if err := c.contacts.Upsert(ctx, contact); err != nil {
+		c.logger.Error("failed to save user contact", "userID", event.UserID, "error", err)
+	}
+
 	// Create welcome notification
 	notification := &models.Notification{
 		Type:        models.UserRegistered,
@@ -161,6 +221,11 @@ func (c *Consumer) handleUserCreatedEvent(ctx context.Context, body []byte) {
 		Recipient:   event.Email,
 		// Message will be auto-generated by enrichMessage
 	}
+	notification.SetMetadata(&models.UserMetadata{
+		UserID: userID,
+		Email:  event.Email,
+		Role:   event.Role,
+	})
 
 	if err := c.notificationSvc.Send(ctx, notification); err != nil {
 		c.logger.Error("failed to send welcome notification", "userID", event.UserID, "error", err)
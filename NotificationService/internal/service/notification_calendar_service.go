@@ -1,6 +1,12 @@
 package service
 
-import "NotificationService/internal/domain/models"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"NotificationService/internal/domain/models"
+)
 
 type CalendarNotificationService struct{}
 
@@ -11,7 +17,10 @@ func NewCalendarNotificationService() *CalendarNotificationService {
 func (s *CalendarNotificationService) Enrich(notification *models.Notification) {
 	switch notification.Type {
 	case models.AppointmentReminder:
-		notification.Message = "Напоминание о приеме через 1 час."
+		notification.Message = "Напоминание о предстоящем приеме."
+		if meta, err := notification.GetAppointmentMetadata(); err == nil && meta != nil && meta.ReminderOffset > 0 {
+			notification.Message = fmt.Sprintf("Напоминание: прием через %s.", formatReminderOffset(meta.ReminderOffset))
+		}
 
 	case models.AppointmentNew:
 		notification.Message = "У вас новая запись на прием."
@@ -24,5 +33,70 @@ func (s *CalendarNotificationService) Enrich(notification *models.Notification)
 
 	case models.AppointmentCanceled:
 		notification.Message = "Ваша запись была отменена."
+
+	case models.AppointmentCompleted:
+		notification.Message = "Ваш прием завершен. Спасибо, что выбрали Vitalem."
+
+	default:
+		return
+	}
+
+	meta, err := notification.GetAppointmentMetadata()
+	if err != nil || meta == nil {
+		return
+	}
+
+	location := appointmentLocation(meta.Timezone)
+
+	// Добавляем время приема, если оно известно
+	if !meta.DateTime.IsZero() {
+		notification.Message += " Дата и время приема: " + formatAppointmentTime(meta.DateTime, location) + "."
+	}
+
+	if meta.CancelReason != "" {
+		notification.Message += " Причина: " + meta.CancelReason + "."
+	}
+
+	// Предлагаем свободное время у того же врача
+	if len(meta.AlternativeSlots) > 0 {
+		slots := make([]string, len(meta.AlternativeSlots))
+		for i, slot := range meta.AlternativeSlots {
+			slots[i] = formatAppointmentTime(slot, location)
+		}
+		notification.Message += " Свободное время у врача: " + strings.Join(slots, ", ") + "."
+	}
+}
+
+// appointmentLocation - часовой пояс из события записи; неизвестный или пустой - UTC
+func appointmentLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// formatAppointmentTime - время приема в поясе врача; для UTC пояс указывается явно
+func formatAppointmentTime(t time.Time, location *time.Location) string {
+	formatted := t.In(location).Format("02.01.2006 15:04")
+	if location == time.UTC {
+		formatted += " (UTC)"
+	}
+	return formatted
+}
+
+// formatReminderOffset - человекочитаемое смещение напоминания: "24 ч", "1 ч 30 мин", "15 мин"
+func formatReminderOffset(minutes int) string {
+	hours, rest := minutes/60, minutes%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%d мин", rest)
+	case rest == 0:
+		return fmt.Sprintf("%d ч", hours)
+	default:
+		return fmt.Sprintf("%d ч %d мин", hours, rest)
 	}
 }
@@ -140,7 +140,13 @@ func (s *notificationService) enrichMessage(notification *models.Notification) {
 	switch {
 	case strings.HasPrefix(typ, "user."):
 		s.identity.Enrich(notification)
-	case strings.HasPrefix(typ, "appointment."), strings.HasPrefix(typ, "lab."), strings.HasPrefix(typ, "patient."):
+	case strings.HasPrefix(typ, "appointment."):
+		// Календарные тексты учитывают время приема; остальные типы записей - у сервиса пациента
+		s.calendar.Enrich(notification)
+		if notification.Message == "" {
+			s.patient.Enrich(notification)
+		}
+	case strings.HasPrefix(typ, "lab."), strings.HasPrefix(typ, "patient."):
 		s.patient.Enrich(notification)
 	case strings.HasPrefix(typ, "specialist."):
 		s.specialist.Enrich(notification)
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -8,14 +10,20 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // База часовых поясов внутри бинарника: в контейнере ее может не быть
 
 	"github.com/labstack/echo/v4"
 	"github.com/printprince/vitalem/appointment_service/internal/config"
 	"github.com/printprince/vitalem/appointment_service/internal/database"
+	"github.com/printprince/vitalem/appointment_service/internal/files"
 	"github.com/printprince/vitalem/appointment_service/internal/handlers"
+	"github.com/printprince/vitalem/appointment_service/internal/meeting"
+	"github.com/printprince/vitalem/appointment_service/internal/payment"
 	"github.com/printprince/vitalem/appointment_service/internal/repository"
 	"github.com/printprince/vitalem/appointment_service/internal/router"
 	"github.com/printprince/vitalem/appointment_service/internal/service"
+	"github.com/printprince/vitalem/appointment_service/internal/specialist"
+	"github.com/printprince/vitalem/appointment_service/migrations"
 	"github.com/printprince/vitalem/logger_service/pkg/logger"
 )
 
@@ -60,27 +68,172 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Выполнение миграций
-	if err := database.RunMigrations(db); err != nil {
-		logError("Failed to run migrations", map[string]interface{}{
-			"error": err.Error(),
-		})
-		log.Fatalf("Failed to run migrations: %v", err)
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
 	}
 
-	// Создание индексов
-	if err := database.CreateIndexes(db); err != nil {
-		logError("Failed to create indexes", map[string]interface{}{
+	// ./appointment_service migrate up|down|status - управление схемой без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(migrator, os.Args[2:]); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	}
+
+	// Схема новее бинарника - старый код не должен работать с ней
+	if err := migrator.CheckVersion(); err != nil {
+		logError("Database schema check failed", map[string]interface{}{
 			"error": err.Error(),
 		})
-		log.Printf("Failed to create indexes: %v", err)
+		log.Fatalf("Database schema check failed: %v", err)
+	}
+
+	// Выполнение миграций
+	if cfg.Database.AutoMigrate {
+		if _, err := migrator.Up(); err != nil {
+			logError("Failed to run migrations", map[string]interface{}{
+				"error": err.Error(),
+			})
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	} else {
+		pending, err := migrator.Pending()
+		if err != nil {
+			log.Fatalf("Failed to check migrations: %v", err)
+		}
+		if len(pending) > 0 {
+			log.Fatalf("Database schema is behind this binary by %d migration(s), run: migrate up", len(pending))
+		}
 	}
 
 	// Инициализация слоев
 	repo := repository.NewAppointmentRepository(db)
-	svc := service.NewAppointmentService(repo, loggerClient)
+
+	// Подключаем RabbitMQ если настроен
+	var messageService service.MessageService
+	if cfg.RabbitMQ.Host != "" {
+		rabbitMQURL := fmt.Sprintf("amqp://%s:%s@%s:%s/",
+			cfg.RabbitMQ.User,
+			cfg.RabbitMQ.Password,
+			cfg.RabbitMQ.Host,
+			cfg.RabbitMQ.Port,
+		)
+
+		messageService, err = service.NewMessageService(rabbitMQURL, cfg.RabbitMQ.Exchange, loggerClient)
+		if err != nil {
+			logError("Failed to connect to RabbitMQ, running without events", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			defer messageService.Close()
+			logInfo("RabbitMQ connected, exchange: %s", cfg.RabbitMQ.Exchange)
+		}
+	} else {
+		logInfo("RabbitMQ is not configured, running without events")
+	}
+
+	// Напоминания о приемах отправляются событиями, поэтому без RabbitMQ не запускаются
+	if cfg.Reminders.Enabled && messageService != nil && len(cfg.Reminders.Offsets) > 0 {
+		reminders := service.NewReminderService(repo, messageService, loggerClient, cfg.Reminders.Offsets, cfg.Reminders.Interval, cfg.Appointments.DefaultTimezone)
+		reminderCtx, cancelReminders := context.WithCancel(context.Background())
+		defer cancelReminders()
+		go reminders.Start(reminderCtx)
+		logInfo("Appointment reminders started, offsets: %v", cfg.Reminders.Offsets)
+	}
+
+	options := service.Options{
+		RescheduleMinNotice:  cfg.Appointments.RescheduleMinNotice,
+		DefaultTimezone:      cfg.Appointments.DefaultTimezone,
+		CalendarFeedBaseURL:  cfg.Appointments.CalendarFeedBaseURL,
+		CalendarAllowedHosts: cfg.CalendarSync.AllowedHosts,
+		CheckInWindow:        cfg.Appointments.CheckInWindow,
+		HoldDuration:         cfg.Payments.HoldDuration,
+	}
+
+	// Цена приема врача из specialist_service - для оплаты записи и оценки выручки в аналитике
+	if cfg.Services.SpecialistURL != "" {
+		options.Prices = specialist.NewClient(cfg.Services.SpecialistURL)
+	}
+
+	// Оплата записи: слот удерживается до подтверждения платежа по цене врача
+	switch {
+	case cfg.Payments.Provider == "":
+		logInfo("Payments are disabled, booking is free")
+	case cfg.Services.SpecialistURL == "":
+		logInfo("Specialist service URL is not configured, payments are disabled")
+	case cfg.Payments.Provider == "fake":
+		// Уведомление с такой подписью подтверждает запись без оплаты: без секрета сервис не стартует
+		if cfg.Payments.WebhookSecret == "" {
+			log.Fatalf("PAYMENT_WEBHOOK_SECRET must be set for fake payment provider")
+		}
+		options.Payments = payment.NewFakeProvider(cfg.Payments.WebhookSecret)
+		logInfo("Fake payment provider enabled, hold duration: %v", cfg.Payments.HoldDuration)
+	default:
+		log.Fatalf("Unknown payment provider: %s", cfg.Payments.Provider)
+	}
+
+	// Видеовстречи онлайн-приемов: комната на запись и личные ссылки участников с ограниченным сроком
+	options.MeetingJoinBefore = cfg.Meeting.JoinBefore
+	options.MeetingJoinAfter = cfg.Meeting.JoinAfter
+	switch cfg.Meeting.Provider {
+	case "":
+		logInfo("Meeting provider is not configured, online appointments have no video rooms")
+	case "jitsi":
+		if cfg.Meeting.AppSecret == "" {
+			log.Fatalf("MEETING_APP_SECRET must be set for jitsi provider")
+		}
+		options.Meetings = meeting.NewJitsiProvider(cfg.Meeting.PlatformURL, cfg.Meeting.AppID, cfg.Meeting.AppSecret)
+		logInfo("Jitsi meeting provider enabled: %s", cfg.Meeting.PlatformURL)
+	case "fake":
+		options.Meetings = meeting.NewFakeProvider()
+		logInfo("Fake meeting provider enabled")
+	default:
+		log.Fatalf("Unknown meeting provider: %s", cfg.Meeting.Provider)
+	}
+
+	// Файлы, прикрепленные к записи: другой участник читает их по подписанной ссылке, пока прием активен
+	switch {
+	case cfg.Files.GrantSecret == "":
+		logInfo("File grant secret is not configured, appointment attachments are disabled")
+	case cfg.Services.FileServerURL == "":
+		logInfo("FileServer URL is not configured, appointment attachments are disabled")
+	default:
+		options.Files = files.NewGrantSigner(cfg.Files.BaseURL, cfg.Files.GrantSecret)
+		options.FileOwners = files.NewClient(cfg.Services.FileServerURL)
+		options.FileGrantTTL = cfg.Files.GrantTTL
+		logInfo("Appointment attachments enabled, grant TTL: %v", cfg.Files.GrantTTL)
+	}
+
+	svc := service.NewAppointmentService(repo, messageService, loggerClient, options)
+
+	// Слоты, не оплаченные вовремя, возвращаются в свободные
+	if options.Payments != nil {
+		holdExpiry := service.NewHoldExpiryService(svc, loggerClient, cfg.Payments.ExpiryInterval)
+		expiryCtx, cancelExpiry := context.WithCancel(context.Background())
+		defer cancelExpiry()
+		go holdExpiry.Start(expiryCtx)
+	}
 	handler := handlers.NewAppointmentHandler(svc)
 
+	// Автоматическая генерация слотов на generate_days_ahead дней вперед
+	if cfg.SlotGeneration.Enabled {
+		slotGeneration := service.NewSlotGenerationService(repo, svc, loggerClient, cfg.SlotGeneration.Interval)
+		generationCtx, cancelGeneration := context.WithCancel(context.Background())
+		defer cancelGeneration()
+		go slotGeneration.Start(generationCtx)
+		logInfo("Automatic slot generation started, interval: %v", cfg.SlotGeneration.Interval)
+	}
+
+	// Синхронизация внешних календарей врачей (занятое время блокирует слоты)
+	if cfg.CalendarSync.Enabled {
+		calendarSync := service.NewCalendarSyncService(repo, svc, loggerClient, cfg.CalendarSync.Interval)
+		syncCtx, cancelSync := context.WithCancel(context.Background())
+		defer cancelSync()
+		go calendarSync.Start(syncCtx)
+		logInfo("External calendar sync started, interval: %v", cfg.CalendarSync.Interval)
+	}
+
 	// Передаем логгер в хендлер
 	if loggerClient != nil {
 		handler.SetLogger(loggerClient)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/printprince/vitalem/appointment_service/internal/database"
+)
+
+const migrateUsage = "usage: appointment_service migrate up|down|status"
+
+// runMigrate - подкоманда migrate: up применяет все новые миграции,
+// down откатывает последнюю, status показывает состояние каждой миграции
+func runMigrate(migrator *database.Migrator, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.CheckVersion(); err != nil {
+			return err
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s), schema version %d\n", len(applied), migrator.Latest())
+
+	case "down":
+		migration, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			fmt.Println("Nothing to roll back")
+			return nil
+		}
+		fmt.Printf("Rolled back %d_%s\n", migration.Version, migration.Name)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Unknown {
+				state += " (unknown to this binary)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	return nil
+}
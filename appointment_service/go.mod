@@ -5,10 +5,12 @@ go 1.24
 toolchain go1.24.2
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.0.0
 	github.com/google/uuid v1.6.0
 	github.com/labstack/echo/v4 v4.13.4
 	github.com/printprince/vitalem/logger_service v0.0.0-00010101000000-000000000000
 	github.com/printprince/vitalem/utils v0.0.0-00010101000000-000000000000
+	github.com/streadway/amqp v1.1.0
 	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.5.2
 	gorm.io/gorm v1.26.1
@@ -4,19 +4,29 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config - конфигурация приложения
 type Config struct {
-	Server   ServerConfig   `yaml:"server" json:"server"`
-	Database DatabaseConfig `yaml:"database" json:"database"`
-	Logging  LoggingConfig  `yaml:"logging" json:"logging"`
-	App      AppConfig      `yaml:"app" json:"app"`
-	Auth     AuthConfig     `yaml:"auth" json:"auth"`
-	Meeting  MeetingConfig  `yaml:"meeting" json:"meeting"`
+	Server         ServerConfig         `yaml:"server" json:"server"`
+	Database       DatabaseConfig       `yaml:"database" json:"database"`
+	Logging        LoggingConfig        `yaml:"logging" json:"logging"`
+	App            AppConfig            `yaml:"app" json:"app"`
+	Auth           AuthConfig           `yaml:"auth" json:"auth"`
+	Meeting        MeetingConfig        `yaml:"meeting" json:"meeting"`
+	RabbitMQ       RabbitMQConfig       `yaml:"rabbitmq" json:"rabbitmq"`
+	Reminders      RemindersConfig      `yaml:"reminders" json:"reminders"`
+	Appointments   AppointmentsConfig   `yaml:"appointments" json:"appointments"`
+	SlotGeneration SlotGenerationConfig `yaml:"slot_generation" json:"slot_generation"`
+	CalendarSync   CalendarSyncConfig   `yaml:"calendar_sync" json:"calendar_sync"`
+	Payments       PaymentsConfig       `yaml:"payments" json:"payments"`
+	Files          FilesConfig          `yaml:"files" json:"files"`
+	Services       ServicesConfig       `yaml:"services" json:"services"`
 }
 
 // ServerConfig - конфигурация сервера
@@ -33,6 +43,8 @@ type DatabaseConfig struct {
 	Password string `yaml:"password" json:"password"`
 	DBName   string `yaml:"db_name" json:"db_name"`
 	SSLMode  string `yaml:"ssl_mode" json:"ssl_mode"`
+	// AutoMigrate - применять новые миграции при старте; иначе их применяют командой migrate up
+	AutoMigrate bool `yaml:"auto_migrate" json:"auto_migrate"`
 }
 
 // LoggingConfig - конфигурация логирования
@@ -56,7 +68,71 @@ type AuthConfig struct {
 
 // MeetingConfig - конфигурация онлайн встреч
 type MeetingConfig struct {
-	PlatformURL string `yaml:"platform_url" env:"MEETING_PLATFORM_URL" envDefault:"https://meet.vitalem.kz"`
+	Provider    string        `yaml:"provider" json:"provider"` // "jitsi", "fake" или пусто - онлайн-записи без комнат
+	PlatformURL string        `yaml:"platform_url" env:"MEETING_PLATFORM_URL" envDefault:"https://meet.vitalem.kz"`
+	AppID       string        `yaml:"app_id" json:"app_id"`           // app_id token auth Jitsi
+	AppSecret   string        `yaml:"-" json:"-"`                     // секрет подписи JWT участников, только из MEETING_APP_SECRET
+	JoinBefore  time.Duration `yaml:"join_before" json:"join_before"` // за сколько до приема действует ссылка на вход
+	JoinAfter   time.Duration `yaml:"join_after" json:"join_after"`   // сколько после приема ссылка еще действует
+}
+
+// RabbitMQConfig - конфигурация брокера сообщений
+type RabbitMQConfig struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     string `yaml:"port" json:"port"`
+	User     string `yaml:"user" json:"user"`
+	Password string `yaml:"password" json:"-"`
+	Exchange string `yaml:"exchange" json:"exchange"`
+}
+
+// RemindersConfig - конфигурация напоминаний о приемах
+type RemindersConfig struct {
+	Enabled  bool            `yaml:"enabled" json:"enabled"`
+	Offsets  []time.Duration `yaml:"offsets" json:"offsets"`   // за сколько до приема напоминать, например 24h, 1h
+	Interval time.Duration   `yaml:"interval" json:"interval"` // как часто проверять наступившие напоминания
+}
+
+// AppointmentsConfig - бизнес-правила записей
+type AppointmentsConfig struct {
+	RescheduleMinNotice time.Duration `yaml:"reschedule_min_notice" json:"reschedule_min_notice"`   // минимальный срок до приема для переноса
+	DefaultTimezone     string        `yaml:"default_timezone" json:"default_timezone"`             // пояс расписаний по умолчанию, например Asia/Almaty
+	CalendarFeedBaseURL string        `yaml:"calendar_feed_base_url" json:"calendar_feed_base_url"` // внешний адрес gateway для ссылок на ICS-ленты
+	CheckInWindow       time.Duration `yaml:"check_in_window" json:"check_in_window"`               // за сколько до начала приема можно отметить приход пациента
+}
+
+// SlotGenerationConfig - конфигурация автоматической генерации слотов
+type SlotGenerationConfig struct {
+	Enabled  bool          `yaml:"enabled" json:"enabled"`
+	Interval time.Duration `yaml:"interval" json:"interval"` // как часто догенерировать слоты
+}
+
+// CalendarSyncConfig - конфигурация синхронизации внешних календарей врачей
+type CalendarSyncConfig struct {
+	Enabled  bool          `yaml:"enabled" json:"enabled"`
+	Interval time.Duration `yaml:"interval" json:"interval"` // как часто перечитывать подключенные календари
+	// Внутренние хосты, IP и сети CIDR, с которых разрешено подключать календари (остальная внутренняя сеть запрещена)
+	AllowedHosts []string `yaml:"allowed_hosts" json:"allowed_hosts"`
+}
+
+// PaymentsConfig - конфигурация оплаты записей
+type PaymentsConfig struct {
+	Provider       string        `yaml:"provider" json:"provider"`               // "fake" или пусто - запись без оплаты
+	WebhookSecret  string        `yaml:"-" json:"-"`                             // секрет подписи уведомлений провайдера, только из PAYMENT_WEBHOOK_SECRET
+	HoldDuration   time.Duration `yaml:"hold_duration" json:"hold_duration"`     // сколько слот ждет оплаты
+	ExpiryInterval time.Duration `yaml:"expiry_interval" json:"expiry_interval"` // как часто освобождать истекшие удержания
+}
+
+// FilesConfig - доступ участников приема к прикрепленным файлам FileServer
+type FilesConfig struct {
+	BaseURL     string        `yaml:"base_url" json:"base_url"`   // внешний адрес API для ссылок на скачивание
+	GrantSecret string        `yaml:"-" json:"-"`                 // общий с FileServer секрет подписи разрешений (только FILE_GRANT_SECRET); пусто - прикрепление выключено
+	GrantTTL    time.Duration `yaml:"grant_ttl" json:"grant_ttl"` // сколько действует ссылка на чужой файл
+}
+
+// ServicesConfig - адреса других сервисов
+type ServicesConfig struct {
+	SpecialistURL string `yaml:"specialist_url" json:"specialist_url"`
+	FileServerURL string `yaml:"fileserver_url" json:"fileserver_url"`
 }
 
 var (
@@ -152,6 +228,9 @@ func overrideFromEnv(config *Config) {
 	if sslMode := os.Getenv("DB_SSL_MODE"); sslMode != "" {
 		config.Database.SSLMode = sslMode
 	}
+	if autoMigrate := os.Getenv("DB_AUTO_MIGRATE"); autoMigrate != "" {
+		config.Database.AutoMigrate = autoMigrate == "true"
+	}
 
 	// Logging
 	if consoleLevel := os.Getenv("CONSOLE_LOG_LEVEL"); consoleLevel != "" {
@@ -178,4 +257,139 @@ func overrideFromEnv(config *Config) {
 	if platformURL := os.Getenv("MEETING_PLATFORM_URL"); platformURL != "" {
 		config.Meeting.PlatformURL = platformURL
 	}
+	if provider := os.Getenv("MEETING_PROVIDER"); provider != "" {
+		config.Meeting.Provider = provider
+	}
+	if appID := os.Getenv("MEETING_APP_ID"); appID != "" {
+		config.Meeting.AppID = appID
+	}
+	if secret := os.Getenv("MEETING_APP_SECRET"); secret != "" {
+		config.Meeting.AppSecret = secret
+	}
+	if before := os.Getenv("MEETING_JOIN_BEFORE"); before != "" {
+		if parsed, err := time.ParseDuration(before); err == nil {
+			config.Meeting.JoinBefore = parsed
+		}
+	}
+	if after := os.Getenv("MEETING_JOIN_AFTER"); after != "" {
+		if parsed, err := time.ParseDuration(after); err == nil {
+			config.Meeting.JoinAfter = parsed
+		}
+	}
+
+	// RabbitMQ
+	if rmqHost := os.Getenv("RMQ_HOST"); rmqHost != "" {
+		config.RabbitMQ.Host = rmqHost
+	}
+	if rmqPort := os.Getenv("RMQ_PORT"); rmqPort != "" {
+		config.RabbitMQ.Port = rmqPort
+	}
+	if rmqUser := os.Getenv("RMQ_USER"); rmqUser != "" {
+		config.RabbitMQ.User = rmqUser
+	}
+	if rmqPass := os.Getenv("RMQ_PASS"); rmqPass != "" {
+		config.RabbitMQ.Password = rmqPass
+	}
+	if rmqExchange := os.Getenv("RMQ_EXCHANGE"); rmqExchange != "" {
+		config.RabbitMQ.Exchange = rmqExchange
+	}
+
+	// Reminders
+	if enabled := os.Getenv("REMINDERS_ENABLED"); enabled != "" {
+		config.Reminders.Enabled = enabled == "true"
+	}
+	if offsets := os.Getenv("REMINDER_OFFSETS"); offsets != "" {
+		var parsed []time.Duration
+		for _, value := range strings.Split(offsets, ",") {
+			if offset, err := time.ParseDuration(strings.TrimSpace(value)); err == nil {
+				parsed = append(parsed, offset)
+			}
+		}
+		config.Reminders.Offsets = parsed
+	}
+	if interval := os.Getenv("REMINDER_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			config.Reminders.Interval = parsed
+		}
+	}
+
+	// Appointments
+	if notice := os.Getenv("RESCHEDULE_MIN_NOTICE"); notice != "" {
+		if parsed, err := time.ParseDuration(notice); err == nil {
+			config.Appointments.RescheduleMinNotice = parsed
+		}
+	}
+	if timezone := os.Getenv("DEFAULT_TIMEZONE"); timezone != "" {
+		config.Appointments.DefaultTimezone = timezone
+	}
+	if feedURL := os.Getenv("CALENDAR_FEED_BASE_URL"); feedURL != "" {
+		config.Appointments.CalendarFeedBaseURL = feedURL
+	}
+	if window := os.Getenv("CHECK_IN_WINDOW"); window != "" {
+		if parsed, err := time.ParseDuration(window); err == nil {
+			config.Appointments.CheckInWindow = parsed
+		}
+	}
+
+	// Slot generation
+	if enabled := os.Getenv("SLOT_GENERATION_ENABLED"); enabled != "" {
+		config.SlotGeneration.Enabled = enabled == "true"
+	}
+	if interval := os.Getenv("SLOT_GENERATION_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			config.SlotGeneration.Interval = parsed
+		}
+	}
+
+	// Payments
+	if provider := os.Getenv("PAYMENT_PROVIDER"); provider != "" {
+		config.Payments.Provider = provider
+	}
+	if secret := os.Getenv("PAYMENT_WEBHOOK_SECRET"); secret != "" {
+		config.Payments.WebhookSecret = secret
+	}
+	if hold := os.Getenv("PAYMENT_HOLD_DURATION"); hold != "" {
+		if parsed, err := time.ParseDuration(hold); err == nil {
+			config.Payments.HoldDuration = parsed
+		}
+	}
+	if interval := os.Getenv("PAYMENT_EXPIRY_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			config.Payments.ExpiryInterval = parsed
+		}
+	}
+
+	// Files
+	if baseURL := os.Getenv("FILES_BASE_URL"); baseURL != "" {
+		config.Files.BaseURL = baseURL
+	}
+	if secret := os.Getenv("FILE_GRANT_SECRET"); secret != "" {
+		config.Files.GrantSecret = secret
+	}
+	if ttl := os.Getenv("FILE_GRANT_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			config.Files.GrantTTL = parsed
+		}
+	}
+
+	// Services
+	if specialistURL := os.Getenv("SPECIALIST_SERVICE_URL"); specialistURL != "" {
+		config.Services.SpecialistURL = specialistURL
+	}
+	if fileServerURL := os.Getenv("FILESERVER_SERVICE_URL"); fileServerURL != "" {
+		config.Services.FileServerURL = fileServerURL
+	}
+
+	// Calendar sync
+	if enabled := os.Getenv("CALENDAR_SYNC_ENABLED"); enabled != "" {
+		config.CalendarSync.Enabled = enabled == "true"
+	}
+	if interval := os.Getenv("CALENDAR_SYNC_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			config.CalendarSync.Interval = parsed
+		}
+	}
+	if allowedHosts := os.Getenv("CALENDAR_ALLOWED_HOSTS"); allowedHosts != "" {
+		config.CalendarSync.AllowedHosts = strings.Split(allowedHosts, ",")
+	}
 }
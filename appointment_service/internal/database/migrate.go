@@ -0,0 +1,312 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationsLockID - ключ advisory lock, чтобы несколько экземпляров сервиса не применяли миграции одновременно
+const migrationsLockID = 7240311
+
+// ErrSchemaTooNew - база уже доведена миграциями, которых нет в этой версии сервиса
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration - версионное изменение схемы с откатом
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus - состояние миграции в базе
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // nil - еще не применена
+	Unknown   bool       // применена, но в этой версии сервиса ее нет
+}
+
+// schemaMigration - строка таблицы schema_migrations
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"type:timestamp with time zone;not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator - применяет и откатывает миграции, учитывая их в таблице schema_migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator - мигратор с миграциями из fsys (файлы NNN_name.up.sql и NNN_name.down.sql)
+func NewMigrator(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations - читает пары up/down, упорядочивая их по версии
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest - версия схемы, до которой доводит эта версия сервиса
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up - применяет все еще не примененные миграции по порядку; каждая - в своей транзакции
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range m.migrations {
+		done, err := m.apply(migration)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if done {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+			applied = append(applied, migration)
+		}
+	}
+
+	return applied, nil
+}
+
+// apply - применяет миграцию, если ее еще не применил другой экземпляр сервиса
+func (m *Migrator) apply(migration Migration) (bool, error) {
+	applied := false
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationsLockID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&schemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		applied = true
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	return applied, err
+}
+
+// Down - откатывает последнюю примененную миграцию. Возвращает nil, если откатывать нечего
+func (m *Migrator) Down() (*Migration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rolledBack *Migration
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationsLockID).Error; err != nil {
+			return err
+		}
+
+		var last schemaMigration
+		result := tx.Order("version DESC").Limit(1).Find(&last)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		migration, ok := m.find(last.Version)
+		if !ok {
+			return fmt.Errorf("%w: migration %d_%s is unknown, cannot roll it back", ErrSchemaTooNew, last.Version, last.Name)
+		}
+
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return fmt.Errorf("migration %d_%s rollback failed: %w", migration.Version, migration.Name, err)
+		}
+		if err := tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error; err != nil {
+			return err
+		}
+		rolledBack = &migration
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rolledBack != nil {
+		log.Printf("Rolled back migration %d_%s", rolledBack.Version, rolledBack.Name)
+	}
+	return rolledBack, nil
+}
+
+// Status - все миграции сервиса и примененные в базе, по возрастанию версии
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// CheckVersion - отказывает в работе, если база доведена миграциями, которых этот бинарник не знает:
+// старый код может испортить данные в новой схеме
+func (m *Migrator) CheckVersion() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	for version, row := range applied {
+		if _, ok := m.find(version); !ok {
+			return fmt.Errorf("%w: database has migration %d_%s, latest known is %d", ErrSchemaTooNew, version, row.Name, m.Latest())
+		}
+	}
+	return nil
+}
+
+// Pending - миграции этой версии сервиса, еще не примененные к базе
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// applied - примененные миграции по версии; пустая карта, если таблицы миграций еще нет
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	applied := make(map[int64]schemaMigration)
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`).Error
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// legacyDB - пустая схема в тестовой базе из APPOINTMENT_TEST_DATABASE_URL со старыми таблицами
+// без уникальных индексов, как их оставлял AutoMigrate. Без переменной тест пропускается
+func legacyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("APPOINTMENT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("APPOINTMENT_TEST_DATABASE_URL is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	base, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	schema := "legacy_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := base.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		base.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+separator+"search_path="+schema), config)
+	if err != nil {
+		t.Fatalf("failed to connect to schema %s: %v", schema, err)
+	}
+
+	if err := db.Exec(`
+		CREATE TABLE appointments (
+			id UUID PRIMARY KEY,
+			start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+			end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+			doctor_id UUID NOT NULL,
+			patient_id UUID,
+			title VARCHAR(255) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'available',
+			created_at TIMESTAMP WITH TIME ZONE,
+			updated_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE TABLE appointment_reminders (
+			id UUID PRIMARY KEY,
+			appointment_id UUID NOT NULL,
+			patient_id UUID NOT NULL,
+			offset_minutes INTEGER NOT NULL,
+			start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+			sent_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`).Error; err != nil {
+		t.Fatalf("failed to create legacy tables: %v", err)
+	}
+	return db
+}
+
+func insertLegacySlot(t *testing.T, db *gorm.DB, doctorID uuid.UUID, start time.Time, status string, patientID *uuid.UUID) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	if err := db.Exec(`INSERT INTO appointments (id, start_time, end_time, doctor_id, patient_id, title, status)
+		VALUES (?, ?, ?, ?, ?, 'Консультация', ?)`, id, start, start.Add(30*time.Minute), doctorID, patientID, status).Error; err != nil {
+		t.Fatalf("failed to insert legacy slot: %v", err)
+	}
+	return id
+}
+
+func TestInitialMigrationDedupesLegacyData(t *testing.T) {
+	db := legacyDB(t)
+
+	doctorID, patientID := uuid.New(), uuid.New()
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	// Свободный слот сгенерирован дважды, на второе время есть запись и ее свободная копия
+	insertLegacySlot(t, db, doctorID, start, "available", nil)
+	insertLegacySlot(t, db, doctorID, start, "available", nil)
+	booked := insertLegacySlot(t, db, doctorID, start.Add(time.Hour), "booked", &patientID)
+	insertLegacySlot(t, db, doctorID, start.Add(time.Hour), "available", nil)
+	for i := 0; i < 2; i++ {
+		if err := db.Exec(`INSERT INTO appointment_reminders (id, appointment_id, patient_id, offset_minutes, start_time, sent_at)
+			VALUES (?, ?, ?, 60, ?, NOW())`, uuid.New(), booked, patientID, start.Add(time.Hour)).Error; err != nil {
+			t.Fatalf("failed to insert legacy reminder: %v", err)
+		}
+	}
+
+	migrator, err := NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("migrate legacy schema: %v", err)
+	}
+
+	var active, reminders int64
+	db.Table("appointments").Where("status <> 'canceled'").Count(&active)
+	db.Table("appointment_reminders").Count(&reminders)
+	if active != 2 || reminders != 1 {
+		t.Fatalf("expected 2 active slots and 1 reminder after dedupe, got %d and %d", active, reminders)
+	}
+	var bookedStatus string
+	db.Table("appointments").Select("status").Where("id = ?", booked).Scan(&bookedStatus)
+	if bookedStatus != "booked" {
+		t.Fatalf("booked appointment must survive dedupe, got status %q", bookedStatus)
+	}
+
+	// Полный откат удаляет созданные миграциями таблицы, но не старые таблицы с данными
+	for {
+		migration, err := migrator.Down()
+		if err != nil {
+			t.Fatalf("rollback: %v", err)
+		}
+		if migration == nil {
+			break
+		}
+	}
+	if !db.Migrator().HasTable("appointments") || !db.Migrator().HasTable("appointment_reminders") {
+		t.Fatal("rollback of 001 must keep tables that existed before it")
+	}
+	if db.Migrator().HasTable("visit_types") || db.Migrator().HasTable("schema_initial_tables") {
+		t.Fatal("rollback of 001 must drop the tables it created")
+	}
+}
+
+func TestInitialMigrationStopsOnConflictingBookings(t *testing.T) {
+	db := legacyDB(t)
+
+	doctorID := uuid.New()
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	first, second := uuid.New(), uuid.New()
+	insertLegacySlot(t, db, doctorID, start, "booked", &first)
+	insertLegacySlot(t, db, doctorID, start, "booked", &second)
+
+	migrator, err := NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err == nil || !strings.Contains(err.Error(), "resolve them manually") {
+		t.Fatalf("expected the precheck to stop the migration, got %v", err)
+	}
+
+	// Миграция откатилась целиком: записи пациентов не тронуты
+	var booked int64
+	db.Table("appointments").Where("status = 'booked'").Count(&booked)
+	if booked != 2 {
+		t.Fatalf("expected both bookings to stay, got %d", booked)
+	}
+}
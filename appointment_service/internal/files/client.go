@@ -0,0 +1,61 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrFileNotFound - файла нет в FileServer или пользователь не может его читать
+var ErrFileNotFound = errors.New("file not found")
+
+// Client - HTTP клиент FileServer
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient - создание клиента; baseURL например http://fileserver_service:8087
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// FileOwner - владелец файла; FileServer проверяет токен пользователя, от имени которого идет запрос
+func (c *Client) FileOwner(ctx context.Context, fileID uuid.UUID, token string) (uuid.UUID, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/files/"+fileID.String(), nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("fileserver request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+		return uuid.Nil, ErrFileNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return uuid.Nil, fmt.Errorf("fileserver responded with %s", resp.Status)
+	}
+
+	var file struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to decode file metadata: %w", err)
+	}
+
+	return file.UserID, nil
+}
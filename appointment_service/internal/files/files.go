@@ -0,0 +1,30 @@
+// Package files - ссылки на файлы FileServer, прикрепленные к записям на прием
+package files
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Grant - разрешение участнику приема читать файл другого участника
+type Grant struct {
+	FileID    uuid.UUID
+	OwnerID   uuid.UUID // кто прикрепил файл; FileServer проверяет, что это владелец файла
+	GranteeID uuid.UUID // кому открыт доступ
+	ExpiresAt time.Time
+}
+
+// Linker - ссылки на скачивание файлов. Свой файл скачивается по обычной ссылке,
+// чужой - по ссылке с подписанным разрешением, которое FileServer проверяет сам
+type Linker interface {
+	DownloadURL(fileID uuid.UUID) string
+	GrantURL(grant Grant) (string, error)
+}
+
+// OwnerResolver - владелец файла по данным FileServer. Запрос идет от имени пользователя (token),
+// поэтому FileServer отдает только файлы, которые пользователь и так может читать
+type OwnerResolver interface {
+	FileOwner(ctx context.Context, fileID uuid.UUID, token string) (uuid.UUID, error)
+}
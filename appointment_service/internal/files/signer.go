@@ -0,0 +1,52 @@
+package files
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// grantAudience - FileServer принимает только разрешения с этим получателем
+const grantAudience = "fileserver"
+
+// GrantSigner - подписывает разрешения общим с FileServer секретом (FILE_GRANT_SECRET).
+// Секрет отдельный от JWT пользователей: разрешение не может сойти за токен входа и наоборот
+type GrantSigner struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewGrantSigner - создание подписчика; baseURL - внешний адрес API с маршрутами /files
+func NewGrantSigner(baseURL, secret string) *GrantSigner {
+	return &GrantSigner{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+	}
+}
+
+// DownloadURL - ссылка на скачивание своего файла
+func (s *GrantSigner) DownloadURL(fileID uuid.UUID) string {
+	return s.baseURL + "/files/" + fileID.String() + "/download"
+}
+
+// GrantURL - ссылка на скачивание с разрешением, действующим до grant.ExpiresAt
+func (s *GrantSigner) GrantURL(grant Grant) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":        "appointment_service",
+		"aud":        grantAudience,
+		"file_id":    grant.FileID.String(),
+		"owner_id":   grant.OwnerID.String(),
+		"grantee_id": grant.GranteeID.String(),
+		"exp":        grant.ExpiresAt.Unix(),
+	})
+
+	signed, err := token.SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign file grant: %w", err)
+	}
+
+	return s.DownloadURL(grant.FileID) + "?grant=" + url.QueryEscape(signed), nil
+}
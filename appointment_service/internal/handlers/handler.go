@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -40,6 +44,63 @@ func (h *AppointmentHandler) logError(message string, metadata map[string]interf
 	}
 }
 
+// errorStatus - определяет HTTP статус по ошибке сервиса
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAppointmentNotFound), errors.Is(err, service.ErrExceptionNotFound),
+		errors.Is(err, service.ErrCalendarFeedNotFound), errors.Is(err, service.ErrCalendarNotFound),
+		errors.Is(err, service.ErrVisitTypeNotFound), errors.Is(err, service.ErrAttachmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidStatus), errors.Is(err, service.ErrAppointmentNotStarted),
+		errors.Is(err, service.ErrSlotTaken), errors.Is(err, service.ErrNoticeTooShort),
+		errors.Is(err, service.ErrScheduleConflict), errors.Is(err, service.ErrPolicyViolation),
+		errors.Is(err, service.ErrAlreadyParticipant), errors.Is(err, service.ErrAlreadyAttached),
+		errors.Is(err, service.ErrPaidGroupSession):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCalendarUnavailable), errors.Is(err, service.ErrPaymentUnavailable),
+		errors.Is(err, service.ErrMeetingUnavailable), errors.Is(err, service.ErrAttachmentsUnavailable):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// scheduleErrorResponse - ответ с ошибкой расписания; при пересечении с другими
+// активными расписаниями в data возвращается список конфликтов
+func scheduleErrorResponse(err error) models.APIResponse {
+	response := models.APIResponse{
+		Success: false,
+		Error:   err.Error(),
+	}
+
+	var conflictErr *service.ScheduleConflictError
+	if errors.As(err, &conflictErr) {
+		response.Data = conflictErr.Conflicts
+	}
+
+	return response
+}
+
+// bookingErrorResponse - ответ с ошибкой записи; при нарушении правил записи врача
+// в code возвращается код нарушенного правила
+func bookingErrorResponse(err error) models.APIResponse {
+	response := models.APIResponse{
+		Success: false,
+		Error:   err.Error(),
+	}
+
+	var policyErr *service.PolicyError
+	if errors.As(err, &policyErr) {
+		response.Code = policyErr.Code
+	}
+
+	return response
+}
+
 // === SCHEDULE ENDPOINTS ===
 
 // CreateSchedule - POST /api/doctor/schedules
@@ -95,10 +156,7 @@ func (h *AppointmentHandler) CreateSchedule(c echo.Context) error {
 			"userID":   userID.String(),
 			"error":    err.Error(),
 		})
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		return c.JSON(errorStatus(err), scheduleErrorResponse(err))
 	}
 
 	h.logInfo("Schedule created successfully", map[string]interface{}{
@@ -192,10 +250,7 @@ func (h *AppointmentHandler) UpdateSchedule(c echo.Context) error {
 
 	response, err := h.service.UpdateSchedule(userID, scheduleID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		return c.JSON(errorStatus(err), scheduleErrorResponse(err))
 	}
 
 	return c.JSON(http.StatusOK, models.APIResponse{
@@ -272,10 +327,7 @@ func (h *AppointmentHandler) ToggleSchedule(c echo.Context) error {
 
 	response, err := h.service.ToggleSchedule(userID, scheduleID, &req, hasRequestBody)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		return c.JSON(errorStatus(err), scheduleErrorResponse(err))
 	}
 
 	return c.JSON(http.StatusOK, models.APIResponse{
@@ -468,9 +520,12 @@ func (h *AppointmentHandler) GetAvailableSlots(c echo.Context) error {
 		})
 	}
 
-	slots, err := h.service.GetAvailableSlots(doctorID, date)
+	// Часовой пояс пациента (IANA): date трактуется как его местный день
+	timezone := c.QueryParam("timezone")
+
+	slots, err := h.service.GetAvailableSlots(doctorID, date, timezone)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -527,10 +582,7 @@ func (h *AppointmentHandler) BookAppointment(c echo.Context) error {
 
 	appointment, err := h.service.BookAppointment(userID, appointmentID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		return c.JSON(errorStatus(err), bookingErrorResponse(err))
 	}
 
 	return c.JSON(http.StatusOK, models.APIResponse{
@@ -558,7 +610,58 @@ func (h *AppointmentHandler) CancelAppointment(c echo.Context) error {
 	}
 
 	if err := h.service.CancelAppointment(userID, appointmentID); err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+		return c.JSON(errorStatus(err), bookingErrorResponse(err))
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    "Appointment canceled successfully",
+	})
+}
+
+// DoctorCancelAppointment - POST /appointments/:id/cancel (врач)
+func (h *AppointmentHandler) DoctorCancelAppointment(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	var req models.DoctorCancelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	// Врач обязан указать причину отмены
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointment, err := h.service.DoctorCancelAppointment(userID, appointmentID, &req)
+	if err != nil {
+		h.logError("Failed to cancel appointment by doctor", map[string]interface{}{
+			"endpoint":      "DoctorCancelAppointment",
+			"userID":        userID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -566,12 +669,12 @@ func (h *AppointmentHandler) CancelAppointment(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Data:    "Appointment canceled successfully",
+		Data:    appointment,
 	})
 }
 
-// GetDoctorAppointments - GET /api/doctor/appointments
-func (h *AppointmentHandler) GetDoctorAppointments(c echo.Context) error {
+// DoctorCancelAppointments - POST /appointments/cancel-range
+func (h *AppointmentHandler) DoctorCancelAppointments(c echo.Context) error {
 	userID, ok := c.Get("user_id").(uuid.UUID)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, models.APIResponse{
@@ -580,17 +683,202 @@ func (h *AppointmentHandler) GetDoctorAppointments(c echo.Context) error {
 		})
 	}
 
-	appointments, err := h.service.GetDoctorAppointments(userID)
+	var req models.DoctorBulkCancelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	result, err := h.service.DoctorCancelAppointments(userID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+		h.logError("Failed to cancel doctor appointments for period", map[string]interface{}{
+			"endpoint":  "DoctorCancelAppointments",
+			"userID":    userID.String(),
+			"startDate": req.StartDate,
+			"endDate":   req.EndDate,
+			"error":     err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	h.logInfo("Doctor appointments canceled for period", map[string]interface{}{
+		"endpoint":      "DoctorCancelAppointments",
+		"userID":        userID.String(),
+		"canceledCount": result.CanceledCount,
+		"closedSlots":   result.ClosedSlots,
+	})
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// RescheduleAppointment - POST /appointments/:id/reschedule
+func (h *AppointmentHandler) RescheduleAppointment(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	var req models.RescheduleAppointmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointment, err := h.service.RescheduleAppointment(userID, appointmentID, &req)
+	if err != nil {
+		h.logError("Failed to reschedule appointment", map[string]interface{}{
+			"endpoint":      "RescheduleAppointment",
+			"userID":        userID.String(),
+			"appointmentID": appointmentID.String(),
+			"targetSlotID":  req.TargetSlotID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), bookingErrorResponse(err))
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    appointment,
+	})
+}
+
+// CompleteAppointment - POST /appointments/:id/complete
+func (h *AppointmentHandler) CompleteAppointment(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		h.logError("Invalid user ID in token", map[string]interface{}{
+			"endpoint": "CompleteAppointment",
+		})
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	var req models.CompleteAppointmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	// Валидация входящих данных
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointment, err := h.service.CompleteAppointment(userID, appointmentID, &req)
+	if err != nil {
+		h.logError("Failed to complete appointment", map[string]interface{}{
+			"endpoint":      "CompleteAppointment",
+			"userID":        userID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
 	}
 
+	h.logInfo("Appointment completed successfully", map[string]interface{}{
+		"endpoint":      "CompleteAppointment",
+		"userID":        userID.String(),
+		"appointmentID": appointmentID.String(),
+	})
+
 	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    appointment,
+	})
+}
+
+// GetDoctorAppointments - GET /appointments?status=booked,completed&date_from=2024-06-01&date_to=2024-06-30&when=upcoming&sort=asc&page=1&limit=20
+func (h *AppointmentHandler) GetDoctorAppointments(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	var req models.AppointmentListRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid query parameters",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointments, total, err := h.service.GetDoctorAppointments(userID, &req)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success: true,
 		Data:    appointments,
+		Total:   total,
+		Page:    req.Page,
+		Limit:   req.Limit,
 	})
 }
 
@@ -654,7 +942,7 @@ func (h *AppointmentHandler) GetDoctorAppointmentByID(c echo.Context) error {
 	})
 }
 
-// GetPatientAppointments - GET /api/patient/appointments
+// GetPatientAppointments - GET /appointments (те же фильтры, что и у врача)
 func (h *AppointmentHandler) GetPatientAppointments(c echo.Context) error {
 	userID, ok := c.Get("user_id").(uuid.UUID)
 	if !ok {
@@ -664,17 +952,35 @@ func (h *AppointmentHandler) GetPatientAppointments(c echo.Context) error {
 		})
 	}
 
-	appointments, err := h.service.GetPatientAppointments(userID)
+	var req models.AppointmentListRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid query parameters",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointments, total, err := h.service.GetPatientAppointments(userID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, models.APIResponse{
+	return c.JSON(http.StatusOK, models.PaginatedResponse{
 		Success: true,
 		Data:    appointments,
+		Total:   total,
+		Page:    req.Page,
+		Limit:   req.Limit,
 	})
 }
 
@@ -738,10 +1044,10 @@ func (h *AppointmentHandler) GetPatientAppointmentByID(c echo.Context) error {
 	})
 }
 
-// === EXCEPTION ENDPOINTS ===
+// === VISIT TYPE ENDPOINTS ===
 
-// AddException - POST /api/doctor/exceptions
-func (h *AppointmentHandler) AddException(c echo.Context) error {
+// CreateVisitType - POST /appointments/visit-types
+func (h *AppointmentHandler) CreateVisitType(c echo.Context) error {
 	userID, ok := c.Get("user_id").(uuid.UUID)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, models.APIResponse{
@@ -750,7 +1056,7 @@ func (h *AppointmentHandler) AddException(c echo.Context) error {
 		})
 	}
 
-	var req models.AddExceptionRequest
+	var req models.CreateVisitTypeRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
@@ -758,7 +1064,6 @@ func (h *AppointmentHandler) AddException(c echo.Context) error {
 		})
 	}
 
-	// Валидация входящих данных
 	if err := c.Validate(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success: false,
@@ -766,9 +1071,9 @@ func (h *AppointmentHandler) AddException(c echo.Context) error {
 		})
 	}
 
-	exception, err := h.service.AddException(userID, &req)
+	visitType, err := h.service.CreateVisitType(userID, &req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -776,12 +1081,13 @@ func (h *AppointmentHandler) AddException(c echo.Context) error {
 
 	return c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
-		Data:    exception,
+		Data:    visitType,
 	})
 }
 
-// GetDoctorExceptions - GET /api/doctor/exceptions?start_date=2024-06-01&end_date=2024-06-30
-func (h *AppointmentHandler) GetDoctorExceptions(c echo.Context) error {
+// GetDoctorVisitTypes - GET /appointments/visit-types
+// Весь каталог врача, включая выключенные виды приема
+func (h *AppointmentHandler) GetDoctorVisitTypes(c echo.Context) error {
 	userID, ok := c.Get("user_id").(uuid.UUID)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, models.APIResponse{
@@ -790,19 +1096,1069 @@ func (h *AppointmentHandler) GetDoctorExceptions(c echo.Context) error {
 		})
 	}
 
-	startDate := c.QueryParam("start_date")
-	endDate := c.QueryParam("end_date")
-
-	if startDate == "" || endDate == "" {
-		return c.JSON(http.StatusBadRequest, models.APIResponse{
+	visitTypes, err := h.service.GetVisitTypes(userID, false)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    visitTypes,
+	})
+}
+
+// UpdateVisitType - PUT /appointments/visit-types/:id
+func (h *AppointmentHandler) UpdateVisitType(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	visitTypeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid visit type ID",
+		})
+	}
+
+	var req models.UpdateVisitTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	visitType, err := h.service.UpdateVisitType(userID, visitTypeID, &req)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    visitType,
+	})
+}
+
+// DeleteVisitType - DELETE /appointments/visit-types/:id
+func (h *AppointmentHandler) DeleteVisitType(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	visitTypeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid visit type ID",
+		})
+	}
+
+	if err := h.service.DeleteVisitType(userID, visitTypeID); err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    "Visit type deleted successfully",
+	})
+}
+
+// GetVisitTypes - GET /appointments/doctors/:id/visit-types
+// Виды приема врача, на которые можно записаться
+func (h *AppointmentHandler) GetVisitTypes(c echo.Context) error {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid doctor ID",
+		})
+	}
+
+	visitTypes, err := h.service.GetVisitTypes(doctorID, true)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    visitTypes,
+	})
+}
+
+// GetVisitSlots - GET /appointments/doctors/:id/visit-slots?visit_type_id=...&date=2024-06-03&timezone=Asia/Almaty
+func (h *AppointmentHandler) GetVisitSlots(c echo.Context) error {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid doctor ID",
+		})
+	}
+
+	visitTypeID, err := uuid.Parse(c.QueryParam("visit_type_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "visit_type_id parameter is required",
+		})
+	}
+
+	date := c.QueryParam("date")
+	if date == "" {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Date parameter is required",
+		})
+	}
+
+	// Часовой пояс пациента (IANA): date трактуется как его местный день
+	timezone := c.QueryParam("timezone")
+
+	slots, err := h.service.GetVisitSlots(doctorID, visitTypeID, date, timezone)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    slots,
+	})
+}
+
+// BookVisit - POST /appointments/doctors/:id/visits
+// Запись пациента по виду приема на выбранное время
+func (h *AppointmentHandler) BookVisit(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid doctor ID",
+		})
+	}
+
+	var req models.BookVisitRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointment, err := h.service.BookVisit(userID, doctorID, &req)
+	if err != nil {
+		h.logError("Failed to book visit", map[string]interface{}{
+			"endpoint":    "BookVisit",
+			"userID":      userID.String(),
+			"doctorID":    doctorID.String(),
+			"visitTypeID": req.VisitTypeID.String(),
+			"error":       err.Error(),
+		})
+		return c.JSON(errorStatus(err), bookingErrorResponse(err))
+	}
+
+	return c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data:    appointment,
+	})
+}
+
+// === SEARCH ENDPOINTS ===
+
+// SearchAvailableSlots - GET /appointments/search?doctor_ids=...&date_from=2024-06-03&date_to=2024-06-10&format=online&time_from=09:00&time_to=13:00
+// Ближайшие свободные слоты сразу по нескольким врачам, постранично
+func (h *AppointmentHandler) SearchAvailableSlots(c echo.Context) error {
+	var req models.SlotSearchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid query parameters",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	slots, total, err := h.service.SearchAvailableSlots(&req)
+	if err != nil {
+		h.logError("Failed to search available slots", map[string]interface{}{
+			"endpoint":  "SearchAvailableSlots",
+			"doctorIDs": req.DoctorIDs,
+			"dateFrom":  req.DateFrom,
+			"dateTo":    req.DateTo,
+			"error":     err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.PaginatedResponse{
+		Success: true,
+		Data:    slots,
+		Total:   total,
+		Page:    req.Page,
+		Limit:   req.Limit,
+	})
+}
+
+// === FOLLOW-UP ENDPOINTS ===
+
+// BookFollowUp - POST /appointments/:id/follow-up (врач записывает пациента приема на повторный)
+func (h *AppointmentHandler) BookFollowUp(c echo.Context) error {
+	doctorID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	var req models.BookFollowUpRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointment, err := h.service.BookFollowUp(doctorID, appointmentID, &req)
+	if err != nil {
+		h.logError("Failed to book follow-up", map[string]interface{}{
+			"endpoint":      "BookFollowUp",
+			"doctorID":      doctorID.String(),
+			"appointmentID": appointmentID.String(),
+			"slotID":        req.SlotID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data:    appointment,
+	})
+}
+
+// ProposeFollowUp - POST /appointments/:id/follow-up/proposals (врач предлагает слоты на выбор)
+func (h *AppointmentHandler) ProposeFollowUp(c echo.Context) error {
+	doctorID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	var req models.ProposeFollowUpRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	proposal, err := h.service.ProposeFollowUp(doctorID, appointmentID, &req)
+	if err != nil {
+		h.logError("Failed to propose follow-up", map[string]interface{}{
+			"endpoint":      "ProposeFollowUp",
+			"doctorID":      doctorID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data:    proposal,
+	})
+}
+
+// GetFollowUpProposals - GET /appointments/follow-ups?status=pending (предложения пациенту)
+func (h *AppointmentHandler) GetFollowUpProposals(c echo.Context) error {
+	patientID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	proposals, err := h.service.GetPatientFollowUpProposals(patientID, c.QueryParam("status"))
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    proposals,
+	})
+}
+
+// AcceptFollowUpProposal - POST /appointments/follow-ups/:id/accept (пациент выбирает слот)
+func (h *AppointmentHandler) AcceptFollowUpProposal(c echo.Context) error {
+	patientID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	proposalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid proposal ID",
+		})
+	}
+
+	var req models.AcceptFollowUpRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	appointment, err := h.service.AcceptFollowUpProposal(patientID, proposalID, &req)
+	if err != nil {
+		return c.JSON(errorStatus(err), bookingErrorResponse(err))
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    appointment,
+	})
+}
+
+// GetVisitChain - GET /appointments/:id/chain (первый прием и все повторные)
+func (h *AppointmentHandler) GetVisitChain(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	chain, err := h.service.GetVisitChain(userID, role, appointmentID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    chain,
+	})
+}
+
+// === ATTACHMENT ENDPOINTS ===
+
+// AttachFile - POST /appointments/:id/attachments
+// Участник приема прикрепляет к записи свой файл из FileServer
+func (h *AppointmentHandler) AttachFile(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	var req models.AttachFileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+	attachment, err := h.service.AttachFile(userID, role, appointmentID, token, &req)
+	if err != nil {
+		h.logError("Failed to attach file", map[string]interface{}{
+			"endpoint":      "AttachFile",
+			"userID":        userID.String(),
+			"appointmentID": appointmentID.String(),
+			"fileID":        req.FileID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data:    attachment,
+	})
+}
+
+// GetAttachments - GET /appointments/:id/attachments
+// Файлы записи; на файлы другого участника ссылки действуют, пока прием активен
+func (h *AppointmentHandler) GetAttachments(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	attachments, err := h.service.GetAttachments(userID, role, appointmentID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    attachments,
+	})
+}
+
+// DetachFile - DELETE /appointments/:id/attachments/:fileId
+// Открепляет свой файл от активной записи; сам файл в FileServer не удаляется
+func (h *AppointmentHandler) DetachFile(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	fileID, err := uuid.Parse(c.Param("fileId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid file ID",
+		})
+	}
+
+	if err := h.service.DetachFile(userID, role, appointmentID, fileID); err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    "File detached successfully",
+	})
+}
+
+// === BOOKING POLICY ENDPOINTS ===
+
+// GetBookingPolicy - GET /appointments/policy
+// Правила записи текущего врача
+func (h *AppointmentHandler) GetBookingPolicy(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	policy, err := h.service.GetBookingPolicy(userID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    policy,
+	})
+}
+
+// UpdateBookingPolicy - PUT /appointments/policy
+func (h *AppointmentHandler) UpdateBookingPolicy(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	var req models.UpdateBookingPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	policy, err := h.service.UpdateBookingPolicy(userID, &req)
+	if err != nil {
+		h.logError("Failed to update booking policy", map[string]interface{}{
+			"endpoint": "UpdateBookingPolicy",
+			"userID":   userID.String(),
+			"error":    err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    policy,
+	})
+}
+
+// GetDoctorBookingPolicy - GET /appointments/doctors/:id/policy
+// Правила записи врача для пациента: до записи видно, когда можно записаться и отменить запись
+func (h *AppointmentHandler) GetDoctorBookingPolicy(c echo.Context) error {
+	doctorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid doctor ID",
+		})
+	}
+
+	policy, err := h.service.GetBookingPolicy(doctorID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    policy,
+	})
+}
+
+// === ANALYTICS ENDPOINTS ===
+
+// GetDoctorAnalytics - GET /appointments/analytics?date_from=2024-06-01&date_to=2024-06-30&bucket=week&timezone=Asia/Almaty
+func (h *AppointmentHandler) GetDoctorAnalytics(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	var req models.DoctorAnalyticsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid query parameters",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	analytics, err := h.service.GetDoctorAnalytics(userID, &req)
+	if err != nil {
+		h.logError("Failed to get doctor analytics", map[string]interface{}{
+			"endpoint": "GetDoctorAnalytics",
+			"userID":   userID.String(),
+			"dateFrom": req.DateFrom,
+			"dateTo":   req.DateTo,
+			"error":    err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    analytics,
+	})
+}
+
+// === EXCEPTION ENDPOINTS ===
+
+// AddException - POST /api/doctor/exceptions
+func (h *AppointmentHandler) AddException(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	var req models.AddExceptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	// Валидация входящих данных
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	exception, err := h.service.AddException(userID, &req)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data:    exception,
+	})
+}
+
+// GetDoctorExceptions - GET /api/doctor/exceptions?start_date=2024-06-01&end_date=2024-06-30
+func (h *AppointmentHandler) GetDoctorExceptions(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	startDate := c.QueryParam("start_date")
+	endDate := c.QueryParam("end_date")
+
+	if startDate == "" || endDate == "" {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "start_date and end_date parameters are required",
+		})
+	}
+
+	exceptions, err := h.service.GetDoctorExceptions(userID, startDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    exceptions,
+	})
+}
+
+// UpdateException - PUT /appointments/exceptions/:id
+func (h *AppointmentHandler) UpdateException(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	exceptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid exception ID",
+		})
+	}
+
+	var req models.UpdateExceptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	exception, err := h.service.UpdateException(userID, exceptionID, &req)
+	if err != nil {
+		h.logError("Failed to update exception", map[string]interface{}{
+			"endpoint":    "UpdateException",
+			"userID":      userID.String(),
+			"exceptionID": exceptionID.String(),
+			"error":       err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    exception,
+	})
+}
+
+// DeleteException - DELETE /appointments/exceptions/:id
+func (h *AppointmentHandler) DeleteException(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	exceptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid exception ID",
+		})
+	}
+
+	if err := h.service.DeleteException(userID, exceptionID); err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    "Exception deleted successfully",
+	})
+}
+
+// === PAYMENT ENDPOINTS ===
+
+// PaymentWebhook - POST /appointments/payments/webhook
+// Уведомление платежного провайдера об оплате; подлинность проверяет провайдер по подписи
+func (h *AppointmentHandler) PaymentWebhook(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, 1<<20))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := h.service.HandlePaymentConfirmation(body, c.Request().Header); err != nil {
+		h.logError("Failed to handle payment notification", map[string]interface{}{
+			"endpoint": "PaymentWebhook",
+			"error":    err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    "Payment notification processed",
+	})
+}
+
+// === STATUS ENDPOINTS ===
+
+// CheckInAppointment - POST /appointments/:id/check-in
+func (h *AppointmentHandler) CheckInAppointment(c echo.Context) error {
+	return h.changeAppointmentStatus(c, "CheckInAppointment", h.service.CheckInAppointment)
+}
+
+// StartAppointment - POST /appointments/:id/start
+func (h *AppointmentHandler) StartAppointment(c echo.Context) error {
+	return h.changeAppointmentStatus(c, "StartAppointment", h.service.StartAppointment)
+}
+
+// MarkNoShow - POST /appointments/:id/no-show
+func (h *AppointmentHandler) MarkNoShow(c echo.Context) error {
+	var req models.MarkNoShowRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return h.changeAppointmentStatus(c, "MarkNoShow", func(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error) {
+		return h.service.MarkNoShow(doctorID, appointmentID, &req)
+	})
+}
+
+// changeAppointmentStatus - общий обработчик переходов статуса записи врачом
+func (h *AppointmentHandler) changeAppointmentStatus(c echo.Context, endpoint string, change func(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error)) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	appointment, err := change(userID, appointmentID)
+	if err != nil {
+		h.logError("Failed to change appointment status", map[string]interface{}{
+			"endpoint":      endpoint,
+			"userID":        userID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    appointment,
+	})
+}
+
+// GetAppointmentStatusHistory - GET /appointments/:id/history
+func (h *AppointmentHandler) GetAppointmentStatusHistory(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	history, err := h.service.GetAppointmentStatusHistory(userID, role, appointmentID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
-			Error:   "start_date and end_date parameters are required",
+			Error:   err.Error(),
 		})
 	}
 
-	exceptions, err := h.service.GetDoctorExceptions(userID, startDate, endDate)
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    history,
+	})
+}
+
+// GetParticipants - GET /appointments/:id/participants (врач)
+func (h *AppointmentHandler) GetParticipants(c echo.Context) error {
+	doctorID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.APIResponse{
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	participants, err := h.service.GetParticipants(doctorID, appointmentID)
+	if err != nil {
+		h.logError("Failed to get participants", map[string]interface{}{
+			"endpoint":      "GetParticipants",
+			"doctorID":      doctorID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -810,10 +2166,322 @@ func (h *AppointmentHandler) GetDoctorExceptions(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Data:    exceptions,
+		Data:    participants,
+	})
+}
+
+// GetMeetingJoin - GET /appointments/:id/meeting
+// Личная ссылка врача или пациента на вход во встречу онлайн-приема
+func (h *AppointmentHandler) GetMeetingJoin(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	join, err := h.service.GetMeetingJoin(userID, role, appointmentID)
+	if err != nil {
+		h.logError("Failed to get meeting join link", map[string]interface{}{
+			"endpoint":      "GetMeetingJoin",
+			"userID":        userID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    join,
+	})
+}
+
+// === EXTERNAL CALENDAR ENDPOINTS ===
+
+// ImportCalendar - POST /appointments/exceptions/import
+// Принимает .ics файлом в multipart-поле "file" или телом запроса (Content-Type: text/calendar)
+func (h *AppointmentHandler) ImportCalendar(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	body := c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Calendar file is required in field \"file\"",
+			})
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Failed to read calendar file",
+			})
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.service.ImportCalendar(userID, body)
+	if err != nil {
+		h.logError("Failed to import calendar", map[string]interface{}{
+			"userID": userID.String(),
+			"error":  err.Error(),
+		})
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// AddExternalCalendar - POST /appointments/exceptions/calendars
+func (h *AppointmentHandler) AddExternalCalendar(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	var req models.RegisterExternalCalendarRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	result, err := h.service.AddExternalCalendar(userID, &req)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// GetExternalCalendars - GET /appointments/exceptions/calendars
+func (h *AppointmentHandler) GetExternalCalendars(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	calendars, err := h.service.GetExternalCalendars(userID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    calendars,
+	})
+}
+
+// DeleteExternalCalendar - DELETE /appointments/exceptions/calendars/:id
+func (h *AppointmentHandler) DeleteExternalCalendar(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	calendarID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid calendar ID",
+		})
+	}
+
+	if err := h.service.DeleteExternalCalendar(userID, calendarID); err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    "External calendar deleted successfully",
+	})
+}
+
+// SyncExternalCalendar - POST /appointments/exceptions/calendars/:id/sync
+func (h *AppointmentHandler) SyncExternalCalendar(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+
+	calendarID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid calendar ID",
+		})
+	}
+
+	result, err := h.service.SyncExternalCalendar(userID, calendarID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// === CALENDAR ENDPOINTS ===
+
+// GetCalendarFeed - GET /appointments/calendar/feed
+func (h *AppointmentHandler) GetCalendarFeed(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	feed, err := h.service.GetCalendarFeed(userID, role)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    feed,
+	})
+}
+
+// RotateCalendarFeed - POST /appointments/calendar/feed/rotate
+func (h *AppointmentHandler) RotateCalendarFeed(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	feed, err := h.service.RotateCalendarFeed(userID, role)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data:    feed,
 	})
 }
 
+// GetCalendarFeedICS - GET /appointments/calendar/feeds/:token (без JWT, доступ по секретному токену)
+func (h *AppointmentHandler) GetCalendarFeedICS(c echo.Context) error {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	calendar, err := h.service.GetCalendarFeedICS(token)
+	if err != nil {
+		return c.String(errorStatus(err), err.Error())
+	}
+
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
+
+// GetAppointmentICS - GET /appointments/:id/ics
+func (h *AppointmentHandler) GetAppointmentICS(c echo.Context) error {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user ID in token",
+		})
+	}
+	role, _ := c.Get("role").(string)
+
+	appointmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid appointment ID",
+		})
+	}
+
+	calendar, err := h.service.GetAppointmentICS(userID, role, appointmentID)
+	if err != nil {
+		return c.JSON(errorStatus(err), models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"appointment-%s.ics\"", appointmentID))
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
+
 // === HEALTH CHECK ===
 
 // HealthCheck - GET /health
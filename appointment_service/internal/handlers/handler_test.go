@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/printprince/vitalem/appointment_service/internal/service"
+)
+
+func TestErrorStatusSlotTakenIsConflict(t *testing.T) {
+	for _, err := range []error{
+		service.ErrSlotTaken,
+		fmt.Errorf("%w: appointment was booked concurrently", service.ErrSlotTaken),
+	} {
+		if status := errorStatus(err); status != http.StatusConflict {
+			t.Errorf("errorStatus(%v) = %d, want %d", err, status, http.StatusConflict)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package ical
+
+import (
+	"strings"
+	"time"
+)
+
+// Calendar - календарь iCalendar (RFC 5545)
+type Calendar struct {
+	Name   string // X-WR-CALNAME, отображаемое имя календаря
+	Events []Event
+}
+
+// Event - событие календаря (VEVENT)
+type Event struct {
+	UID          string // стабильный идентификатор: по нему календарь обновляет событие, а не дублирует его
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Status       string // CONFIRMED, TENTATIVE, CANCELLED
+	LastModified time.Time
+}
+
+const (
+	productID      = "-//Vitalem//Appointments//RU"
+	utcLayout      = "20060102T150405Z"
+	maxLineOctets  = 75
+	lineTerminator = "\r\n"
+)
+
+// Encode - сериализует календарь в формат iCalendar
+func (c *Calendar) Encode() string {
+	var b strings.Builder
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+productID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	now := time.Now()
+	for _, event := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+formatUTC(now))
+		writeLine(&b, "DTSTART:"+formatUTC(event.Start))
+		writeLine(&b, "DTEND:"+formatUTC(event.End))
+		if !event.LastModified.IsZero() {
+			writeLine(&b, "LAST-MODIFIED:"+formatUTC(event.LastModified))
+		}
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Location != "" {
+			writeLine(&b, "LOCATION:"+escapeText(event.Location))
+		}
+		if event.URL != "" {
+			writeLine(&b, "URL:"+event.URL)
+		}
+		if event.Status != "" {
+			writeLine(&b, "STATUS:"+event.Status)
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
+
+// escapeText - экранирование значений типа TEXT (RFC 5545, 3.3.11)
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// writeLine - записывает строку контента с переносом длинных строк по 75 октетов (RFC 5545, 3.1).
+// Перенос не разрывает многобайтовые символы UTF-8
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString(lineTerminator)
+		b.WriteString(" ")
+		line = line[cut:]
+		// Продолжение начинается с пробела, который тоже считается в длину строки
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString(lineTerminator)
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,417 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// BusyEvent - занятое время из внешнего календаря
+type BusyEvent struct {
+	UID     string // UID события; у вхождений повторяющихся событий - UID/начало вхождения
+	Start   time.Time
+	End     time.Time
+	Summary string
+}
+
+// ErrNoCalendar - во входных данных нет VCALENDAR
+var ErrNoCalendar = errors.New("no VCALENDAR found")
+
+// ParseBusy - разбирает iCalendar и возвращает занятое время, пересекающее [from, until).
+// Пропускаются отмененные (STATUS:CANCELLED) и прозрачные (TRANSP:TRANSPARENT) события.
+// Время без часового пояса (floating) трактуется в defaultLocation.
+// Повторяющиеся события (RRULE, RDATE) разворачиваются в отдельные вхождения до until без EXDATE;
+// измененные и отмененные экземпляры (RECURRENCE-ID) заменяют соответствующие вхождения.
+// Правила, которые не удается развернуть, дают только первое вхождение
+func ParseBusy(r io.Reader, defaultLocation *time.Location, from, until time.Time) ([]BusyEvent, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		components []*component
+		inCalendar bool
+		current    *component
+	)
+
+	for _, line := range lines {
+		prop := parseProperty(line)
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCALENDAR"):
+			inCalendar = true
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT") && inCalendar:
+			current = &component{props: make(map[string]property)}
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT") && current != nil:
+			components = append(components, current)
+			current = nil
+		case current != nil:
+			current.add(prop)
+		}
+	}
+
+	if !inCalendar {
+		return nil, ErrNoCalendar
+	}
+
+	// Экземпляры с RECURRENCE-ID заменяют вхождения правила, даже если сами отменены
+	overridden := make(map[string]bool)
+	for _, vevent := range components {
+		if key, ok := vevent.recurrenceKey(defaultLocation); ok {
+			overridden[vevent.props["UID"].value+"/"+key] = true
+		}
+	}
+
+	var events []BusyEvent
+	for _, vevent := range components {
+		event, ok := buildBusyEvent(vevent.props, defaultLocation)
+		if !ok {
+			continue
+		}
+
+		if key, ok := vevent.recurrenceKey(defaultLocation); ok {
+			event.UID += "/" + key
+			if event.End.After(from) {
+				events = append(events, event)
+			}
+			continue
+		}
+
+		if !vevent.isRecurring() {
+			if event.End.After(from) {
+				events = append(events, event)
+			}
+			continue
+		}
+
+		for _, occurrence := range vevent.occurrences(event, defaultLocation, from, until) {
+			if overridden[event.UID+"/"+occurrence.UID] {
+				continue
+			}
+			occurrence.UID = event.UID + "/" + occurrence.UID
+			events = append(events, occurrence)
+		}
+	}
+
+	return events, nil
+}
+
+// component - свойства VEVENT. Одиночные свойства - первое вхождение (VALARM и прочие вложенные
+// компоненты не мешают, т.к. их свойства не пересекаются с нужными); EXDATE и RDATE - все вхождения
+type component struct {
+	props   map[string]property
+	exdates []property
+	rdates  []property
+}
+
+func (c *component) add(prop property) {
+	switch prop.name {
+	case "EXDATE":
+		c.exdates = append(c.exdates, prop)
+	case "RDATE":
+		c.rdates = append(c.rdates, prop)
+	default:
+		if _, exists := c.props[prop.name]; !exists {
+			c.props[prop.name] = prop
+		}
+	}
+}
+
+func (c *component) isRecurring() bool {
+	_, hasRule := c.props["RRULE"]
+	return hasRule || len(c.rdates) > 0
+}
+
+// recurrenceKey - ключ экземпляра повторяющегося события, который заменяет этот VEVENT
+func (c *component) recurrenceKey(defaultLocation *time.Location) (string, bool) {
+	prop, ok := c.props["RECURRENCE-ID"]
+	if !ok {
+		return "", false
+	}
+	start, allDay, err := parseDateTime(prop, defaultLocation)
+	if err != nil {
+		return "", false
+	}
+	return occurrenceKey(start, allDay), true
+}
+
+// occurrences - вхождения повторяющегося события, пересекающие [from, until), без EXDATE.
+// UID вхождения - ключ его начала, к нему добавляется UID события
+func (c *component) occurrences(event BusyEvent, defaultLocation *time.Location, from, until time.Time) []BusyEvent {
+	_, allDay, _ := parseDateTime(c.props["DTSTART"], defaultLocation)
+	duration := event.End.Sub(event.Start)
+
+	var starts []time.Time
+	if ruleProp, ok := c.props["RRULE"]; ok {
+		rule, err := parseRecurrence(ruleProp.value, event.Start.Location())
+		if err == nil {
+			starts = rule.occurrences(event.Start, duration, from, until)
+		} else if event.End.After(from) {
+			starts = append(starts, event.Start)
+		}
+	} else if event.End.After(from) && event.Start.Before(until) {
+		starts = append(starts, event.Start)
+	}
+
+	for _, rdate := range c.rdates {
+		for _, start := range parseDateList(rdate, defaultLocation) {
+			if start.Add(duration).After(from) && start.Before(until) {
+				starts = append(starts, start)
+			}
+		}
+	}
+
+	excluded := make(map[string]bool)
+	for _, exdate := range c.exdates {
+		for _, start := range parseDateList(exdate, defaultLocation) {
+			excluded[occurrenceKey(start, allDay)] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	occurrences := make([]BusyEvent, 0, len(starts))
+	for _, start := range starts {
+		key := occurrenceKey(start, allDay)
+		if excluded[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		end := start.Add(duration)
+		if allDay {
+			// Целые дни остаются целыми и при переходе на летнее время
+			end = start.AddDate(0, 0, int(duration.Round(24*time.Hour)/(24*time.Hour)))
+		}
+		occurrences = append(occurrences, BusyEvent{
+			UID:     key,
+			Start:   start,
+			End:     end,
+			Summary: event.Summary,
+		})
+	}
+	return occurrences
+}
+
+// parseDateList - значения EXDATE или RDATE через запятую; периоды (VALUE=PERIOD) пропускаются
+func parseDateList(prop property, defaultLocation *time.Location) []time.Time {
+	if prop.params["VALUE"] == "PERIOD" {
+		return nil
+	}
+
+	var dates []time.Time
+	for _, value := range strings.Split(prop.value, ",") {
+		item := property{name: prop.name, params: prop.params, value: strings.TrimSpace(value)}
+		if t, _, err := parseDateTime(item, defaultLocation); err == nil {
+			dates = append(dates, t)
+		}
+	}
+	return dates
+}
+
+// occurrenceKey - ключ вхождения: дата для событий на весь день, иначе начало в UTC
+func occurrenceKey(start time.Time, allDay bool) string {
+	if allDay {
+		return start.Format("20060102")
+	}
+	return start.UTC().Format(utcLayout)
+}
+
+// property - строка контента: NAME;PARAM=VALUE:value
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold - склеивает перенесенные строки (строка, начинающаяся с пробела или табуляции, продолжает предыдущую)
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+func parseProperty(line string) property {
+	prop := property{params: make(map[string]string)}
+
+	// Значение начинается после первого двоеточия вне кавычек
+	colon := -1
+	inQuotes := false
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		}
+		if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		prop.name = strings.ToUpper(line)
+		return prop
+	}
+
+	head := line[:colon]
+	prop.value = line[colon+1:]
+
+	parts := strings.Split(head, ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop
+}
+
+func buildBusyEvent(props map[string]property, defaultLocation *time.Location) (BusyEvent, bool) {
+	if status, ok := props["STATUS"]; ok && strings.EqualFold(status.value, "CANCELLED") {
+		return BusyEvent{}, false
+	}
+	if transp, ok := props["TRANSP"]; ok && strings.EqualFold(transp.value, "TRANSPARENT") {
+		return BusyEvent{}, false
+	}
+
+	uid, ok := props["UID"]
+	if !ok || uid.value == "" {
+		return BusyEvent{}, false
+	}
+	startProp, ok := props["DTSTART"]
+	if !ok {
+		return BusyEvent{}, false
+	}
+
+	start, allDay, err := parseDateTime(startProp, defaultLocation)
+	if err != nil {
+		return BusyEvent{}, false
+	}
+
+	var end time.Time
+	if endProp, ok := props["DTEND"]; ok {
+		end, _, err = parseDateTime(endProp, defaultLocation)
+		if err != nil {
+			return BusyEvent{}, false
+		}
+	} else if durationProp, ok := props["DURATION"]; ok {
+		duration, err := parseDuration(durationProp.value)
+		if err != nil {
+			return BusyEvent{}, false
+		}
+		end = start.Add(duration)
+	} else if allDay {
+		end = start.AddDate(0, 0, 1)
+	} else {
+		end = start
+	}
+
+	if !end.After(start) {
+		return BusyEvent{}, false
+	}
+
+	event := BusyEvent{
+		UID:   uid.value,
+		Start: start,
+		End:   end,
+	}
+	if summary, ok := props["SUMMARY"]; ok {
+		event.Summary = unescapeText(summary.value)
+	}
+	return event, true
+}
+
+// parseDateTime - DATE-TIME в UTC (Z), с TZID или плавающее время; DATE - весь день
+func parseDateTime(prop property, defaultLocation *time.Location) (time.Time, bool, error) {
+	value := prop.value
+	location := defaultLocation
+	if tzid, ok := prop.params["TZID"]; ok {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			location = loc
+		}
+	}
+
+	if prop.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+// parseDuration - длительность RFC 5545 вида P1D, PT1H30M, P1W
+func parseDuration(value string) (time.Duration, error) {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	value = value[1:]
+
+	var (
+		total  time.Duration
+		number int
+		inTime bool
+		digits bool
+	)
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			number = number*10 + int(c-'0')
+			digits = true
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		switch {
+		case c == 'W':
+			total += time.Duration(number) * 7 * 24 * time.Hour
+		case c == 'D':
+			total += time.Duration(number) * 24 * time.Hour
+		case c == 'H' && inTime:
+			total += time.Duration(number) * time.Hour
+		case c == 'M' && inTime:
+			total += time.Duration(number) * time.Minute
+		case c == 'S' && inTime:
+			total += time.Duration(number) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number, digits = 0, false
+	}
+
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
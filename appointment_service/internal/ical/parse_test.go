@@ -0,0 +1,103 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseCalendar(t *testing.T, events string, from, until time.Time) []BusyEvent {
+	t.Helper()
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + events + "END:VCALENDAR\r\n"
+	busy, err := ParseBusy(strings.NewReader(data), time.UTC, from, until)
+	if err != nil {
+		t.Fatalf("ParseBusy: %v", err)
+	}
+	return busy
+}
+
+func starts(events []BusyEvent) []string {
+	result := make([]string, len(events))
+	for i, event := range events {
+		result[i] = event.Start.UTC().Format("2006-01-02 15:04")
+	}
+	return result
+}
+
+func assertStarts(t *testing.T, events []BusyEvent, want ...string) {
+	t.Helper()
+	got := starts(events)
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("occurrences = %v, want %v", got, want)
+	}
+}
+
+func TestParseBusyWeeklyRuleExpandsWithinWindow(t *testing.T) {
+	events := parseCalendar(t, ""+
+		"BEGIN:VEVENT\r\nUID:standup\r\nDTSTART:20240101T090000Z\r\nDTEND:20240101T100000Z\r\n"+
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\nEXDATE:20240612T090000Z\r\nEND:VEVENT\r\n",
+		time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC))
+
+	assertStarts(t, events, "2024-06-10 09:00", "2024-06-17 09:00", "2024-06-19 09:00")
+	if events[0].UID != "standup/20240610T090000Z" || !events[0].End.Equal(events[0].Start.Add(time.Hour)) {
+		t.Fatalf("unexpected occurrence %+v", events[0])
+	}
+}
+
+func TestParseBusyCountAndUntil(t *testing.T) {
+	from, until := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assertStarts(t, parseCalendar(t, "BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20240301T080000Z\r\nDURATION:PT30M\r\n"+
+		"RRULE:FREQ=DAILY;INTERVAL=2;COUNT=3\r\nEND:VEVENT\r\n", from, until),
+		"2024-03-01 08:00", "2024-03-03 08:00", "2024-03-05 08:00")
+
+	assertStarts(t, parseCalendar(t, "BEGIN:VEVENT\r\nUID:b\r\nDTSTART:20240301T080000Z\r\nDURATION:PT30M\r\n"+
+		"RRULE:FREQ=DAILY;UNTIL=20240303\r\nEND:VEVENT\r\n", from, until),
+		"2024-03-01 08:00", "2024-03-02 08:00", "2024-03-03 08:00")
+}
+
+func TestParseBusyRecurrenceOverrides(t *testing.T) {
+	events := parseCalendar(t, ""+
+		"BEGIN:VEVENT\r\nUID:gym\r\nDTSTART:20240603T180000Z\r\nDTEND:20240603T190000Z\r\nRRULE:FREQ=DAILY;COUNT=3\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nUID:gym\r\nRECURRENCE-ID:20240604T180000Z\r\nDTSTART:20240604T200000Z\r\nDTEND:20240604T210000Z\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nUID:gym\r\nRECURRENCE-ID:20240605T180000Z\r\nDTSTART:20240605T180000Z\r\nDTEND:20240605T190000Z\r\nSTATUS:CANCELLED\r\nEND:VEVENT\r\n",
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	assertStarts(t, events, "2024-06-03 18:00", "2024-06-04 20:00")
+	if events[1].UID != "gym/20240604T180000Z" {
+		t.Fatalf("override should replace occurrence by its key, got UID %q", events[1].UID)
+	}
+}
+
+func TestParseBusyMonthlyOrdinalWeekday(t *testing.T) {
+	events := parseCalendar(t, "BEGIN:VEVENT\r\nUID:board\r\nDTSTART:20240126T100000Z\r\nDTEND:20240126T110000Z\r\n"+
+		"RRULE:FREQ=MONTHLY;BYDAY=-1FR\r\nEND:VEVENT\r\n",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+
+	assertStarts(t, events, "2024-01-26 10:00", "2024-02-23 10:00", "2024-03-29 10:00")
+}
+
+func TestParseBusyKeepsLocalTimeAcrossDST(t *testing.T) {
+	events := parseCalendar(t, "BEGIN:VEVENT\r\nUID:clinic\r\nDTSTART;TZID=Europe/Berlin:20240322T090000\r\n"+
+		"DTEND;TZID=Europe/Berlin:20240322T100000\r\nRRULE:FREQ=WEEKLY\r\nEND:VEVENT\r\n",
+		time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC))
+
+	assertStarts(t, events, "2024-03-22 08:00", "2024-03-29 08:00", "2024-04-05 07:00")
+}
+
+func TestParseBusyUnsupportedRuleKeepsFirstOccurrence(t *testing.T) {
+	events := parseCalendar(t, "BEGIN:VEVENT\r\nUID:odd\r\nDTSTART:20240601T090000Z\r\nDTEND:20240601T100000Z\r\n"+
+		"RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1\r\nEND:VEVENT\r\n",
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC))
+
+	assertStarts(t, events, "2024-06-01 09:00")
+}
+
+func TestParseBusySkipsEndedEvents(t *testing.T) {
+	events := parseCalendar(t, "BEGIN:VEVENT\r\nUID:old\r\nDTSTART:20200101T090000Z\r\nDTEND:20200101T100000Z\r\nEND:VEVENT\r\n",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	if len(events) != 0 {
+		t.Fatalf("ended event should be skipped, got %v", starts(events))
+	}
+}
@@ -0,0 +1,325 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrencePeriods - предел перебора периодов правила (дней, недель, месяцев или лет от DTSTART).
+// Защищает от бесконечных правил, начатых очень давно
+const maxRecurrencePeriods = 20000
+
+// errUnsupportedRule - правило использует части RRULE, которые не разворачиваются
+var errUnsupportedRule = errors.New("unsupported recurrence rule")
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// weekdayRule - значение BYDAY: день недели с необязательным номером в месяце (2TU, -1FR)
+type weekdayRule struct {
+	ordinal int
+	weekday time.Weekday
+}
+
+// recurrence - правило повторения RRULE. Разворачиваются FREQ=DAILY, WEEKLY, MONTHLY и YEARLY
+// с INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY и BYMONTH; остальные правила - errUnsupportedRule
+type recurrence struct {
+	freq       string
+	interval   int
+	count      int
+	until      *time.Time // последнее допустимое начало вхождения, включительно
+	byDay      []weekdayRule
+	byMonthDay []int
+	byMonth    []time.Month
+}
+
+func parseRecurrence(value string, location *time.Location) (*recurrence, error) {
+	rule := &recurrence{interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		val = strings.ToUpper(strings.TrimSpace(val))
+
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "FREQ":
+			rule.freq = val
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.count = count
+		case "UNTIL":
+			until, allDay, err := parseDateTime(property{value: val, params: map[string]string{}}, location)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			if allDay {
+				until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			rule.until = &until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				day, err := parseWeekdayRule(item)
+				if err != nil {
+					return nil, err
+				}
+				rule.byDay = append(rule.byDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(item)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+				}
+				rule.byMonthDay = append(rule.byMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				month, err := strconv.Atoi(item)
+				if err != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", item)
+				}
+				rule.byMonth = append(rule.byMonth, time.Month(month))
+			}
+		case "WKST":
+			// Неделя считается с понедельника
+		default:
+			return nil, fmt.Errorf("%w: %s", errUnsupportedRule, key)
+		}
+	}
+
+	switch rule.freq {
+	case "DAILY", "MONTHLY":
+	case "WEEKLY":
+		if len(rule.byMonthDay) > 0 {
+			return nil, fmt.Errorf("%w: BYMONTHDAY with FREQ=WEEKLY", errUnsupportedRule)
+		}
+	case "YEARLY":
+		if (len(rule.byDay) > 0 || len(rule.byMonthDay) > 0) && len(rule.byMonth) == 0 {
+			return nil, fmt.Errorf("%w: yearly BYDAY or BYMONTHDAY without BYMONTH", errUnsupportedRule)
+		}
+	default:
+		return nil, fmt.Errorf("%w: FREQ=%s", errUnsupportedRule, rule.freq)
+	}
+
+	return rule, nil
+}
+
+func parseWeekdayRule(value string) (weekdayRule, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 {
+		return weekdayRule{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	weekday, ok := weekdayCodes[value[len(value)-2:]]
+	if !ok {
+		return weekdayRule{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	rule := weekdayRule{weekday: weekday}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		ordinal, err := strconv.Atoi(prefix)
+		if err != nil || ordinal == 0 || ordinal < -5 || ordinal > 5 {
+			return weekdayRule{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+		rule.ordinal = ordinal
+	}
+	return rule, nil
+}
+
+// occurrences - начала вхождений правила, которые пересекают [from, until) при длительности duration.
+// DTSTART всегда первое вхождение и учитывается в COUNT, даже если не подходит под правило
+func (r *recurrence) occurrences(dtstart time.Time, duration time.Duration, from, until time.Time) []time.Time {
+	var (
+		result  []time.Time
+		emitted int
+	)
+	// emit - учитывает вхождение; false - вхождений больше не будет
+	emit := func(start time.Time) bool {
+		if (r.until != nil && start.After(*r.until)) || !start.Before(until) {
+			return false
+		}
+		if start.Add(duration).After(from) {
+			result = append(result, start)
+		}
+		emitted++
+		return r.count == 0 || emitted < r.count
+	}
+
+	if !emit(dtstart) {
+		return result
+	}
+	for period := 1; period < maxRecurrencePeriods; period++ {
+		for _, candidate := range r.periodCandidates(dtstart, period*r.interval) {
+			if !candidate.After(dtstart) {
+				continue
+			}
+			if !emit(candidate) {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// periodCandidates - вхождения n-го периода от DTSTART по порядку (период 0 - период самого DTSTART)
+func (r *recurrence) periodCandidates(dtstart time.Time, n int) []time.Time {
+	var candidates []time.Time
+
+	switch r.freq {
+	case "DAILY":
+		day := dtstart.AddDate(0, 0, n)
+		if r.matchesDay(day) && r.matchesMonth(day) {
+			candidates = append(candidates, day)
+		}
+	case "WEEKLY":
+		weekStart := dtstart.AddDate(0, 0, -daysFromMonday(dtstart.Weekday())+7*n)
+		weekdays := []time.Weekday{dtstart.Weekday()}
+		if len(r.byDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, day := range r.byDay {
+				weekdays = append(weekdays, day.weekday)
+			}
+		}
+		for _, weekday := range weekdays {
+			day := weekStart.AddDate(0, 0, daysFromMonday(weekday))
+			if r.matchesMonth(day) {
+				candidates = append(candidates, day)
+			}
+		}
+	case "MONTHLY":
+		first := dayInMonth(dtstart, dtstart.Year(), dtstart.Month()+time.Month(n), 1)
+		if r.matchesMonth(first) {
+			candidates = r.monthCandidates(dtstart, first)
+		}
+	case "YEARLY":
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []time.Month{dtstart.Month()}
+		}
+		for _, month := range months {
+			candidates = append(candidates, r.monthCandidates(dtstart, dayInMonth(dtstart, dtstart.Year()+n, month, 1))...)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Before(candidates[j])
+	})
+	return candidates
+}
+
+// monthCandidates - вхождения месяца, начинающегося с first, по BYMONTHDAY и BYDAY;
+// без них - день месяца DTSTART (месяцы без такого дня пропускаются)
+func (r *recurrence) monthCandidates(dtstart, first time.Time) []time.Time {
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	var candidates []time.Time
+
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, monthDay := range r.byMonthDay {
+			if monthDay < 0 {
+				monthDay = daysInMonth + monthDay + 1
+			}
+			if monthDay < 1 || monthDay > daysInMonth {
+				continue
+			}
+			day := dayInMonth(dtstart, first.Year(), first.Month(), monthDay)
+			if r.matchesDay(day) {
+				candidates = append(candidates, day)
+			}
+		}
+	case len(r.byDay) > 0:
+		for _, rule := range r.byDay {
+			var matching []time.Time
+			for monthDay := 1; monthDay <= daysInMonth; monthDay++ {
+				day := dayInMonth(dtstart, first.Year(), first.Month(), monthDay)
+				if day.Weekday() == rule.weekday {
+					matching = append(matching, day)
+				}
+			}
+			switch {
+			case rule.ordinal == 0:
+				candidates = append(candidates, matching...)
+			case rule.ordinal > 0 && rule.ordinal <= len(matching):
+				candidates = append(candidates, matching[rule.ordinal-1])
+			case rule.ordinal < 0 && -rule.ordinal <= len(matching):
+				candidates = append(candidates, matching[len(matching)+rule.ordinal])
+			}
+		}
+	default:
+		if dtstart.Day() <= daysInMonth {
+			candidates = append(candidates, dayInMonth(dtstart, first.Year(), first.Month(), dtstart.Day()))
+		}
+	}
+	return candidates
+}
+
+// matchesDay - день подходит под BYDAY (номер в месяце здесь не учитывается) и BYMONTHDAY
+func (r *recurrence) matchesDay(day time.Time) bool {
+	if len(r.byDay) > 0 {
+		found := false
+		for _, rule := range r.byDay {
+			if rule.weekday == day.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.byMonthDay) > 0 && r.freq == "DAILY" {
+		daysInMonth := day.AddDate(0, 1, -day.Day()).Day()
+		found := false
+		for _, monthDay := range r.byMonthDay {
+			if monthDay == day.Day() || daysInMonth+monthDay+1 == day.Day() {
+				found = true
+				break
+			}
+		}
+		return found
+	}
+	return true
+}
+
+func (r *recurrence) matchesMonth(day time.Time) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+	for _, month := range r.byMonth {
+		if month == day.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+// dayInMonth - день месяца со временем суток и часовым поясом DTSTART
+func dayInMonth(dtstart time.Time, year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+}
+
+func daysFromMonday(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
@@ -0,0 +1,27 @@
+package meeting
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// FakeProvider - провайдер без внешней системы для разработки и тестов:
+// комнаты ни к чему не привязаны, ссылка на вход совпадает с адресом комнаты
+type FakeProvider struct{}
+
+// NewFakeProvider - создание фейкового провайдера
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// CreateRoom - выдает уникальное имя комнаты
+func (p *FakeProvider) CreateRoom(ctx context.Context, req RoomRequest) (*Room, error) {
+	id := "fake-" + uuid.New().String()
+	return &Room{ID: id, URL: "https://meet.invalid/" + id}, nil
+}
+
+// JoinURL - адрес комнаты без токена
+func (p *FakeProvider) JoinURL(ctx context.Context, req JoinRequest) (string, error) {
+	return "https://meet.invalid/" + req.RoomID, nil
+}
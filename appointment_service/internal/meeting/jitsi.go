@@ -0,0 +1,82 @@
+package meeting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JitsiProvider - Jitsi Meet с аутентификацией по JWT (prosody token auth).
+// Комнаты в Jitsi появляются при первом входе, поэтому создание комнаты - это выбор
+// неугадываемого имени, а войти в нее можно только по подписанному токену участника
+type JitsiProvider struct {
+	baseURL string
+	appID   string
+	secret  []byte
+}
+
+// NewJitsiProvider - создание провайдера; baseURL например https://meet.vitalem.kz,
+// appID и secret совпадают с app_id и app_secret в настройках token auth Jitsi
+func NewJitsiProvider(baseURL, appID, secret string) *JitsiProvider {
+	return &JitsiProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		appID:   appID,
+		secret:  []byte(secret),
+	}
+}
+
+// CreateRoom - случайное имя комнаты, не выводимое из ID записи
+func (p *JitsiProvider) CreateRoom(ctx context.Context, req RoomRequest) (*Room, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate room name: %w", err)
+	}
+
+	id := "vitalem-" + hex.EncodeToString(random)
+	return &Room{ID: id, URL: p.roomURL(id)}, nil
+}
+
+// JoinURL - адрес комнаты с токеном участника, действующим только в [NotBefore, ExpiresAt]
+func (p *JitsiProvider) JoinURL(ctx context.Context, req JoinRequest) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":  p.appID,
+		"aud":  p.appID,
+		"sub":  p.domain(),
+		"room": req.RoomID,
+		"nbf":  req.NotBefore.Unix(),
+		"exp":  req.ExpiresAt.Unix(),
+		"context": map[string]interface{}{
+			"user": map[string]interface{}{
+				"id":        req.Participant.UserID.String(),
+				"name":      req.Participant.Name,
+				"moderator": req.Participant.Moderator,
+			},
+		},
+		"moderator": req.Participant.Moderator,
+	})
+
+	signed, err := token.SignedString(p.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign meeting token: %w", err)
+	}
+
+	return p.roomURL(req.RoomID) + "?jwt=" + url.QueryEscape(signed), nil
+}
+
+func (p *JitsiProvider) roomURL(roomID string) string {
+	return p.baseURL + "/" + url.PathEscape(roomID)
+}
+
+// domain - хост Jitsi для claim sub
+func (p *JitsiProvider) domain() string {
+	parsed, err := url.Parse(p.baseURL)
+	if err != nil || parsed.Host == "" {
+		return "*"
+	}
+	return parsed.Host
+}
@@ -0,0 +1,44 @@
+// Package meeting - провайдеры видеовстреч для онлайн-приемов
+package meeting
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoomRequest - комната для онлайн-приема
+type RoomRequest struct {
+	AppointmentID uuid.UUID
+	StartTime     time.Time
+	EndTime       time.Time
+}
+
+// Room - созданная у провайдера комната
+type Room struct {
+	ID  string // имя комнаты у провайдера
+	URL string // адрес комнаты без прав на вход
+}
+
+// Participant - участник встречи
+type Participant struct {
+	UserID    uuid.UUID
+	Name      string
+	Moderator bool // врач управляет комнатой, пациент - обычный участник
+}
+
+// JoinRequest - ссылка на вход участника в комнату, действующая в [NotBefore, ExpiresAt]
+type JoinRequest struct {
+	RoomID      string
+	Participant Participant
+	NotBefore   time.Time
+	ExpiresAt   time.Time
+}
+
+// Provider - провайдер видеовстреч. Комната создается при записи, а ссылки на вход
+// выдаются каждому участнику отдельно и действуют только около времени приема
+type Provider interface {
+	CreateRoom(ctx context.Context, req RoomRequest) (*Room, error)
+	JoinURL(ctx context.Context, req JoinRequest) (string, error)
+}
@@ -20,7 +20,7 @@ type Appointment struct {
 
 	// Информация о записи
 	Title           string `gorm:"type:varchar(255);not null" json:"title"`                     // "Консультация терапевта"
-	Status          string `gorm:"type:varchar(20);not null;default:'available'" json:"status"` // available, booked, completed, canceled
+	Status          string `gorm:"type:varchar(20);not null;default:'available'" json:"status"` // см. Status* и TransitionTo
 	AppointmentType string `gorm:"type:varchar(10);default:'offline'" json:"appointment_type"`  // offline, online
 
 	// Ссылка на онлайн встречу (только для онлайн записей)
@@ -31,11 +31,43 @@ type Appointment struct {
 	PatientNotes string `gorm:"type:text" json:"patient_notes"` // Жалобы пациента
 	DoctorNotes  string `gorm:"type:text" json:"doctor_notes"`  // Заметки врача
 
+	// Итоги приема (заполняются врачом при завершении)
+	Diagnosis       string     `gorm:"type:text" json:"diagnosis,omitempty"`
+	Recommendations string     `gorm:"type:text" json:"recommendations,omitempty"`
+	FollowUpDate    *time.Time `gorm:"type:date" json:"follow_up_date,omitempty"`
+	CompletedAt     *time.Time `gorm:"type:timestamp with time zone" json:"completed_at,omitempty"`
+
+	// Отмена записи
+	CanceledBy     *uuid.UUID `gorm:"type:uuid" json:"canceled_by,omitempty"`
+	CanceledByRole string     `gorm:"type:varchar(20)" json:"canceled_by_role,omitempty"` // patient, doctor
+	CancelReason   string     `gorm:"type:text" json:"cancel_reason,omitempty"`
+	CanceledAt     *time.Time `gorm:"type:timestamp with time zone" json:"canceled_at,omitempty"`
+
+	// Оплата: слот удерживается за пациентом до held_until, запись подтверждается оплатой
+	HeldUntil     *time.Time `gorm:"type:timestamp with time zone;index" json:"held_until,omitempty"`
+	PaymentID     *string    `gorm:"type:varchar(100);index" json:"payment_id,omitempty"`
+	PaymentURL    string     `gorm:"type:text" json:"payment_url,omitempty"`
+	PaymentAmount float64    `gorm:"type:decimal(10,2);not null;default:0" json:"payment_amount"`
+	PaymentStatus string     `gorm:"type:varchar(20)" json:"payment_status,omitempty"` // pending, paid, failed, refund_required
+
 	// Связь с расписанием
 	ScheduleID *uuid.UUID `gorm:"type:uuid;index" json:"schedule_id,omitempty"`
 
+	// Вид приема, если запись вырезана из свободного времени по VisitType
+	VisitTypeID *uuid.UUID `gorm:"type:uuid;index" json:"visit_type_id,omitempty"`
+
+	// Повторный прием: прием, по итогам которого врач назначил эту запись (цепочка визитов)
+	PreviousAppointmentID *uuid.UUID `gorm:"type:uuid;index" json:"previous_appointment_id,omitempty"`
+
+	// Групповое занятие: мест больше одного, записавшиеся - в AppointmentParticipant
+	Capacity   int `gorm:"type:integer;not null;default:1" json:"capacity"`    // 8 мест
+	SeatsTaken int `gorm:"type:integer;not null;default:0" json:"seats_taken"` // 3 занято
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Несохраненные переходы статуса для журнала appointment_status_history
+	statusChanges []*AppointmentStatusHistory `gorm:"-"`
 }
 
 func (Appointment) TableName() string {
@@ -51,12 +83,85 @@ func (a *Appointment) BeforeCreate(tx *gorm.DB) error {
 
 // Методы для работы с записью
 func (a *Appointment) IsAvailable() bool {
-	return a.Status == "available" && a.PatientID == nil
+	return a.Status == StatusAvailable && a.PatientID == nil
+}
+
+// IsGroup - групповое занятие, на которое записываются несколько пациентов
+func (a *Appointment) IsGroup() bool {
+	return a.Capacity > 1
+}
+
+// SeatsLeft - сколько мест на занятии еще свободно
+func (a *Appointment) SeatsLeft() int {
+	if a.Capacity <= 1 {
+		if a.IsAvailable() {
+			return 1
+		}
+		return 0
+	}
+	if a.Status != StatusAvailable && a.Status != StatusBooked {
+		return 0
+	}
+	return a.Capacity - a.SeatsTaken
+}
+
+// HasPatients - на запись кто-то записан: пациент личного приема или участники группового занятия
+func (a *Appointment) HasPatients() bool {
+	return a.PatientID != nil || a.SeatsTaken > 0
+}
+
+// JoinGroup - пациент занимает место на групповом занятии.
+// Первый записавшийся переводит занятие в booked, дальше меняется только число занятых мест
+func (a *Appointment) JoinGroup(patientID uuid.UUID) error {
+	if !a.IsGroup() || a.SeatsLeft() <= 0 {
+		return fmt.Errorf("no free seats left")
+	}
+	if a.Status == StatusAvailable {
+		if err := a.TransitionTo(StatusBooked, &patientID, "patient", "first seat taken"); err != nil {
+			return err
+		}
+	}
+	a.SeatsTaken++
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// LeaveGroup - пациент освобождает место; без участников занятие снова становится свободным слотом
+func (a *Appointment) LeaveGroup(patientID uuid.UUID) error {
+	if a.Status != StatusBooked || a.SeatsTaken <= 0 {
+		return &IllegalTransitionError{From: a.Status, To: StatusAvailable}
+	}
+	a.SeatsTaken--
+	if a.SeatsTaken == 0 {
+		if err := a.TransitionTo(StatusAvailable, &patientID, "patient", "last seat released"); err != nil {
+			return err
+		}
+		a.MeetingLink = nil
+		a.MeetingID = nil
+	}
+	a.UpdatedAt = time.Now()
+	return nil
 }
 
-func (a *Appointment) Book(patientID uuid.UUID, appointmentType, notes string) {
+// IsVisit - запись вырезана из свободного времени по виду приема, а не создана из слота
+func (a *Appointment) IsVisit() bool {
+	return a.VisitTypeID != nil
+}
+
+// releasedStatus - статус освобожденной записи: слот возвращается в пул свободных,
+// а запись по виду приема закрывается - ее время снова свободно для любых видов приема
+func (a *Appointment) releasedStatus() string {
+	if a.IsVisit() {
+		return StatusCanceled
+	}
+	return StatusAvailable
+}
+
+func (a *Appointment) Book(patientID uuid.UUID, appointmentType, notes string) error {
+	if err := a.TransitionTo(StatusBooked, &patientID, "patient", ""); err != nil {
+		return err
+	}
 	a.PatientID = &patientID
-	a.Status = "booked"
 	if appointmentType != "" {
 		a.AppointmentType = appointmentType
 	}
@@ -64,30 +169,178 @@ func (a *Appointment) Book(patientID uuid.UUID, appointmentType, notes string) {
 		a.PatientNotes = notes
 	}
 
-	// Если это онлайн запись, генерируем заглушку ссылки
-	if appointmentType == "online" {
-		meetingID := fmt.Sprintf("vitalem-%s", a.ID.String()[:8])
-		meetingLink := fmt.Sprintf("https://meet.vitalem.kz/room/%s", meetingID)
-		a.MeetingID = &meetingID
-		a.MeetingLink = &meetingLink
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// BookFollowUp - врач записывает пациента на повторный прием по итогам приема previousID
+func (a *Appointment) BookFollowUp(doctorID, patientID, previousID uuid.UUID, appointmentType, doctorNotes string) error {
+	if err := a.TransitionTo(StatusBooked, &doctorID, "doctor", "follow-up of "+previousID.String()); err != nil {
+		return err
+	}
+	a.PatientID = &patientID
+	a.PreviousAppointmentID = &previousID
+	if appointmentType != "" {
+		a.AppointmentType = appointmentType
+	}
+	if doctorNotes != "" {
+		a.DoctorNotes = doctorNotes
+	}
+
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// Hold - удерживает слот за пациентом до until, пока не придет подтверждение оплаты
+func (a *Appointment) Hold(patientID uuid.UUID, appointmentType, notes string, amount float64, until time.Time) error {
+	if err := a.TransitionTo(StatusHeld, &patientID, "patient", ""); err != nil {
+		return err
+	}
+	a.PatientID = &patientID
+	if appointmentType != "" {
+		a.AppointmentType = appointmentType
+	}
+	if notes != "" {
+		a.PatientNotes = notes
 	}
+	a.HeldUntil = &until
+	a.PaymentAmount = amount
+	a.PaymentStatus = "pending"
+	a.UpdatedAt = time.Now()
+	return nil
+}
 
+// ConfirmPayment - оплата прошла, удерживаемый слот становится записью
+func (a *Appointment) ConfirmPayment() error {
+	if err := a.TransitionTo(StatusBooked, nil, "system", "payment confirmed"); err != nil {
+		return err
+	}
+	a.HeldUntil = nil
+	a.PaymentStatus = "paid"
 	a.UpdatedAt = time.Now()
+	return nil
 }
 
-func (a *Appointment) Cancel() {
-	a.Status = "canceled"
+// ReleaseHold - снимает удержание (оплата не прошла, истекло время или пациент передумал)
+func (a *Appointment) ReleaseHold(actorID *uuid.UUID, role, reason string) error {
+	if err := a.TransitionTo(a.releasedStatus(), actorID, role, reason); err != nil {
+		return err
+	}
 	a.PatientID = nil
+	a.PatientNotes = ""
+	a.PreviousAppointmentID = nil
+	a.HeldUntil = nil
+	// Платеж остается в истории платежей; слот больше не ссылается на него
+	a.PaymentID = nil
+	a.PaymentURL = ""
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetMeeting - привязывает к записи комнату онлайн-встречи (см. meeting.Provider)
+func (a *Appointment) SetMeeting(roomID, roomURL string) {
+	a.MeetingID = &roomID
+	a.MeetingLink = &roomURL
+}
+
+// IsOnline - прием проходит по видеосвязи
+func (a *Appointment) IsOnline() bool {
+	return a.AppointmentType == "online"
+}
+
+// IsActive - пациент записан, и прием еще не завершен и не отменен
+func (a *Appointment) IsActive() bool {
+	switch a.Status {
+	case StatusBooked, StatusCheckedIn, StatusInProgress:
+		return true
+	}
+	return false
+}
+
+// Cancel - отменяет запись с указанием, кто и почему ее отменил.
+// При отмене пациентом запись отвязывается от него; при отмене врачом пациент остается,
+// чтобы видеть отмененную запись и ее причину в своем списке
+func (a *Appointment) Cancel(canceledBy uuid.UUID, role, reason string) error {
+	if err := a.TransitionTo(StatusCanceled, &canceledBy, role, reason); err != nil {
+		return err
+	}
+	now := time.Now()
+	if role == "patient" {
+		a.PatientID = nil
+	}
+	a.CanceledBy = &canceledBy
+	a.CanceledByRole = role
+	a.CancelReason = reason
+	a.CanceledAt = &now
 	// Очищаем ссылку на встречу при отмене
 	a.MeetingLink = nil
 	a.MeetingID = nil
+	a.UpdatedAt = now
+	return nil
+}
+
+// Release - возвращает слот в пул свободных (используется при переносе записи)
+func (a *Appointment) Release(actorID uuid.UUID, role, reason string) error {
+	if err := a.TransitionTo(a.releasedStatus(), &actorID, role, reason); err != nil {
+		return err
+	}
+	a.PatientID = nil
+	a.PatientNotes = ""
+	a.PreviousAppointmentID = nil
+	a.MeetingLink = nil
+	a.MeetingID = nil
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// CheckIn - отметка врача о том, что пациент пришел (подключился к онлайн-приему)
+func (a *Appointment) CheckIn(doctorID uuid.UUID) error {
+	if err := a.TransitionTo(StatusCheckedIn, &doctorID, "doctor", ""); err != nil {
+		return err
+	}
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// Start - врач начал прием
+func (a *Appointment) Start(doctorID uuid.UUID) error {
+	if err := a.TransitionTo(StatusInProgress, &doctorID, "doctor", ""); err != nil {
+		return err
+	}
 	a.UpdatedAt = time.Now()
+	return nil
 }
 
-func (a *Appointment) Complete(doctorNotes string) {
-	a.Status = "completed"
+// MarkNoShow - пациент не пришел на прием; пациент остается в записи для истории
+func (a *Appointment) MarkNoShow(doctorID uuid.UUID, reason string) error {
+	if err := a.TransitionTo(StatusNoShow, &doctorID, "doctor", reason); err != nil {
+		return err
+	}
+	a.MeetingLink = nil
+	a.MeetingID = nil
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// VisitNotes - структурированные итоги приема
+type VisitNotes struct {
+	Diagnosis       string
+	Recommendations string
+	FollowUpDate    *time.Time
+}
+
+func (a *Appointment) Complete(doctorID uuid.UUID, doctorNotes string, visit VisitNotes) error {
+	if err := a.TransitionTo(StatusCompleted, &doctorID, "doctor", ""); err != nil {
+		return err
+	}
+	now := time.Now()
 	if doctorNotes != "" {
 		a.DoctorNotes = doctorNotes
 	}
-	a.UpdatedAt = time.Now()
+	a.Diagnosis = visit.Diagnosis
+	a.Recommendations = visit.Recommendations
+	a.FollowUpDate = visit.FollowUpDate
+	a.CompletedAt = &now
+	a.UpdatedAt = now
+	return nil
 }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppointmentAttachment - файл FileServer (анализы, снимки, заключения), прикрепленный к записи.
+// Сам файл хранится в FileServer; здесь только ссылка на него и кто ее добавил
+type AppointmentAttachment struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	AppointmentID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_attachments_appointment_file" json:"appointment_id"`
+	FileID         uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_attachments_appointment_file" json:"file_id"`
+	AttachedBy     uuid.UUID `gorm:"type:uuid;not null" json:"attached_by"`
+	AttachedByRole string    `gorm:"type:varchar(20);not null" json:"attached_by_role"` // doctor, patient
+	Note           string    `gorm:"type:text" json:"note,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AppointmentAttachment) TableName() string {
+	return "appointment_attachments"
+}
+
+func (a *AppointmentAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookingPolicy - правила записи к врачу. Нулевое значение правила означает "без ограничения",
+// поэтому врач без настроенных правил принимает записи как раньше
+type BookingPolicy struct {
+	DoctorID            uuid.UUID `gorm:"type:uuid;primary_key" json:"doctor_id"`
+	MinLeadMinutes      int       `gorm:"type:integer;not null;default:0" json:"min_lead_minutes"`      // Не раньше чем за 120 минут до начала
+	MaxHorizonDays      int       `gorm:"type:integer;not null;default:0" json:"max_horizon_days"`      // Не дальше чем на 30 дней вперед
+	CancelCutoffMinutes int       `gorm:"type:integer;not null;default:0" json:"cancel_cutoff_minutes"` // Отмена пациентом не позже чем за 1440 минут
+	MaxActiveBookings   int       `gorm:"type:integer;not null;default:0" json:"max_active_bookings"`   // Не больше 2 предстоящих записей у пациента
+	BufferMinutes       int       `gorm:"type:integer;not null;default:0" json:"buffer_minutes"`        // 10 минут между приемами
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (BookingPolicy) TableName() string {
+	return "booking_policies"
+}
+
+// MinLead - минимальное время от записи до начала приема
+func (p *BookingPolicy) MinLead() time.Duration {
+	return time.Duration(p.MinLeadMinutes) * time.Minute
+}
+
+// CancelCutoff - за сколько до начала приема пациент теряет возможность отменить запись
+func (p *BookingPolicy) CancelCutoff() time.Duration {
+	return time.Duration(p.CancelCutoffMinutes) * time.Minute
+}
+
+// Buffer - обязательный перерыв между приемами врача
+func (p *BookingPolicy) Buffer() time.Duration {
+	return time.Duration(p.BufferMinutes) * time.Minute
+}
+
+// HorizonEnd - самое позднее начало приема, на которое можно записаться в момент now; nil - без ограничения
+func (p *BookingPolicy) HorizonEnd(now time.Time) *time.Time {
+	if p.MaxHorizonDays <= 0 {
+		return nil
+	}
+	end := now.AddDate(0, 0, p.MaxHorizonDays)
+	return &end
+}
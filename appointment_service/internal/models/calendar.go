@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CalendarFeedToken - секретный токен персональной ICS-ленты пользователя.
+// Лента открывается по ссылке без JWT (ее опрашивают календарные приложения), поэтому токен - единственная защита
+type CalendarFeedToken struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	Role      string    `gorm:"type:varchar(20);not null" json:"role"` // doctor, patient
+	Token     string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (CalendarFeedToken) TableName() string {
+	return "calendar_feed_tokens"
+}
+
+// ExternalCalendar - внешний календарь врача (например, график в больнице), занятое время из которого блокирует слоты
+type ExternalCalendar struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	DoctorID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"doctor_id"`
+	URL          string     `gorm:"type:text;not null" json:"url"`
+	LastSyncedAt *time.Time `gorm:"type:timestamp with time zone" json:"last_synced_at,omitempty"`
+	LastError    string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (ExternalCalendar) TableName() string {
+	return "external_calendars"
+}
+
+func (c *ExternalCalendar) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// Source - значение schedule_exceptions.source для исключений, импортированных из этого календаря
+func (c *ExternalCalendar) Source() string {
+	return "ics_url:" + c.ID.String()
+}
@@ -19,24 +19,32 @@ type CreateScheduleRequest struct {
 	SlotDuration      int64   `json:"slot_duration" validate:"required,min=15,max=180"`                 // 30
 	SlotTitle         string  `json:"slot_title" validate:"max=255"`                                    // "Консультация"
 	AppointmentFormat string  `json:"appointment_format" validate:"required,oneof=offline online both"` // "offline", "online", "both"
+	SlotCapacity      int     `json:"slot_capacity" validate:"min=0,max=100"`                           // 8 - групповое занятие на 8 мест, по умолчанию 1
+	Timezone          string  `json:"timezone" validate:"max=64"`                                       // "Asia/Almaty", по умолчанию - пояс сервиса
+	GenerateDaysAhead int     `json:"generate_days_ahead" validate:"min=0,max=365"`                     // 30 - держать слоты на 30 дней вперед, 0 - вручную
+	Exclusive         bool    `json:"exclusive"`                                                        // true - деактивировать остальные расписания врача
 }
 
 // ScheduleResponse - ответ с расписанием
 type ScheduleResponse struct {
-	ID                uuid.UUID `json:"id"`
-	DoctorID          uuid.UUID `json:"doctor_id"`
-	Name              string    `json:"name"`
-	WorkDays          []int     `json:"work_days"`
-	StartTime         string    `json:"start_time"`
-	EndTime           string    `json:"end_time"`
-	BreakStart        *string   `json:"break_start,omitempty"`
-	BreakEnd          *string   `json:"break_end,omitempty"`
-	SlotDuration      int64     `json:"slot_duration"`
-	SlotTitle         string    `json:"slot_title"`
-	AppointmentFormat string    `json:"appointment_format"`
-	IsActive          bool      `json:"is_active"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                uuid.UUID  `json:"id"`
+	DoctorID          uuid.UUID  `json:"doctor_id"`
+	Name              string     `json:"name"`
+	WorkDays          []int      `json:"work_days"`
+	StartTime         string     `json:"start_time"`
+	EndTime           string     `json:"end_time"`
+	BreakStart        *string    `json:"break_start,omitempty"`
+	BreakEnd          *string    `json:"break_end,omitempty"`
+	SlotDuration      int64      `json:"slot_duration"`
+	SlotTitle         string     `json:"slot_title"`
+	AppointmentFormat string     `json:"appointment_format"`
+	SlotCapacity      int        `json:"slot_capacity"`
+	IsActive          bool       `json:"is_active"`
+	Timezone          string     `json:"timezone"`
+	GenerateDaysAhead int        `json:"generate_days_ahead"`
+	GeneratedUntil    *time.Time `json:"generated_until,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // UpdateScheduleRequest - обновление расписания врача
@@ -50,23 +58,48 @@ type UpdateScheduleRequest struct {
 	SlotDuration      *int64  `json:"slot_duration,omitempty" validate:"omitempty,min=15,max=180"`
 	SlotTitle         *string `json:"slot_title,omitempty" validate:"omitempty,max=255"`
 	AppointmentFormat *string `json:"appointment_format,omitempty" validate:"omitempty,oneof=offline online both"`
+	SlotCapacity      *int    `json:"slot_capacity,omitempty" validate:"omitempty,min=1,max=100"`
+	Timezone          *string `json:"timezone,omitempty" validate:"omitempty,max=64"`
+	GenerateDaysAhead *int    `json:"generate_days_ahead,omitempty" validate:"omitempty,min=0,max=365"`
 }
 
 // ToggleScheduleRequest - активация/деактивация расписания
 type ToggleScheduleRequest struct {
-	IsActive bool `json:"is_active"`
+	IsActive  bool `json:"is_active"`
+	Exclusive bool `json:"exclusive"` // true - при активации деактивировать остальные расписания врача
+}
+
+// ScheduleConflict - пересечение с другим активным расписанием врача
+type ScheduleConflict struct {
+	ScheduleID uuid.UUID `json:"schedule_id"`
+	Name       string    `json:"name"`
+	WorkDays   []int     `json:"work_days"`  // общие рабочие дни
+	StartTime  string    `json:"start_time"` // начало общего интервала, "10:00"
+	EndTime    string    `json:"end_time"`   // конец общего интервала, "13:00"
 }
 
 // GenerateSlotsRequest - генерация слотов
 type GenerateSlotsRequest struct {
 	StartDate string `json:"start_date" validate:"required,len=10"` // "2024-06-01"
 	EndDate   string `json:"end_date" validate:"required,len=10"`   // "2024-06-30"
+	// Слоты, пересекающиеся с существующими, пропускать и перечислять в ответе;
+	// по умолчанию генерация отменяется целиком при первом пересечении
+	SkipConflicts bool `json:"skip_conflicts"`
 }
 
 // GenerateSlotsResponse - ответ генерации слотов
 type GenerateSlotsResponse struct {
-	SlotsCreated int    `json:"slots_created"`
-	Message      string `json:"message"`
+	SlotsCreated int           `json:"slots_created"`
+	SlotsSkipped int           `json:"slots_skipped"`
+	SkippedSlots []SkippedSlot `json:"skipped_slots,omitempty"`
+	Message      string        `json:"message"`
+}
+
+// SkippedSlot - слот, который генерация не создала
+type SkippedSlot struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Reason    string    `json:"reason"` // exists - время уже занято другим слотом, busy - занято во внешнем календаре
 }
 
 // === APPOINTMENT DTOs ===
@@ -77,6 +110,44 @@ type BookAppointmentRequest struct {
 	PatientNotes    string `json:"patient_notes" validate:"max=1000"`                          // "Болит голова"
 }
 
+// RescheduleAppointmentRequest - перенос записи на другой слот
+type RescheduleAppointmentRequest struct {
+	TargetSlotID uuid.UUID `json:"target_slot_id" validate:"required"` // ID свободного слота того же врача
+}
+
+// DoctorCancelRequest - отмена записи врачом
+type DoctorCancelRequest struct {
+	Reason string `json:"reason" validate:"required,min=1,max=1000"` // "Врач заболел"
+}
+
+// DoctorBulkCancelRequest - отмена всех записей врача за период
+type DoctorBulkCancelRequest struct {
+	StartDate string `json:"start_date" validate:"required,len=10"`     // "2024-06-10"
+	EndDate   string `json:"end_date" validate:"required,len=10"`       // "2024-06-14" (включительно)
+	Reason    string `json:"reason" validate:"required,min=1,max=1000"` // "Больничный"
+}
+
+// DoctorBulkCancelResponse - результат массовой отмены
+type DoctorBulkCancelResponse struct {
+	CanceledCount  int         `json:"canceled_count"`  // отменено забронированных записей
+	ClosedSlots    int64       `json:"closed_slots"`    // закрыто свободных слотов в периоде
+	ExceptionID    uuid.UUID   `json:"exception_id"`    // выходной на период: генерация не создаст в нем новых слотов
+	AppointmentIDs []uuid.UUID `json:"appointment_ids"` // ID отмененных записей
+}
+
+// CompleteAppointmentRequest - завершение приема врачом
+type CompleteAppointmentRequest struct {
+	Diagnosis       string  `json:"diagnosis" validate:"required,min=1,max=2000"`         // "ОРВИ"
+	Recommendations string  `json:"recommendations" validate:"max=4000"`                  // "Постельный режим 3 дня"
+	FollowUpDate    *string `json:"follow_up_date,omitempty" validate:"omitempty,len=10"` // "2024-06-20"
+	DoctorNotes     string  `json:"doctor_notes" validate:"max=4000"`                     // Свободные заметки врача
+}
+
+// MarkNoShowRequest - отметка о неявке пациента
+type MarkNoShowRequest struct {
+	Reason string `json:"reason" validate:"max=500"` // "Не пришел, на звонки не отвечает"
+}
+
 // AppointmentResponse - ответ с записью
 type AppointmentResponse struct {
 	ID        uuid.UUID `json:"id"`
@@ -97,41 +168,375 @@ type AppointmentResponse struct {
 	PatientNotes string `json:"patient_notes"`
 	DoctorNotes  string `json:"doctor_notes"`
 
+	// Итоги приема (только для завершенных записей)
+	Diagnosis       string     `json:"diagnosis,omitempty"`
+	Recommendations string     `json:"recommendations,omitempty"`
+	FollowUpDate    *time.Time `json:"follow_up_date,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+
+	// Отмена (только для отмененных записей)
+	CanceledByRole string     `json:"canceled_by_role,omitempty"`
+	CancelReason   string     `json:"cancel_reason,omitempty"`
+	CanceledAt     *time.Time `json:"canceled_at,omitempty"`
+
+	// Оплата (для удерживаемых до оплаты и оплаченных записей)
+	HeldUntil     *time.Time `json:"held_until,omitempty"`
+	PaymentAmount float64    `json:"payment_amount,omitempty"`
+	PaymentStatus string     `json:"payment_status,omitempty"`
+	PaymentURL    string     `json:"payment_url,omitempty"`
+
+	// Вид приема (для записей, вырезанных из свободного времени)
+	VisitTypeID *uuid.UUID `json:"visit_type_id,omitempty"`
+
+	// Групповое занятие (capacity > 1): пациенты перечислены в списке участников
+	Capacity   int `json:"capacity"`
+	SeatsTaken int `json:"seats_taken"`
+
+	// Повторный прием: прием, по итогам которого записал врач (см. GET /appointments/:id/chain)
+	PreviousAppointmentID *uuid.UUID `json:"previous_appointment_id,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // AvailableSlot - доступный слот для пациента
 type AvailableSlot struct {
+	ID              uuid.UUID  `json:"id"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	Duration        int        `json:"duration_minutes"`
+	Title           string     `json:"title"`
+	AppointmentType string     `json:"appointment_type"`     // "offline", "online", "both"
+	Status          string     `json:"status"`               // "available", "held" - удерживается другим пациентом до оплаты, "booked" - групповое занятие с местами
+	HeldUntil       *time.Time `json:"held_until,omitempty"` // когда удержание истечет, если оплата не придет
+	Capacity        int        `json:"capacity"`             // мест на занятии, 1 - личный прием
+	SeatsLeft       int        `json:"seats_left"`           // сколько мест еще свободно
+}
+
+// SlotSearchRequest - поиск ближайших свободных слотов сразу по нескольким врачам
+type SlotSearchRequest struct {
+	DoctorIDs string `query:"doctor_ids"`                                       // "id1,id2"; пусто - все врачи
+	DateFrom  string `query:"date_from" validate:"required,len=10"`             // "2024-06-03"
+	DateTo    string `query:"date_to" validate:"required,len=10"`               // "2024-06-09", включительно
+	Format    string `query:"format" validate:"omitempty,oneof=online offline"` // "online", "offline"
+	TimeFrom  string `query:"time_from" validate:"omitempty,len=5"`             // "09:00" - начало приема не раньше
+	TimeTo    string `query:"time_to" validate:"omitempty,len=5"`               // "13:00" - окончание приема не позже
+	Timezone  string `query:"timezone" validate:"max=64"`                       // пояс пациента для дат и времени суток, по умолчанию UTC
+	Page      int    `query:"page" validate:"min=0"`                            // с 1
+	Limit     int    `query:"limit" validate:"min=0,max=100"`                   // по умолчанию 20
+}
+
+// SlotSearchItem - найденный свободный слот
+type SlotSearchItem struct {
 	ID              uuid.UUID `json:"id"`
+	DoctorID        uuid.UUID `json:"doctor_id"`
 	StartTime       time.Time `json:"start_time"`
 	EndTime         time.Time `json:"end_time"`
 	Duration        int       `json:"duration_minutes"`
 	Title           string    `json:"title"`
 	AppointmentType string    `json:"appointment_type"` // "offline", "online", "both"
+	Capacity        int       `json:"capacity"`         // мест на занятии, 1 - личный прием
+	SeatsLeft       int       `json:"seats_left"`
+}
+
+// ParticipantResponse - пациент, записанный на групповое занятие
+type ParticipantResponse struct {
+	PatientID       uuid.UUID `json:"patient_id"`
+	AppointmentType string    `json:"appointment_type"` // "offline", "online"
+	PatientNotes    string    `json:"patient_notes,omitempty"`
+	BookedAt        time.Time `json:"booked_at"`
+}
+
+// AppointmentListRequest - фильтры, сортировка и пагинация списка записей (GET /appointments)
+type AppointmentListRequest struct {
+	Status   string `query:"status"`                                        // "booked,completed"; пусто - любые
+	DateFrom string `query:"date_from" validate:"omitempty,len=10"`         // "2024-06-01" - начало приема не раньше этого дня
+	DateTo   string `query:"date_to" validate:"omitempty,len=10"`           // "2024-06-30", включительно
+	When     string `query:"when" validate:"omitempty,oneof=upcoming past"` // предстоящие или прошедшие
+	Sort     string `query:"sort" validate:"omitempty,oneof=asc desc"`      // по времени начала; для past по умолчанию desc
+	Timezone string `query:"timezone" validate:"max=64"`                    // пояс для date_from/date_to, по умолчанию UTC
+	Page     int    `query:"page" validate:"min=0"`                         // с 1
+	Limit    int    `query:"limit" validate:"min=0,max=100"`                // по умолчанию 20
+}
+
+// MeetingJoinResponse - личная ссылка участника на вход во встречу онлайн-приема
+type MeetingJoinResponse struct {
+	AppointmentID uuid.UUID `json:"appointment_id"`
+	MeetingID     string    `json:"meeting_id"`
+	JoinURL       string    `json:"join_url"`
+	Moderator     bool      `json:"moderator"`
+	ValidFrom     time.Time `json:"valid_from"`  // ссылка начинает действовать незадолго до приема
+	ValidUntil    time.Time `json:"valid_until"` // и перестает вскоре после его окончания
+}
+
+// === ANALYTICS DTOs ===
+
+// DoctorAnalyticsRequest - период и разбивка аналитики приема врача
+type DoctorAnalyticsRequest struct {
+	DateFrom string `query:"date_from" validate:"required,len=10"`             // "2024-06-01"
+	DateTo   string `query:"date_to" validate:"required,len=10"`               // "2024-06-30", включительно
+	Bucket   string `query:"bucket" validate:"omitempty,oneof=day week month"` // по умолчанию day
+	Timezone string `query:"timezone" validate:"max=64"`                       // пояс для дат, дней недели и часов, по умолчанию UTC
+}
+
+// AnalyticsStats - показатели приема за период или его часть
+type AnalyticsStats struct {
+	SlotsOffered     int64   `json:"slots_offered"`     // все предложенные слоты и записи, кроме закрытых врачом свободных слотов
+	Booked           int64   `json:"booked"`            // занятые пациентами (записан, пришел, на приеме, принят, не пришел)
+	Completed        int64   `json:"completed"`         // проведенные приемы
+	Canceled         int64   `json:"canceled"`          // отмененные записи пациентов
+	NoShow           int64   `json:"no_show"`           // неявки
+	Online           int64   `json:"online"`            // занятые онлайн
+	Offline          int64   `json:"offline"`           // занятые очно
+	Utilization      float64 `json:"utilization"`       // booked / slots_offered
+	CancellationRate float64 `json:"cancellation_rate"` // canceled / (booked + canceled)
+	NoShowRate       float64 `json:"no_show_rate"`      // no_show / (completed + no_show)
+	Revenue          float64 `json:"estimated_revenue"` // оплаченная сумма, а без оплаты - цена приема врача, по проведенным приемам
+}
+
+// AnalyticsBucket - показатели за день, неделю или месяц
+type AnalyticsBucket struct {
+	Start string `json:"start"` // первый день периода, "2024-06-03"
+	AnalyticsStats
+}
+
+// WeekdayLoad - занятые приемы по дню недели
+type WeekdayLoad struct {
+	Weekday      int   `json:"weekday"` // 1 - понедельник ... 7 - воскресенье
+	Appointments int64 `json:"appointments"`
+}
+
+// HourLoad - занятые приемы по часу начала
+type HourLoad struct {
+	Hour         int   `json:"hour"` // 0-23 в поясе отчета
+	Appointments int64 `json:"appointments"`
+}
+
+// DoctorAnalyticsResponse - аналитика приема врача за период
+type DoctorAnalyticsResponse struct {
+	DateFrom    string            `json:"date_from"`
+	DateTo      string            `json:"date_to"`
+	Bucket      string            `json:"bucket"`
+	Timezone    string            `json:"timezone"`
+	DoctorPrice float64           `json:"doctor_price"` // цена приема для оценки выручки; 0 - не удалось узнать
+	Totals      AnalyticsStats    `json:"totals"`
+	Buckets     []AnalyticsBucket `json:"buckets"`
+	Weekdays    []WeekdayLoad     `json:"busiest_weekdays"` // от самых загруженных
+	Hours       []HourLoad        `json:"busiest_hours"`    // от самых загруженных
+}
+
+// === VISIT TYPE DTOs ===
+
+// CreateVisitTypeRequest - добавление вида приема в каталог врача
+type CreateVisitTypeRequest struct {
+	Title    string  `json:"title" validate:"required,min=1,max=255"`              // "Первичная консультация"
+	Duration int64   `json:"duration" validate:"required,min=5,max=480"`           // 60
+	Price    float64 `json:"price" validate:"min=0"`                               // 15000
+	Format   string  `json:"format" validate:"required,oneof=offline online both"` // "offline", "online", "both"
+}
+
+// UpdateVisitTypeRequest - изменение вида приема
+type UpdateVisitTypeRequest struct {
+	Title    *string  `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
+	Duration *int64   `json:"duration,omitempty" validate:"omitempty,min=5,max=480"`
+	Price    *float64 `json:"price,omitempty" validate:"omitempty,min=0"`
+	Format   *string  `json:"format,omitempty" validate:"omitempty,oneof=offline online both"`
+	IsActive *bool    `json:"is_active,omitempty"`
+}
+
+// VisitTypeResponse - вид приема
+type VisitTypeResponse struct {
+	ID        uuid.UUID `json:"id"`
+	DoctorID  uuid.UUID `json:"doctor_id"`
+	Title     string    `json:"title"`
+	Duration  int64     `json:"duration"`
+	Price     float64   `json:"price"`
+	Format    string    `json:"format"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// VisitSlot - свободное время, на которое можно записаться по виду приема
+type VisitSlot struct {
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	AppointmentType string    `json:"appointment_type"` // "offline", "online", "both"
+}
+
+// BookVisitRequest - запись по виду приема на выбранное время
+type BookVisitRequest struct {
+	VisitTypeID     uuid.UUID `json:"visit_type_id" validate:"required"`
+	StartTime       time.Time `json:"start_time" validate:"required"`                             // "2024-06-03T10:00:00+05:00"
+	AppointmentType string    `json:"appointment_type" validate:"omitempty,oneof=offline online"` // "offline", "online"
+	PatientNotes    string    `json:"patient_notes" validate:"max=1000"`                          // "Болит голова"
+}
+
+// === FOLLOW-UP DTOs ===
+
+// BookFollowUpRequest - врач записывает пациента прошедшего приема на свой свободный слот
+type BookFollowUpRequest struct {
+	SlotID          uuid.UUID `json:"slot_id" validate:"required"`
+	AppointmentType string    `json:"appointment_type" validate:"omitempty,oneof=offline online"` // "offline", "online"
+	DoctorNotes     string    `json:"doctor_notes" validate:"max=4000"`                           // "Контроль анализов"
+}
+
+// ProposeFollowUpRequest - врач предлагает пациенту несколько своих слотов на выбор
+type ProposeFollowUpRequest struct {
+	SlotIDs         []uuid.UUID `json:"slot_ids" validate:"required,min=1,max=10"`
+	AppointmentType string      `json:"appointment_type" validate:"omitempty,oneof=offline online"` // пусто - выбирает пациент
+	Message         string      `json:"message" validate:"max=1000"`                                // "Покажите анализы через 2 недели"
+}
+
+// AcceptFollowUpRequest - пациент выбирает слот из предложения
+type AcceptFollowUpRequest struct {
+	SlotID          uuid.UUID `json:"slot_id" validate:"required"`
+	AppointmentType string    `json:"appointment_type" validate:"omitempty,oneof=offline online"` // если врач не задал формат
+	PatientNotes    string    `json:"patient_notes" validate:"max=1000"`
+}
+
+// FollowUpSlot - слот в предложении повторного приема
+type FollowUpSlot struct {
+	ID              uuid.UUID `json:"id"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	AppointmentType string    `json:"appointment_type"` // "offline", "online", "both"
+	Available       bool      `json:"available"`        // на слот еще можно записаться
+}
+
+// FollowUpProposalResponse - предложение повторного приема
+type FollowUpProposalResponse struct {
+	ID                    uuid.UUID      `json:"id"`
+	DoctorID              uuid.UUID      `json:"doctor_id"`
+	PatientID             uuid.UUID      `json:"patient_id"`
+	PreviousAppointmentID uuid.UUID      `json:"previous_appointment_id"`
+	AppointmentType       string         `json:"appointment_type,omitempty"`
+	Message               string         `json:"message,omitempty"`
+	Status                string         `json:"status"`                   // "pending", "accepted"
+	AppointmentID         *uuid.UUID     `json:"appointment_id,omitempty"` // запись, выбранная пациентом
+	Slots                 []FollowUpSlot `json:"slots"`
+	CreatedAt             time.Time      `json:"created_at"`
+}
+
+// === ATTACHMENT DTOs ===
+
+// AttachFileRequest - прикрепить к записи свой файл из FileServer
+type AttachFileRequest struct {
+	FileID uuid.UUID `json:"file_id" validate:"required"`
+	Note   string    `json:"note" validate:"max=500"` // "Анализ крови от 12.06"
+}
+
+// AttachmentResponse - файл, прикрепленный к записи
+type AttachmentResponse struct {
+	FileID         uuid.UUID `json:"file_id"`
+	AttachedBy     uuid.UUID `json:"attached_by"`
+	AttachedByRole string    `json:"attached_by_role"` // "doctor", "patient"
+	Note           string    `json:"note,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	// DownloadURL - ссылка на скачивание; на файл другого участника - с разрешением,
+	// действующим ограниченное время. Пусто, если прием уже не активен
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// === BOOKING POLICY DTOs ===
+
+// UpdateBookingPolicyRequest - правила записи к врачу целиком; 0 - правило не действует
+type UpdateBookingPolicyRequest struct {
+	MinLeadMinutes      int `json:"min_lead_minutes" validate:"min=0,max=43200"`      // 120 - записаться не позже чем за 2 часа
+	MaxHorizonDays      int `json:"max_horizon_days" validate:"min=0,max=365"`        // 30 - не дальше чем на месяц вперед
+	CancelCutoffMinutes int `json:"cancel_cutoff_minutes" validate:"min=0,max=43200"` // 1440 - отменить не позже чем за сутки
+	MaxActiveBookings   int `json:"max_active_bookings" validate:"min=0,max=100"`     // 2 - не больше двух предстоящих записей
+	BufferMinutes       int `json:"buffer_minutes" validate:"min=0,max=240"`          // 10 - перерыв между приемами
+}
+
+// BookingPolicyResponse - правила записи к врачу
+type BookingPolicyResponse struct {
+	DoctorID            uuid.UUID  `json:"doctor_id"`
+	MinLeadMinutes      int        `json:"min_lead_minutes"`
+	MaxHorizonDays      int        `json:"max_horizon_days"`
+	CancelCutoffMinutes int        `json:"cancel_cutoff_minutes"`
+	MaxActiveBookings   int        `json:"max_active_bookings"`
+	BufferMinutes       int        `json:"buffer_minutes"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"` // nil - врач правила не настраивал
 }
 
 // === EXCEPTION DTOs ===
 
 // AddExceptionRequest - добавление исключения
 type AddExceptionRequest struct {
-	Date            string  `json:"date" validate:"required,len=10"`                        // "2024-06-15"
-	Type            string  `json:"type" validate:"required,oneof=day_off custom_hours"`    // "day_off", "custom_hours"
-	CustomStartTime *string `json:"custom_start_time,omitempty" validate:"omitempty,len=5"` // "10:00"
-	CustomEndTime   *string `json:"custom_end_time,omitempty" validate:"omitempty,len=5"`   // "16:00"
-	Reason          string  `json:"reason" validate:"max=255"`                              // "Отпуск"
+	Date            string  `json:"date" validate:"required,len=10"`                              // "2024-06-15", первый день
+	EndDate         *string `json:"end_date,omitempty" validate:"omitempty,len=10"`               // "2024-06-28", последний день отпуска
+	Recurrence      string  `json:"recurrence" validate:"omitempty,oneof=weekly monthly_weekday"` // "monthly_weekday" - каждый первый понедельник, если date - первый понедельник
+	Type            string  `json:"type" validate:"required,oneof=day_off custom_hours"`          // "day_off", "custom_hours"
+	CustomStartTime *string `json:"custom_start_time,omitempty" validate:"omitempty,len=5"`       // "10:00"
+	CustomEndTime   *string `json:"custom_end_time,omitempty" validate:"omitempty,len=5"`         // "16:00"
+	Reason          string  `json:"reason" validate:"max=255"`                                    // "Отпуск"
 }
 
 // ExceptionResponse - ответ с исключением
 type ExceptionResponse struct {
-	ID              uuid.UUID `json:"id"`
-	DoctorID        uuid.UUID `json:"doctor_id"`
-	Date            time.Time `json:"date"`
-	Type            string    `json:"type"`
-	CustomStartTime *string   `json:"custom_start_time,omitempty"`
-	CustomEndTime   *string   `json:"custom_end_time,omitempty"`
-	Reason          string    `json:"reason"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              uuid.UUID  `json:"id"`
+	DoctorID        uuid.UUID  `json:"doctor_id"`
+	Date            time.Time  `json:"date"`
+	EndDate         *time.Time `json:"end_date,omitempty"`
+	Recurrence      string     `json:"recurrence,omitempty"`
+	Type            string     `json:"type"`
+	CustomStartTime *string    `json:"custom_start_time,omitempty"`
+	CustomEndTime   *string    `json:"custom_end_time,omitempty"`
+	Reason          string     `json:"reason"`
+	BusyStart       *time.Time `json:"busy_start,omitempty"`
+	BusyEnd         *time.Time `json:"busy_end,omitempty"`
+	Source          string     `json:"source,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	// Последствия для уже сгенерированных слотов (только при создании и изменении)
+	RemovedSlots       int                    `json:"removed_slots,omitempty"`       // удалено свободных слотов
+	BookedAppointments []*AppointmentResponse `json:"booked_appointments,omitempty"` // записи пациентов, попавшие в исключение - требуют решения врача
+	HeldAppointments   []*AppointmentResponse `json:"held_appointments,omitempty"`   // слоты, удерживаемые за пациентом до оплаты
+}
+
+// UpdateExceptionRequest - изменение исключения
+type UpdateExceptionRequest struct {
+	Date            *string `json:"date,omitempty" validate:"omitempty,len=10"`
+	EndDate         *string `json:"end_date,omitempty" validate:"omitempty,max=10"` // "" - снять конец периода
+	Recurrence      *string `json:"recurrence,omitempty" validate:"omitempty,oneof=none weekly monthly_weekday"`
+	Type            *string `json:"type,omitempty" validate:"omitempty,oneof=day_off custom_hours"`
+	CustomStartTime *string `json:"custom_start_time,omitempty" validate:"omitempty,len=5"`
+	CustomEndTime   *string `json:"custom_end_time,omitempty" validate:"omitempty,len=5"`
+	Reason          *string `json:"reason,omitempty" validate:"omitempty,max=255"`
+}
+
+// === CALENDAR DTOs ===
+
+// CalendarFeedResponse - ссылка на персональную ICS-ленту
+type CalendarFeedResponse struct {
+	URL       string    `json:"url"` // подписка в календаре телефона
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RegisterExternalCalendarRequest - подключение внешнего ICS-календаря по ссылке
+type RegisterExternalCalendarRequest struct {
+	URL string `json:"url" validate:"required,max=2048"` // "https://hospital.example/roster.ics" или webcal://
+}
+
+// CalendarImportResponse - итоги импорта занятого времени
+type CalendarImportResponse struct {
+	CalendarID         *uuid.UUID             `json:"calendar_id,omitempty"` // для подключенных по ссылке
+	Created            int                    `json:"created"`               // новых блокирующих исключений
+	Updated            int                    `json:"updated"`               // обновленных (событие изменилось во внешнем календаре)
+	Removed            int                    `json:"removed"`               // удаленных (события больше нет в календаре)
+	RemovedSlots       int                    `json:"removed_slots"`         // удалено свободных слотов, пересекающихся с занятым временем
+	BookedAppointments []*AppointmentResponse `json:"booked_appointments,omitempty"`
+	HeldAppointments   []*AppointmentResponse `json:"held_appointments,omitempty"` // удерживаются за пациентом до оплаты: после оплаты станут записями
+}
+
+// ExternalCalendarResponse - подключенный внешний календарь
+type ExternalCalendarResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	URL          string     `json:"url"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"` // ошибка последней синхронизации, если была
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 // === COMMON RESPONSES ===
@@ -141,6 +546,7 @@ type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"` // машинный код ошибки, например нарушенное правило записи
 }
 
 // PaginatedResponse - ответ с пагинацией
@@ -172,6 +578,10 @@ type GeneratedSlotDetail struct {
 	PatientID    *uuid.UUID `json:"patient_id,omitempty"`
 	PatientNotes string     `json:"patient_notes,omitempty"`
 	BookedAt     *time.Time `json:"booked_at,omitempty"`
+
+	// Групповое занятие: участники - в GET /appointments/:id/participants
+	Capacity   int `json:"capacity"`
+	SeatsTaken int `json:"seats_taken"`
 }
 
 // ScheduleMetadata - метаданные расписания для сгенерированных слотов
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ключи маршрутизации событий жизненного цикла записи
+const (
+	EventAppointmentBooked      = "appointment.booked"
+	EventAppointmentCanceled    = "appointment.canceled"
+	EventAppointmentCompleted   = "appointment.completed"
+	EventAppointmentRescheduled = "appointment.rescheduled"
+	EventAppointmentReminder    = "appointment.reminder"
+	EventFollowUpProposed       = "appointment.follow_up_proposed"
+)
+
+// Получатели события; пусто - уведомляются и пациент, и врач
+const (
+	RecipientPatient = "patient"
+	RecipientDoctor  = "doctor"
+)
+
+// AppointmentEvent событие изменения записи к врачу,
+// которое будет отправлено в RabbitMQ
+type AppointmentEvent struct {
+	EventType       string     `json:"event_type"`
+	AppointmentID   uuid.UUID  `json:"appointment_id"`
+	DoctorID        uuid.UUID  `json:"doctor_id"`
+	PatientID       *uuid.UUID `json:"patient_id,omitempty"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	Title           string     `json:"title"`
+	Status          string     `json:"status"`
+	AppointmentType string     `json:"appointment_type"`
+	MeetingLink     *string    `json:"meeting_link,omitempty"`
+
+	// Прежнее время приема (только для appointment.rescheduled)
+	PreviousAppointmentID *uuid.UUID `json:"previous_appointment_id,omitempty"`
+	PreviousStartTime     *time.Time `json:"previous_start_time,omitempty"`
+	PreviousEndTime       *time.Time `json:"previous_end_time,omitempty"`
+
+	// Кто и почему отменил запись, и свободные слоты того же врача взамен (только для appointment.canceled)
+	CanceledByRole   string       `json:"canceled_by_role,omitempty"`
+	CancelReason     string       `json:"cancel_reason,omitempty"`
+	AlternativeSlots []SlotOption `json:"alternative_slots,omitempty"`
+
+	// Предложение повторного приема (только для appointment.follow_up_proposed):
+	// событие относится к прошедшему приему, предложенные слоты - в AlternativeSlots
+	ProposalID *uuid.UUID `json:"proposal_id,omitempty"`
+
+	// За сколько минут до приема отправлено напоминание (только для appointment.reminder)
+	ReminderOffsetMinutes int `json:"reminder_offset_minutes,omitempty"`
+
+	// Кому адресовано событие (RecipientPatient, RecipientDoctor); пусто - пациенту и врачу.
+	// Напоминания группового занятия уходят каждому участнику, а врачу - одно на занятие
+	Recipient string `json:"recipient,omitempty"`
+
+	// Часовой пояс врача (IANA), в котором получателю показывается время приема
+	Timezone string `json:"timezone,omitempty"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// SlotOption - свободный слот, предлагаемый пациенту взамен отмененной записи
+type SlotOption struct {
+	ID        uuid.UUID `json:"id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Статусы предложения повторного приема
+const (
+	ProposalPending  = "pending"  // ждет выбора пациента
+	ProposalAccepted = "accepted" // пациент записался на один из слотов
+)
+
+// FollowUpProposal - повторный прием, предложенный врачом: несколько своих свободных слотов,
+// из которых пациент выбирает один. Запись получает ссылку на прием PreviousAppointmentID
+type FollowUpProposal struct {
+	ID                    uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	DoctorID              uuid.UUID  `gorm:"type:uuid;not null" json:"doctor_id"`
+	PatientID             uuid.UUID  `gorm:"type:uuid;not null;index" json:"patient_id"`
+	PreviousAppointmentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"previous_appointment_id"`
+	SlotIDsJSON           string     `gorm:"type:text;not null" json:"-"`
+	AppointmentType       string     `gorm:"type:varchar(10)" json:"appointment_type,omitempty"` // offline, online; пусто - выбирает пациент
+	Message               string     `gorm:"type:text" json:"message,omitempty"`                 // "Контроль анализов через 2 недели"
+	Status                string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	AppointmentID         *uuid.UUID `gorm:"type:uuid" json:"appointment_id,omitempty"` // запись, выбранная пациентом
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (FollowUpProposal) TableName() string {
+	return "follow_up_proposals"
+}
+
+func (p *FollowUpProposal) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// SlotIDs возвращает предложенные слоты
+func (p *FollowUpProposal) SlotIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	if p.SlotIDsJSON != "" {
+		json.Unmarshal([]byte(p.SlotIDsJSON), &ids)
+	}
+	return ids
+}
+
+// SetSlotIDs сохраняет предложенные слоты в JSON
+func (p *FollowUpProposal) SetSlotIDs(ids []uuid.UUID) {
+	if data, err := json.Marshal(ids); err == nil {
+		p.SlotIDsJSON = string(data)
+	}
+}
+
+// HasSlot - слот входит в предложение
+func (p *FollowUpProposal) HasSlot(slotID uuid.UUID) bool {
+	for _, id := range p.SlotIDs() {
+		if id == slotID {
+			return true
+		}
+	}
+	return false
+}
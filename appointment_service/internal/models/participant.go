@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Статусы участника группового занятия
+const (
+	ParticipantBooked   = "booked"
+	ParticipantCanceled = "canceled"
+)
+
+// AppointmentParticipant - пациент, занявший место на групповом занятии (Appointment с Capacity > 1).
+// У группового занятия PatientID не заполняется: все записавшиеся хранятся здесь
+type AppointmentParticipant struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	AppointmentID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"appointment_id"`
+	PatientID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"patient_id"`
+	Status          string     `gorm:"type:varchar(20);not null;default:'booked'" json:"status"`            // booked, canceled
+	AppointmentType string     `gorm:"type:varchar(10);not null;default:'offline'" json:"appointment_type"` // offline, online
+	PatientNotes    string     `gorm:"type:text" json:"patient_notes"`
+	CanceledAt      *time.Time `gorm:"type:timestamp with time zone" json:"canceled_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (AppointmentParticipant) TableName() string {
+	return "appointment_participants"
+}
+
+func (p *AppointmentParticipant) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Cancel - пациент освободил место
+func (p *AppointmentParticipant) Cancel() {
+	now := time.Now()
+	p.Status = ParticipantCanceled
+	p.CanceledAt = &now
+	p.UpdatedAt = now
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppointmentPayment - платеж пациента за удержание слота.
+// Хранится отдельно от записи: после снятия удержания слот может занять другой пациент,
+// а позднее уведомление провайдера все равно сопоставляется с тем, кто платил
+type AppointmentPayment struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	PaymentID     string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"payment_id"`
+	AppointmentID uuid.UUID `gorm:"type:uuid;not null;index" json:"appointment_id"`
+	PatientID     uuid.UUID `gorm:"type:uuid;not null;index" json:"patient_id"`
+	Amount        float64   `gorm:"type:decimal(10,2);not null;default:0" json:"amount"`
+	Status        string    `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending, paid, failed, expired, canceled, refund_required
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (AppointmentPayment) TableName() string {
+	return "appointment_payments"
+}
+
+func (p *AppointmentPayment) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppointmentReminder - отметка об отправленном напоминании о приеме.
+// Уникальность (appointment_id, patient_id, offset_minutes) гарантирует,
+// что одно и то же напоминание не уйдет дважды - даже после рестарта или с нескольких реплик
+type AppointmentReminder struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	AppointmentID uuid.UUID `gorm:"type:uuid;not null" json:"appointment_id"`
+	PatientID     uuid.UUID `gorm:"type:uuid;not null" json:"patient_id"` // получатель; для врача группового занятия - ID врача
+	OffsetMinutes int       `gorm:"not null" json:"offset_minutes"`
+	StartTime     time.Time `gorm:"type:timestamp with time zone;not null" json:"start_time"`
+	SentAt        time.Time `gorm:"type:timestamp with time zone;not null" json:"sent_at"`
+}
+
+func (AppointmentReminder) TableName() string {
+	return "appointment_reminders"
+}
+
+func (r *AppointmentReminder) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -29,8 +29,16 @@ type DoctorSchedule struct {
 	SlotDuration      int64  `gorm:"type:bigint;not null;default:30" json:"slot_duration"`                  // 30 минут
 	SlotTitle         string `gorm:"type:varchar(255)" json:"slot_title"`                                   // "Консультация"
 	AppointmentFormat string `gorm:"type:varchar(10);not null;default:'offline'" json:"appointment_format"` // "offline", "online", "both"
+	SlotCapacity      int    `gorm:"type:integer;not null;default:1" json:"slot_capacity"`                  // 1 - личный прием, больше - групповое занятие
 	IsActive          bool   `gorm:"type:boolean;default:true" json:"is_active"`                            // Активно ли расписание
 
+	// Автоматическая генерация слотов: на сколько дней вперед поддерживать календарь (0 - выключена)
+	GenerateDaysAhead int        `gorm:"not null;default:0" json:"generate_days_ahead"`
+	GeneratedUntil    *time.Time `gorm:"type:date" json:"generated_until,omitempty"` // последний день, обработанный автогенерацией
+
+	// Часовой пояс врача (IANA), в котором заданы StartTime/EndTime и перерыв
+	Timezone string `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"` // "Asia/Almaty"
+
 	CreatedAt time.Time `gorm:"type:timestamp with time zone" json:"created_at"`
 	UpdatedAt time.Time `gorm:"type:timestamp with time zone" json:"updated_at"`
 }
@@ -51,6 +59,14 @@ func (s *DoctorSchedule) SetWorkDays(days []int) {
 	}
 }
 
+// Location возвращает часовой пояс расписания; пустой пояс считается UTC
+func (s *DoctorSchedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
 func (DoctorSchedule) TableName() string {
 	return "doctor_schedules"
 }
@@ -62,22 +78,81 @@ func (s *DoctorSchedule) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Повторяемость исключений
+const (
+	RecurrenceNone           = ""                // разовое исключение: день или период Date..EndDate
+	RecurrenceWeekly         = "weekly"          // каждую неделю в день недели Date
+	RecurrenceMonthlyWeekday = "monthly_weekday" // каждый месяц в тот же по счету день недели, что и Date (например, первый понедельник)
+)
+
 // ScheduleException - исключения в расписании (выходные, изменения)
 type ScheduleException struct {
 	ID       uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
 	DoctorID uuid.UUID `gorm:"type:uuid;not null;index" json:"doctor_id"`
-	Date     time.Time `gorm:"type:date;not null;index" json:"date"`
-	Type     string    `gorm:"type:varchar(20);not null" json:"type"` // "day_off", "custom_hours"
+	Date     time.Time `gorm:"type:date;not null;index" json:"date"`  // первый день исключения
+	Type     string    `gorm:"type:varchar(20);not null" json:"type"` // "day_off", "custom_hours", "busy"
+
+	// Последний день периода (включительно); для повторяющихся - конец действия, nil - бессрочно
+	EndDate    *time.Time `gorm:"type:date" json:"end_date,omitempty"`
+	Recurrence string     `gorm:"type:varchar(20);not null;default:''" json:"recurrence,omitempty"`
 
 	// Для кастомных часов
 	CustomStartTime *string `gorm:"type:varchar(5)" json:"custom_start_time,omitempty"`
 	CustomEndTime   *string `gorm:"type:varchar(5)" json:"custom_end_time,omitempty"`
 
+	// Занятое время (type = busy): точный интервал, в который нельзя предлагать слоты
+	BusyStart *time.Time `gorm:"type:timestamp with time zone" json:"busy_start,omitempty"`
+	BusyEnd   *time.Time `gorm:"type:timestamp with time zone" json:"busy_end,omitempty"`
+
+	// Источник импортированного исключения ("ics_upload", "ics_url:<id>") и UID события в нем
+	Source      string `gorm:"type:varchar(64);not null;default:''" json:"source,omitempty"`
+	ExternalUID string `gorm:"type:varchar(512);not null;default:''" json:"-"`
+
 	Reason    string    `gorm:"type:varchar(255)" json:"reason"` // "Отпуск"
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Overlaps проверяет, пересекает ли занятое время интервал [start, end)
+func (e *ScheduleException) Overlaps(start, end time.Time) bool {
+	if e.BusyStart == nil || e.BusyEnd == nil {
+		return false
+	}
+	return e.BusyStart.Before(end) && e.BusyEnd.After(start)
+}
+
+// AppliesTo проверяет, действует ли исключение в календарный день day (сравниваются только даты)
+func (e *ScheduleException) AppliesTo(day time.Time) bool {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	start := time.Date(e.Date.Year(), e.Date.Month(), e.Date.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(start) {
+		return false
+	}
+
+	if e.EndDate != nil {
+		end := time.Date(e.EndDate.Year(), e.EndDate.Month(), e.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+		if day.After(end) {
+			return false
+		}
+	} else if e.Recurrence == RecurrenceNone {
+		return day.Equal(start)
+	}
+
+	switch e.Recurrence {
+	case RecurrenceWeekly:
+		return day.Weekday() == start.Weekday()
+	case RecurrenceMonthlyWeekday:
+		return day.Weekday() == start.Weekday() && weekOfMonth(day) == weekOfMonth(start)
+	default:
+		return true
+	}
+}
+
+// weekOfMonth - порядковый номер дня недели в месяце: 1 для первого понедельника, 2 для второго и т.д.
+func weekOfMonth(day time.Time) int {
+	return (day.Day()-1)/7 + 1
+}
+
 func (ScheduleException) TableName() string {
 	return "schedule_exceptions"
 }
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Статусы записи
+const (
+	StatusAvailable  = "available"   // свободный слот
+	StatusHeld       = "held"        // слот удерживается за пациентом до оплаты
+	StatusBooked     = "booked"      // пациент записан
+	StatusCheckedIn  = "checked_in"  // пациент пришел / подключился
+	StatusInProgress = "in_progress" // прием идет
+	StatusCompleted  = "completed"   // прием завершен
+	StatusCanceled   = "canceled"    // запись или слот отменены
+	StatusNoShow     = "no_show"     // пациент не пришел
+)
+
+// statusTransitions - допустимые переходы между статусами записи.
+// held → booked - оплата подтверждена, held → available - удержание снято или истекло.
+// booked → available - освобождение слота при переносе записи.
+// Запись по виду приема при освобождении не становится слотом, а закрывается (→ canceled).
+// Завершить прием можно и без явной отметки о приходе и начале
+var statusTransitions = map[string][]string{
+	StatusAvailable:  {StatusHeld, StatusBooked, StatusCanceled},
+	StatusHeld:       {StatusBooked, StatusAvailable, StatusCanceled},
+	StatusBooked:     {StatusCheckedIn, StatusInProgress, StatusCompleted, StatusCanceled, StatusNoShow, StatusAvailable},
+	StatusCheckedIn:  {StatusInProgress, StatusCompleted, StatusCanceled},
+	StatusInProgress: {StatusCompleted},
+	StatusCompleted:  {},
+	StatusCanceled:   {},
+	StatusNoShow:     {},
+}
+
+// CanTransition проверяет, допустим ли переход из статуса from в статус to
+func CanTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnownStatus проверяет, что status - один из статусов записи
+func IsKnownStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// IllegalTransitionError - попытка недопустимого перехода статуса записи
+type IllegalTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("cannot change appointment status from '%s' to '%s'", e.From, e.To)
+}
+
+// AppointmentStatusHistory - запись журнала переходов статуса
+type AppointmentStatusHistory struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	AppointmentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"appointment_id"`
+	FromStatus    string     `gorm:"type:varchar(20);not null" json:"from_status"`
+	ToStatus      string     `gorm:"type:varchar(20);not null" json:"to_status"`
+	ActorID       *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	ActorRole     string     `gorm:"type:varchar(20)" json:"actor_role"` // patient, doctor, system
+	Reason        string     `gorm:"type:text" json:"reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (AppointmentStatusHistory) TableName() string {
+	return "appointment_status_history"
+}
+
+func (h *AppointmentStatusHistory) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
+
+// TransitionTo - переводит запись в статус to, если переход допустим.
+// Переход запоминается и сохраняется репозиторием в журнал вместе с записью
+func (a *Appointment) TransitionTo(to string, actorID *uuid.UUID, actorRole, reason string) error {
+	if !CanTransition(a.Status, to) {
+		return &IllegalTransitionError{From: a.Status, To: to}
+	}
+
+	a.statusChanges = append(a.statusChanges, &AppointmentStatusHistory{
+		AppointmentID: a.ID,
+		FromStatus:    a.Status,
+		ToStatus:      to,
+		ActorID:       actorID,
+		ActorRole:     actorRole,
+		Reason:        reason,
+		CreatedAt:     time.Now(),
+	})
+	a.Status = to
+	return nil
+}
+
+// StatusChanges - переходы статуса, еще не сохраненные в журнал
+func (a *Appointment) StatusChanges() []*AppointmentStatusHistory {
+	return a.statusChanges
+}
+
+// PersistedStatus - статус записи в базе до несохраненных переходов;
+// по нему репозиторий условно обновляет строку
+func (a *Appointment) PersistedStatus() string {
+	if len(a.statusChanges) > 0 {
+		return a.statusChanges[0].FromStatus
+	}
+	return a.Status
+}
+
+// ClearStatusChanges - вызывается репозиторием после сохранения журнала
+func (a *Appointment) ClearStatusChanges() {
+	a.statusChanges = nil
+}
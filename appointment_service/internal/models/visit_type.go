@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// VisitType - вид приема врача со своей длительностью, ценой и форматом
+// ("Первичная консультация" на 60 минут, "Повторный прием" на 20 минут).
+// Запись по виду приема вырезается из свободного рабочего времени, а не из заранее созданных слотов
+type VisitType struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	DoctorID uuid.UUID `gorm:"type:uuid;not null;index" json:"doctor_id"`
+	Title    string    `gorm:"type:varchar(255);not null" json:"title"`                   // "Первичная консультация"
+	Duration int64     `gorm:"type:bigint;not null" json:"duration"`                      // 60 минут
+	Price    float64   `gorm:"type:decimal(10,2);not null;default:0" json:"price"`        // 0 - бесплатно
+	Format   string    `gorm:"type:varchar(10);not null;default:'offline'" json:"format"` // "offline", "online", "both"
+	IsActive bool      `gorm:"type:boolean;not null;default:true" json:"is_active"`       // Доступен ли для записи
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (VisitType) TableName() string {
+	return "visit_types"
+}
+
+func (v *VisitType) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// DurationTime - длительность приема
+func (v *VisitType) DurationTime() time.Duration {
+	return time.Duration(v.Duration) * time.Minute
+}
@@ -0,0 +1,67 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// FakeSignatureHeader - заголовок с подписью уведомления фейкового провайдера
+const FakeSignatureHeader = "X-Fake-Payment-Signature"
+
+// FakeProvider - локальный провайдер для разработки: платежи не списываются,
+// а подтверждение отправляется вручную POST-запросом на webhook с HMAC-SHA256 подписью тела
+type FakeProvider struct {
+	secret []byte
+}
+
+// NewFakeProvider - создание фейкового провайдера с секретом для подписи уведомлений
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(secret)}
+}
+
+// CreatePayment - выдает идентификатор платежа без обращения к внешним системам
+func (p *FakeProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*Payment, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("invalid payment amount: %.2f", req.Amount)
+	}
+	return &Payment{ID: "fake_" + uuid.New().String()}, nil
+}
+
+// ParseConfirmation - проверяет подпись и разбирает уведомление {"payment_id": "...", "status": "succeeded"}
+func (p *FakeProvider) ParseConfirmation(body []byte, header http.Header) (*Confirmation, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var confirmation Confirmation
+	if err := json.Unmarshal(body, &confirmation); err != nil {
+		return nil, fmt.Errorf("invalid payment notification: %w", err)
+	}
+	if confirmation.PaymentID == "" {
+		return nil, fmt.Errorf("invalid payment notification: payment_id is empty")
+	}
+	if confirmation.Status != StatusSucceeded && confirmation.Status != StatusFailed {
+		return nil, fmt.Errorf("invalid payment notification: unknown status '%s'", confirmation.Status)
+	}
+
+	return &confirmation, nil
+}
+
+// Sign - подпись тела уведомления; нужна локальным скриптам, имитирующим оплату
+func (p *FakeProvider) Sign(body []byte) string {
+	return hex.EncodeToString(p.sign(body))
+}
+
+func (p *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
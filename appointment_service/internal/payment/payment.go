@@ -0,0 +1,49 @@
+// Package payment - платежные провайдеры для оплаты записей к врачу
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы платежа в подтверждении провайдера
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// ErrInvalidSignature - уведомление провайдера не прошло проверку подписи
+var ErrInvalidSignature = errors.New("invalid payment notification signature")
+
+// CreatePaymentRequest - платеж за удерживаемый слот
+type CreatePaymentRequest struct {
+	AppointmentID uuid.UUID
+	PatientID     uuid.UUID
+	Amount        float64
+	Currency      string
+	Description   string
+	ExpiresAt     time.Time // после этого момента слот будет освобожден
+}
+
+// Payment - созданный у провайдера платеж
+type Payment struct {
+	ID         string // идентификатор платежа у провайдера
+	PaymentURL string // страница оплаты для пациента (может быть пустой)
+}
+
+// Confirmation - уведомление провайдера о результате платежа
+type Confirmation struct {
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"` // succeeded, failed
+}
+
+// Provider - платежный провайдер. Результат оплаты приходит асинхронно уведомлением (webhook),
+// которое провайдер проверяет и разбирает сам
+type Provider interface {
+	CreatePayment(ctx context.Context, req CreatePaymentRequest) (*Payment, error)
+	ParseConfirmation(body []byte, header http.Header) (*Confirmation, error)
+}
@@ -1,13 +1,104 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/printprince/vitalem/appointment_service/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrSlotTaken - слот уже занят или изменен параллельным запросом
+var ErrSlotTaken = errors.New("slot already taken")
+
+// ErrAlreadyParticipant - пациент уже занял место на этом групповом занятии
+var ErrAlreadyParticipant = errors.New("patient already booked this session")
+
+// ErrAlreadyAttached - файл уже прикреплен к этой записи
+var ErrAlreadyAttached = errors.New("file already attached")
+
+// ErrBookingLimitReached - у пациента уже максимум незавершенных записей к врачу
+var ErrBookingLimitReached = errors.New("patient booking limit reached")
+
+// ErrBufferConflict - между записью и соседними записями врача не остается перерыва
+var ErrBufferConflict = errors.New("no buffer between doctor appointments")
+
+// BookingLimits - правила врача, которые перепроверяются в транзакции бронирования,
+// чтобы параллельные записи не обошли их (nil - без ограничений)
+type BookingLimits struct {
+	PatientID         uuid.UUID
+	MaxActiveBookings int           // 0 - без ограничения
+	Buffer            time.Duration // перерыв между записями врача; 0 - не нужен
+}
+
+// ImportResult - итоги синхронизации импортированных исключений.
+// Changed - созданные и измененные исключения, по ним нужно освободить пересекающиеся слоты
+type ImportResult struct {
+	Created int
+	Updated int
+	Removed int
+	Changed []*models.ScheduleException
+}
+
+// SlotSearchFilter - условия поиска свободных слотов по нескольким врачам
+type SlotSearchFilter struct {
+	DoctorIDs []uuid.UUID // пусто - все врачи
+	From      time.Time
+	To        time.Time
+	Format    string // online, offline; слоты "both" подходят под любой
+	TimeFrom  string // "09:00" - начало слота не раньше, в поясе Timezone
+	TimeTo    string // "13:00" - окончание слота не позже, в поясе Timezone
+	Timezone  string
+	Now       time.Time // слоты, недоступные по правилам записи врача на этот момент, не попадают в выдачу
+	Offset    int
+	Limit     int
+}
+
+// AppointmentListFilter - условия выборки записей врача или пациента
+type AppointmentListFilter struct {
+	DoctorID  *uuid.UUID
+	PatientID *uuid.UUID
+	Statuses  []string   // пусто - любые
+	From      *time.Time // начало приема не раньше
+	To        *time.Time // начало приема раньше
+	Upcoming  *bool      // true - предстоящие, false - прошедшие относительно Now
+	Now       time.Time
+	Desc      bool // сортировка по времени начала от поздних к ранним
+	Offset    int
+	Limit     int
+}
+
+// AnalyticsFilter - период и параметры аналитики приема врача
+type AnalyticsFilter struct {
+	DoctorID uuid.UUID
+	From     time.Time
+	To       time.Time
+	Timezone string  // пояс, в котором считаются периоды, дни недели и часы
+	Bucket   string  // day, week, month
+	Price    float64 // цена приема для неоплаченных проведенных приемов
+}
+
+// AnalyticsCounts - агрегаты по записям за период разбивки
+type AnalyticsCounts struct {
+	Bucket    time.Time // начало периода в поясе фильтра
+	Offered   int64
+	Booked    int64
+	Completed int64
+	Canceled  int64
+	NoShow    int64
+	Online    int64
+	Offline   int64
+	Revenue   float64
+}
+
+// AnalyticsLoad - число занятых приемов по дню недели (1-7) или часу (0-23)
+type AnalyticsLoad struct {
+	Key   int
+	Count int64
+}
+
 // AppointmentRepository - интерфейс репозитория
 type AppointmentRepository interface {
 	// Schedules
@@ -22,20 +113,105 @@ type AppointmentRepository interface {
 	GetScheduleSlots(scheduleID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error)
 	// НОВЫЙ метод для обновления типа записи в слотах расписания
 	UpdateScheduleAppointmentType(scheduleID uuid.UUID, appointmentType string) error
+	GetAutoGenerateSchedules() ([]*models.DoctorSchedule, error)
+	AdvanceScheduleGeneratedUntil(scheduleID uuid.UUID, previous *time.Time, next time.Time) (bool, error)
+
+	// Visit types
+	CreateVisitType(visitType *models.VisitType) error
+	GetVisitTypeByID(id uuid.UUID) (*models.VisitType, error)
+	GetDoctorVisitTypes(doctorID uuid.UUID, activeOnly bool) ([]*models.VisitType, error)
+	UpdateVisitType(visitType *models.VisitType) error
+	DeleteVisitType(id uuid.UUID) error
+
+	// Booking policies
+	GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicy, error)
+	SaveBookingPolicy(policy *models.BookingPolicy) error
+	CountPatientActiveBookings(doctorID, patientID uuid.UUID, now time.Time) (int64, error)
 
 	// Appointments
 	CreateAppointment(appointment *models.Appointment) error
 	GetAppointmentByID(id uuid.UUID) (*models.Appointment, error)
 	GetAvailableSlots(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error)
+	SearchAvailableSlots(filter SlotSearchFilter) ([]*models.Appointment, int64, error)
+	ListAppointments(filter AppointmentListFilter) ([]*models.Appointment, int64, error)
 	GetDoctorAppointments(doctorID uuid.UUID) ([]*models.Appointment, error)
 	GetPatientAppointments(patientID uuid.UUID) ([]*models.Appointment, error)
 	UpdateAppointment(appointment *models.Appointment) error
 	CheckSlotExists(doctorID uuid.UUID, startTime, endTime time.Time) (bool, error)
+	BookSlot(appointment *models.Appointment, limits *BookingLimits) error
+	CancelBooked(appointment *models.Appointment) error
+	GetDoctorBookedAppointments(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error)
+	CloseAvailableSlots(doctorID uuid.UUID, startDate, endDate time.Time, canceledBy uuid.UUID, reason string) (int64, error)
+	RescheduleAppointment(from, to *models.Appointment, patientID uuid.UUID) error
+	ChangeStatus(appointment *models.Appointment, columns ...string) error
+	HoldSlot(appointment *models.Appointment, limits *BookingLimits) error
+	SavePayment(appointment *models.Appointment, payment *models.AppointmentPayment) error
+	ConfirmPayment(appointment *models.Appointment, payment *models.AppointmentPayment) error
+	ReleaseHold(appointment *models.Appointment, patientID uuid.UUID, paymentID *string) error
+	GetPayment(paymentID string) (*models.AppointmentPayment, error)
+	UpdatePaymentStatus(paymentID, status string) error
+	GetHeldSlots(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error)
+	GetExpiredHolds(now time.Time) ([]*models.Appointment, error)
+	GetAppointmentStatusHistory(appointmentID uuid.UUID) ([]*models.AppointmentStatusHistory, error)
+	GetDoctorOccupiedTime(doctorID uuid.UUID, from, to time.Time) ([]*models.Appointment, error)
+	CreateVisit(appointment *models.Appointment, limits *BookingLimits) error
+	SaveMeeting(appointment *models.Appointment) error
+
+	// Group sessions
+	JoinGroupSession(appointmentID uuid.UUID, participant *models.AppointmentParticipant, limits *BookingLimits) (*models.Appointment, error)
+	LeaveGroupSession(appointmentID, patientID uuid.UUID) (*models.Appointment, error)
+	GetParticipants(appointmentID uuid.UUID) ([]*models.AppointmentParticipant, error)
+	GetParticipant(appointmentID, patientID uuid.UUID) (*models.AppointmentParticipant, error)
+
+	// Follow-ups
+	BookFollowUpSlot(appointment *models.Appointment) error
+	GetAppointmentsByIDs(ids []uuid.UUID) ([]*models.Appointment, error)
+	GetVisitChain(appointmentID uuid.UUID) ([]*models.Appointment, error)
+	CreateFollowUpProposal(proposal *models.FollowUpProposal) error
+	GetFollowUpProposalByID(id uuid.UUID) (*models.FollowUpProposal, error)
+	GetPatientFollowUpProposals(patientID uuid.UUID, status string) ([]*models.FollowUpProposal, error)
+	ClaimFollowUpProposal(proposal *models.FollowUpProposal, appointmentID uuid.UUID) error
+	ReopenFollowUpProposal(proposal *models.FollowUpProposal) error
+	ReopenAcceptedFollowUp(appointmentID, patientID, previousAppointmentID uuid.UUID) (bool, error)
+
+	// Attachments
+	CreateAttachment(attachment *models.AppointmentAttachment) error
+	GetAttachments(appointmentID uuid.UUID) ([]*models.AppointmentAttachment, error)
+	GetAttachment(appointmentID, fileID uuid.UUID) (*models.AppointmentAttachment, error)
+	DeleteAttachment(id uuid.UUID) error
 
 	// Exceptions
 	CreateException(exception *models.ScheduleException) error
 	GetDoctorExceptions(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.ScheduleException, error)
+	GetExceptionByID(id uuid.UUID) (*models.ScheduleException, error)
+	UpdateException(exception *models.ScheduleException) error
 	DeleteException(id uuid.UUID) error
+	GetDoctorSlotsFrom(doctorID uuid.UUID, from time.Time, until *time.Time) ([]*models.Appointment, error)
+	DeleteAvailableSlots(ids []uuid.UUID) (int64, error)
+	GetDoctorBusyTime(doctorID uuid.UUID, from, to time.Time) ([]*models.ScheduleException, error)
+	SyncImportedExceptions(doctorID uuid.UUID, source string, exceptions []*models.ScheduleException, removeMissing bool) (*ImportResult, error)
+
+	// External calendars
+	CreateExternalCalendar(calendar *models.ExternalCalendar) error
+	GetExternalCalendarByID(id uuid.UUID) (*models.ExternalCalendar, error)
+	GetDoctorExternalCalendars(doctorID uuid.UUID) ([]*models.ExternalCalendar, error)
+	GetAllExternalCalendars() ([]*models.ExternalCalendar, error)
+	UpdateExternalCalendar(calendar *models.ExternalCalendar) error
+	DeleteExternalCalendar(calendar *models.ExternalCalendar) error
+
+	// Calendar feeds
+	GetCalendarFeedToken(userID uuid.UUID) (*models.CalendarFeedToken, error)
+	GetCalendarFeedTokenByToken(token string) (*models.CalendarFeedToken, error)
+	SaveCalendarFeedToken(feedToken *models.CalendarFeedToken) error
+
+	// Analytics
+	GetDoctorAnalytics(filter AnalyticsFilter) ([]*AnalyticsCounts, error)
+	GetDoctorLoad(filter AnalyticsFilter, part string) ([]*AnalyticsLoad, error)
+
+	// Reminders
+	GetAppointmentsForReminder(from, to time.Time, offsetMinutes int) ([]*models.Appointment, error)
+	ClaimReminder(reminder *models.AppointmentReminder) (bool, error)
+	ReleaseReminder(id uuid.UUID) error
 }
 
 // appointmentRepository - реализация репозитория
@@ -85,6 +261,76 @@ func (r *appointmentRepository) DeleteSchedule(id uuid.UUID) error {
 	return r.db.Delete(&models.DoctorSchedule{}, "id = ?", id).Error
 }
 
+// === VISIT TYPES ===
+
+func (r *appointmentRepository) CreateVisitType(visitType *models.VisitType) error {
+	return r.db.Create(visitType).Error
+}
+
+func (r *appointmentRepository) GetVisitTypeByID(id uuid.UUID) (*models.VisitType, error) {
+	var visitType models.VisitType
+	err := r.db.Where("id = ?", id).First(&visitType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &visitType, nil
+}
+
+// GetDoctorVisitTypes - каталог видов приема врача; activeOnly - только доступные для записи
+func (r *appointmentRepository) GetDoctorVisitTypes(doctorID uuid.UUID, activeOnly bool) ([]*models.VisitType, error) {
+	var visitTypes []*models.VisitType
+	query := r.db.Where("doctor_id = ?", doctorID)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("duration ASC, title ASC").Find(&visitTypes).Error
+	return visitTypes, err
+}
+
+func (r *appointmentRepository) UpdateVisitType(visitType *models.VisitType) error {
+	return r.db.Save(visitType).Error
+}
+
+func (r *appointmentRepository) DeleteVisitType(id uuid.UUID) error {
+	return r.db.Delete(&models.VisitType{}, "id = ?", id).Error
+}
+
+// openSlotCondition - слоты, на которые еще можно записаться: свободные
+// и групповые занятия, на которых остались места
+const openSlotCondition = `(status = ? OR (status = ? AND capacity > 1 AND seats_taken < capacity))`
+
+// patientAppointmentsCondition - записи пациента: личные и групповые занятия, на которых за ним есть место
+const patientAppointmentsCondition = `(patient_id = ? OR id IN (SELECT appointment_id FROM appointment_participants WHERE patient_id = ? AND status = 'booked'))`
+
+// === BOOKING POLICIES ===
+
+func (r *appointmentRepository) GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicy, error) {
+	var policy models.BookingPolicy
+	err := r.db.Where("doctor_id = ?", doctorID).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SaveBookingPolicy - создает или заменяет правила записи врача
+func (r *appointmentRepository) SaveBookingPolicy(policy *models.BookingPolicy) error {
+	return r.db.Save(policy).Error
+}
+
+// activeBookingStatuses - записи пациента, которые еще предстоят или идут прямо сейчас
+var activeBookingStatuses = []string{models.StatusHeld, models.StatusBooked, models.StatusCheckedIn, models.StatusInProgress}
+
+// CountPatientActiveBookings - сколько незавершенных записей пациента к врачу еще не закончились
+func (r *appointmentRepository) CountPatientActiveBookings(doctorID, patientID uuid.UUID, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Appointment{}).
+		Where("doctor_id = ? AND status IN ? AND end_time > ?", doctorID, activeBookingStatuses, now).
+		Where(patientAppointmentsCondition, patientID, patientID).
+		Count(&count).Error
+	return count, err
+}
+
 // === APPOINTMENTS ===
 
 func (r *appointmentRepository) CreateAppointment(appointment *models.Appointment) error {
@@ -102,13 +348,109 @@ func (r *appointmentRepository) GetAppointmentByID(id uuid.UUID) (*models.Appoin
 
 func (r *appointmentRepository) GetAvailableSlots(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error) {
 	var appointments []*models.Appointment
-	err := r.db.Where("doctor_id = ? AND status = ? AND start_time >= ? AND end_time <= ?",
-		doctorID, "available", startDate, endDate).
+	// Слоты, пересекающиеся с занятым временем из внешних календарей, не предлагаем
+	err := r.db.Where("doctor_id = ? AND start_time >= ? AND end_time <= ?", doctorID, startDate, endDate).
+		Where(openSlotCondition, models.StatusAvailable, models.StatusBooked).
+		Where(`NOT EXISTS (SELECT 1 FROM schedule_exceptions e WHERE e.doctor_id = appointments.doctor_id
+			AND e.type = 'busy' AND e.busy_start < appointments.end_time AND e.busy_end > appointments.start_time)`).
 		Order("start_time ASC").
 		Find(&appointments).Error
 	return appointments, err
 }
 
+// SearchAvailableSlots - страница свободных слотов, подходящих под filter, от ближайших к поздним,
+// и общее число найденных слотов
+func (r *appointmentRepository) SearchAvailableSlots(filter SlotSearchFilter) ([]*models.Appointment, int64, error) {
+	query := r.db.Model(&models.Appointment{}).
+		Where("start_time >= ? AND start_time < ?", filter.From, filter.To).
+		Where(openSlotCondition, models.StatusAvailable, models.StatusBooked).
+		Where(`NOT EXISTS (SELECT 1 FROM schedule_exceptions e WHERE e.doctor_id = appointments.doctor_id
+			AND e.type = 'busy' AND e.busy_start < appointments.end_time AND e.busy_end > appointments.start_time)`).
+		// Правила записи врача: слишком близкие, слишком далекие и стоящие впритык к другим записям слоты не предлагаем
+		Where(`NOT EXISTS (SELECT 1 FROM booking_policies p WHERE p.doctor_id = appointments.doctor_id
+			AND (appointments.start_time < CAST(? AS timestamptz) + p.min_lead_minutes * interval '1 minute'
+				OR (p.max_horizon_days > 0 AND appointments.start_time > CAST(? AS timestamptz) + p.max_horizon_days * interval '1 day')))`,
+			filter.Now, filter.Now).
+		Where(`NOT EXISTS (SELECT 1 FROM booking_policies p JOIN appointments o ON o.doctor_id = p.doctor_id
+			WHERE p.doctor_id = appointments.doctor_id AND p.buffer_minutes > 0 AND o.id <> appointments.id AND o.status IN ?
+			AND o.start_time < appointments.end_time + p.buffer_minutes * interval '1 minute'
+			AND o.end_time > appointments.start_time - p.buffer_minutes * interval '1 minute')`,
+			occupyingStatuses)
+
+	if len(filter.DoctorIDs) > 0 {
+		query = query.Where("doctor_id IN ?", filter.DoctorIDs)
+	}
+	if filter.Format != "" {
+		query = query.Where("appointment_type IN ?", []string{filter.Format, "both"})
+	}
+	// Время суток сравнивается строками "HH:MM" по местному времени пациента
+	if filter.TimeFrom != "" {
+		query = query.Where("to_char(start_time AT TIME ZONE ?, 'HH24:MI') >= ?", filter.Timezone, filter.TimeFrom)
+	}
+	if filter.TimeTo != "" {
+		query = query.Where("to_char(end_time AT TIME ZONE ?, 'HH24:MI') <= ?", filter.Timezone, filter.TimeTo)
+	}
+	// Новая сессия: подсчет и выборка страницы строятся от общих условий независимо
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var appointments []*models.Appointment
+	err := query.Order("start_time ASC, doctor_id ASC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&appointments).Error
+	return appointments, total, err
+}
+
+func (r *appointmentRepository) ListAppointments(filter AppointmentListFilter) ([]*models.Appointment, int64, error) {
+	query := r.db.Model(&models.Appointment{})
+
+	if filter.DoctorID != nil {
+		query = query.Where("doctor_id = ?", *filter.DoctorID)
+	}
+	if filter.PatientID != nil {
+		query = query.Where(patientAppointmentsCondition, *filter.PatientID, *filter.PatientID)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.From != nil {
+		query = query.Where("start_time >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("start_time < ?", *filter.To)
+	}
+	if filter.Upcoming != nil {
+		if *filter.Upcoming {
+			query = query.Where("start_time >= ?", filter.Now)
+		} else {
+			query = query.Where("start_time < ?", filter.Now)
+		}
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "start_time ASC, id ASC"
+	if filter.Desc {
+		order = "start_time DESC, id DESC"
+	}
+
+	var appointments []*models.Appointment
+	err := query.Order(order).
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&appointments).Error
+	return appointments, total, err
+}
+
 func (r *appointmentRepository) GetDoctorAppointments(doctorID uuid.UUID) ([]*models.Appointment, error) {
 	var appointments []*models.Appointment
 	err := r.db.Where("doctor_id = ?", doctorID).
@@ -119,14 +461,389 @@ func (r *appointmentRepository) GetDoctorAppointments(doctorID uuid.UUID) ([]*mo
 
 func (r *appointmentRepository) GetPatientAppointments(patientID uuid.UUID) ([]*models.Appointment, error) {
 	var appointments []*models.Appointment
-	err := r.db.Where("patient_id = ?", patientID).
+	err := r.db.Where(patientAppointmentsCondition, patientID, patientID).
 		Order("start_time ASC").
 		Find(&appointments).Error
 	return appointments, err
 }
 
 func (r *appointmentRepository) UpdateAppointment(appointment *models.Appointment) error {
-	return r.db.Save(appointment).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(appointment).Error; err != nil {
+			return err
+		}
+		return saveStatusHistory(tx, appointment)
+	})
+}
+
+// saveStatusHistory - пишет несохраненные переходы статуса записи в журнал
+func saveStatusHistory(tx *gorm.DB, appointment *models.Appointment) error {
+	changes := appointment.StatusChanges()
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := tx.Create(&changes).Error; err != nil {
+		return err
+	}
+	appointment.ClearStatusChanges()
+	return nil
+}
+
+// updateStatus - условно сохраняет columns записи вместе с журналом переходов.
+// Строка обновляется, только если ее статус в базе все еще тот, из которого делался переход,
+// и выполнены дополнительные условия where; иначе возвращается ErrSlotTaken
+func updateStatus(tx *gorm.DB, appointment *models.Appointment, columns []string, where string, args ...interface{}) error {
+	query := tx.Model(appointment).Where("status = ?", appointment.PersistedStatus())
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+	result := query.Select(columns).Updates(appointment)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSlotTaken
+	}
+	return saveStatusHistory(tx, appointment)
+}
+
+// ChangeStatus - сохраняет переход статуса записи (и поля columns) с журналом.
+// Возвращает ErrSlotTaken, если статус записи успели изменить параллельно
+func (r *appointmentRepository) ChangeStatus(appointment *models.Appointment, columns ...string) error {
+	columns = append([]string{"status", "updated_at"}, columns...)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateStatus(tx, appointment, columns, "")
+	})
+}
+
+// GetAppointmentStatusHistory - журнал переходов статуса записи в хронологическом порядке
+func (r *appointmentRepository) GetAppointmentStatusHistory(appointmentID uuid.UUID) ([]*models.AppointmentStatusHistory, error) {
+	var history []*models.AppointmentStatusHistory
+	err := r.db.Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC").
+		Find(&history).Error
+	return history, err
+}
+
+// bookingColumns - поля записи, которые меняются при бронировании и освобождении слота
+var bookingColumns = []string{"patient_id", "status", "appointment_type", "patient_notes", "previous_appointment_id", "meeting_id", "meeting_link", "updated_at"}
+
+// BookSlot - сохраняет бронирование, только если слот все еще свободен.
+// Проверка и запись выполняются одним UPDATE, поэтому из параллельных запросов выигрывает ровно один,
+// остальные получают ErrSlotTaken. Правила limits проверяются в той же транзакции
+func (r *appointmentRepository) BookSlot(appointment *models.Appointment, limits *BookingLimits) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkBookingLimits(tx, appointment, limits); err != nil {
+			return err
+		}
+		return updateStatus(tx, appointment, bookingColumns, "patient_id IS NULL")
+	})
+}
+
+// checkBookingLimits - проверяет правила limits для записи appointment внутри транзакции tx.
+// Записи выполняются по очереди под advisory-блокировкой до конца транзакции: с перерывом -
+// все записи врача (ключ тот же, что у CreateVisit), с лимитом - записи пациента к этому врачу
+func checkBookingLimits(tx *gorm.DB, appointment *models.Appointment, limits *BookingLimits) error {
+	if limits == nil || (limits.MaxActiveBookings <= 0 && limits.Buffer <= 0) {
+		return nil
+	}
+
+	lockKey := appointment.DoctorID.String()
+	if limits.Buffer <= 0 {
+		lockKey += "/" + limits.PatientID.String()
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lockKey).Error; err != nil {
+		return err
+	}
+
+	if limits.MaxActiveBookings > 0 {
+		var active int64
+		if err := tx.Model(&models.Appointment{}).
+			Where("doctor_id = ? AND status IN ? AND end_time > ? AND id <> ?",
+				appointment.DoctorID, activeBookingStatuses, time.Now(), appointment.ID).
+			Where(patientAppointmentsCondition, limits.PatientID, limits.PatientID).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active >= int64(limits.MaxActiveBookings) {
+			return ErrBookingLimitReached
+		}
+	}
+
+	if limits.Buffer > 0 {
+		var neighbours int64
+		if err := tx.Model(&models.Appointment{}).
+			Where("doctor_id = ? AND status IN ? AND start_time < ? AND end_time > ? AND id <> ?",
+				appointment.DoctorID, occupyingStatuses,
+				appointment.EndTime.Add(limits.Buffer), appointment.StartTime.Add(-limits.Buffer), appointment.ID).
+			Count(&neighbours).Error; err != nil {
+			return err
+		}
+		if neighbours > 0 {
+			return ErrBufferConflict
+		}
+	}
+
+	return nil
+}
+
+// holdColumns - поля записи, которые меняются при удержании слота до оплаты
+var holdColumns = append(append([]string{}, bookingColumns...), "held_until", "payment_amount", "payment_status")
+
+// HoldSlot - удерживает слот за пациентом, только если он все еще свободен (как BookSlot)
+func (r *appointmentRepository) HoldSlot(appointment *models.Appointment, limits *BookingLimits) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkBookingLimits(tx, appointment, limits); err != nil {
+			return err
+		}
+		return updateStatus(tx, appointment, holdColumns, "patient_id IS NULL")
+	})
+}
+
+// SavePayment - сохраняет платеж удерживаемого слота: запись платежа и ссылку на него в слоте.
+// Если удержание успели снять, платеж все равно запоминается (статус canceled), чтобы вернуть деньги,
+// если пациент его оплатит, а вызывающий получает ErrSlotTaken
+func (r *appointmentRepository) SavePayment(appointment *models.Appointment, payment *models.AppointmentPayment) error {
+	released := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(appointment).
+			Where("status = ? AND patient_id = ? AND payment_id IS NULL", models.StatusHeld, payment.PatientID).
+			Select("payment_id", "payment_url", "payment_status", "updated_at").
+			Updates(appointment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			released = true
+			payment.Status = "canceled"
+		}
+		return tx.Create(payment).Error
+	})
+	if err != nil {
+		return err
+	}
+	if released {
+		return ErrSlotTaken
+	}
+	return nil
+}
+
+// ConfirmPayment - подтверждает оплаченное удержание, только если слот все еще удерживается
+// за пациентом этого платежа, и отмечает платеж оплаченным
+func (r *appointmentRepository) ConfirmPayment(appointment *models.Appointment, payment *models.AppointmentPayment) error {
+	columns := []string{"status", "updated_at", "held_until", "payment_status", "meeting_id", "meeting_link"}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateStatus(tx, appointment, columns, "payment_id = ? AND patient_id = ?", payment.PaymentID, payment.PatientID); err != nil {
+			return err
+		}
+		return setPaymentStatus(tx, payment.PaymentID, "paid")
+	})
+}
+
+// releaseColumns - поля записи, которые меняются при снятии удержания
+var releaseColumns = []string{"status", "updated_at", "patient_id", "patient_notes", "previous_appointment_id", "held_until", "payment_id", "payment_url", "payment_status"}
+
+// ReleaseHold - снимает удержание, только если слот все еще удерживается за patientID по платежу paymentID
+// (nil - платеж еще не создан), и закрывает этот платеж итоговым статусом записи.
+// Поэтому запоздавшее уведомление о платеже одного пациента не снимет удержание другого
+func (r *appointmentRepository) ReleaseHold(appointment *models.Appointment, patientID uuid.UUID, paymentID *string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if paymentID == nil {
+			return updateStatus(tx, appointment, releaseColumns, "patient_id = ? AND payment_id IS NULL", patientID)
+		}
+		if err := updateStatus(tx, appointment, releaseColumns, "patient_id = ? AND payment_id = ?", patientID, *paymentID); err != nil {
+			return err
+		}
+		return setPaymentStatus(tx, *paymentID, appointment.PaymentStatus)
+	})
+}
+
+// GetPayment - платеж по идентификатору провайдера
+func (r *appointmentRepository) GetPayment(paymentID string) (*models.AppointmentPayment, error) {
+	var payment models.AppointmentPayment
+	err := r.db.Where("payment_id = ?", paymentID).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// UpdatePaymentStatus - меняет статус платежа (например, refund_required для оплаты снятого удержания)
+func (r *appointmentRepository) UpdatePaymentStatus(paymentID, status string) error {
+	return setPaymentStatus(r.db, paymentID, status)
+}
+
+func setPaymentStatus(tx *gorm.DB, paymentID, status string) error {
+	return tx.Model(&models.AppointmentPayment{}).
+		Where("payment_id = ?", paymentID).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+}
+
+// GetHeldSlots - слоты врача в [startDate, endDate], удерживаемые до оплаты и еще не истекшие
+func (r *appointmentRepository) GetHeldSlots(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	err := r.db.Where("doctor_id = ? AND status = ? AND held_until > ? AND start_time >= ? AND end_time <= ?",
+		doctorID, models.StatusHeld, time.Now(), startDate, endDate).
+		Order("start_time ASC").
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// GetExpiredHolds - удержания, время оплаты которых истекло к now
+func (r *appointmentRepository) GetExpiredHolds(now time.Time) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	err := r.db.Where("status = ? AND held_until <= ?", models.StatusHeld, now).
+		Order("held_until ASC").
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// cancelColumns - поля записи, которые меняются при отмене
+var cancelColumns = []string{"patient_id", "status", "canceled_by", "canceled_by_role", "cancel_reason", "canceled_at", "meeting_id", "meeting_link", "updated_at"}
+
+// CancelBooked - сохраняет отмену, только если статус записи не изменился с момента чтения
+// (забронирована или пациент отмечен пришедшим). Возвращает ErrSlotTaken,
+// если запись успели отменить или изменить параллельно
+func (r *appointmentRepository) CancelBooked(appointment *models.Appointment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateStatus(tx, appointment, cancelColumns, "")
+	})
+}
+
+// GetDoctorBookedAppointments - забронированные (в т.ч. с отметкой о приходе) записи врача, начинающиеся в [startDate, endDate)
+func (r *appointmentRepository) GetDoctorBookedAppointments(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	err := r.db.Where("doctor_id = ? AND status IN ? AND start_time >= ? AND start_time < ?",
+		doctorID, []string{models.StatusBooked, models.StatusCheckedIn}, startDate, endDate).
+		Order("start_time ASC").
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// CloseAvailableSlots - закрывает свободные слоты врача в [startDate, endDate), чтобы на них нельзя было записаться
+func (r *appointmentRepository) CloseAvailableSlots(doctorID uuid.UUID, startDate, endDate time.Time, canceledBy uuid.UUID, reason string) (int64, error) {
+	var closed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		closed, err = closeAvailableSlots(tx, &canceledBy, "doctor", reason,
+			"doctor_id = ? AND start_time >= ? AND start_time < ?", doctorID, startDate, endDate)
+		return err
+	})
+	return closed, err
+}
+
+// closeAvailableSlots - переводит свободные слоты, подходящие под условие where, в canceled
+// и пишет переходы в журнал статусов
+func closeAvailableSlots(tx *gorm.DB, actorID *uuid.UUID, role, reason string, where string, args ...interface{}) (int64, error) {
+	now := time.Now()
+	var slots []*models.Appointment
+	result := tx.Model(&slots).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("status = ?", models.StatusAvailable).
+		Where(where, args...).
+		Updates(map[string]interface{}{
+			"status":           models.StatusCanceled,
+			"canceled_by":      actorID,
+			"canceled_by_role": role,
+			"cancel_reason":    reason,
+			"canceled_at":      now,
+			"updated_at":       now,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(slots) == 0 {
+		return result.RowsAffected, nil
+	}
+
+	history := make([]*models.AppointmentStatusHistory, len(slots))
+	for i, slot := range slots {
+		history[i] = &models.AppointmentStatusHistory{
+			AppointmentID: slot.ID,
+			FromStatus:    models.StatusAvailable,
+			ToStatus:      models.StatusCanceled,
+			ActorID:       actorID,
+			ActorRole:     role,
+			Reason:        reason,
+			CreatedAt:     now,
+		}
+	}
+	return result.RowsAffected, tx.Create(&history).Error
+}
+
+// occupyingStatuses - статусы записей, время которых занято и не может быть отдано другому пациенту
+var occupyingStatuses = []string{models.StatusHeld, models.StatusBooked, models.StatusCheckedIn, models.StatusInProgress, models.StatusCompleted}
+
+// GetDoctorOccupiedTime - записи врача, занимающие время в интервале [from, to)
+func (r *appointmentRepository) GetDoctorOccupiedTime(doctorID uuid.UUID, from, to time.Time) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	err := r.db.Where("doctor_id = ? AND status IN ? AND start_time < ? AND end_time > ?",
+		doctorID, occupyingStatuses, to, from).
+		Order("start_time ASC").
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// CreateVisit - сохраняет запись, вырезанную из свободного времени врача.
+// Записи одного врача создаются последовательно под advisory-блокировкой: свободные слоты,
+// пересекающие время записи, закрываются, и если время уже занято другой записью
+// или внешним календарем, транзакция откатывается с ErrSlotTaken
+func (r *appointmentRepository) CreateVisit(appointment *models.Appointment, limits *BookingLimits) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", appointment.DoctorID.String()).Error; err != nil {
+			return err
+		}
+		if err := checkBookingLimits(tx, appointment, limits); err != nil {
+			return err
+		}
+
+		// Сначала закрываем слоты: параллельное бронирование такого слота дождется
+		// этой транзакции и не найдет его свободным
+		if _, err := closeAvailableSlots(tx, nil, "system", "time booked by visit "+appointment.ID.String(),
+			"doctor_id = ? AND start_time < ? AND end_time > ?",
+			appointment.DoctorID, appointment.EndTime, appointment.StartTime); err != nil {
+			return err
+		}
+
+		var occupied int64
+		if err := tx.Model(&models.Appointment{}).
+			Where("doctor_id = ? AND status IN ? AND start_time < ? AND end_time > ?",
+				appointment.DoctorID, occupyingStatuses, appointment.EndTime, appointment.StartTime).
+			Count(&occupied).Error; err != nil {
+			return err
+		}
+		if occupied > 0 {
+			return ErrSlotTaken
+		}
+
+		var busy int64
+		if err := tx.Model(&models.ScheduleException{}).
+			Where("doctor_id = ? AND type = ? AND busy_start < ? AND busy_end > ?",
+				appointment.DoctorID, "busy", appointment.EndTime, appointment.StartTime).
+			Count(&busy).Error; err != nil {
+			return err
+		}
+		if busy > 0 {
+			return ErrSlotTaken
+		}
+
+		if err := tx.Create(appointment).Error; err != nil {
+			return err
+		}
+		return saveStatusHistory(tx, appointment)
+	})
+}
+
+// RescheduleAppointment - переносит бронирование пациента из from в to в одной транзакции.
+// Обе строки обновляются условно: если целевой слот уже занят или исходная запись изменилась,
+// транзакция откатывается с ErrSlotTaken и пациент сохраняет исходную запись
+func (r *appointmentRepository) RescheduleAppointment(from, to *models.Appointment, patientID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateStatus(tx, to, bookingColumns, "patient_id IS NULL"); err != nil {
+			return err
+		}
+		return updateStatus(tx, from, bookingColumns, "patient_id = ?", patientID)
+	})
 }
 
 // === EXCEPTIONS ===
@@ -135,28 +852,200 @@ func (r *appointmentRepository) CreateException(exception *models.ScheduleExcept
 	return r.db.Create(exception).Error
 }
 
+// GetDoctorExceptions - исключения, которые могут действовать в [startDate, endDate]:
+// разовые дни и периоды, пересекающие интервал, и повторяющиеся, начавшиеся до его конца
 func (r *appointmentRepository) GetDoctorExceptions(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.ScheduleException, error) {
 	var exceptions []*models.ScheduleException
-	err := r.db.Where("doctor_id = ? AND date >= ? AND date <= ?",
-		doctorID, startDate, endDate).
+	err := r.db.Where("doctor_id = ? AND date <= ? AND (COALESCE(end_date, date) >= ? OR (recurrence <> '' AND end_date IS NULL))",
+		doctorID, endDate, startDate).
 		Order("date ASC").
 		Find(&exceptions).Error
 	return exceptions, err
 }
 
+func (r *appointmentRepository) GetExceptionByID(id uuid.UUID) (*models.ScheduleException, error) {
+	var exception models.ScheduleException
+	err := r.db.Where("id = ?", id).First(&exception).Error
+	if err != nil {
+		return nil, err
+	}
+	return &exception, nil
+}
+
+func (r *appointmentRepository) UpdateException(exception *models.ScheduleException) error {
+	return r.db.Save(exception).Error
+}
+
 func (r *appointmentRepository) DeleteException(id uuid.UUID) error {
 	return r.db.Delete(&models.ScheduleException{}, "id = ?", id).Error
 }
 
+// CheckSlotExists - есть ли у врача неотмененный слот, пересекающийся с [startTime, endTime)
 func (r *appointmentRepository) CheckSlotExists(doctorID uuid.UUID, startTime, endTime time.Time) (bool, error) {
 	var count int64
 	err := r.db.Model(&models.Appointment{}).
-		Where("doctor_id = ? AND ((start_time <= ? AND end_time > ?) OR (start_time < ? AND end_time >= ?) OR (start_time >= ? AND end_time <= ?))",
-			doctorID, startTime, startTime, endTime, endTime, startTime, endTime).
+		Where("doctor_id = ? AND status <> ? AND ((start_time <= ? AND end_time > ?) OR (start_time < ? AND end_time >= ?) OR (start_time >= ? AND end_time <= ?))",
+			doctorID, models.StatusCanceled, startTime, startTime, endTime, endTime, startTime, endTime).
 		Count(&count).Error
 	return count > 0, err
 }
 
+// GetDoctorSlotsFrom - свободные, удерживаемые до оплаты и забронированные (в т.ч. с отметкой о приходе)
+// слоты врача, начинающиеся не раньше from (и до until, если задан)
+func (r *appointmentRepository) GetDoctorSlotsFrom(doctorID uuid.UUID, from time.Time, until *time.Time) ([]*models.Appointment, error) {
+	var slots []*models.Appointment
+	query := r.db.Where("doctor_id = ? AND status IN ? AND start_time >= ?",
+		doctorID, []string{models.StatusAvailable, models.StatusHeld, models.StatusBooked, models.StatusCheckedIn}, from)
+	if until != nil {
+		query = query.Where("start_time < ?", *until)
+	}
+	err := query.Order("start_time ASC").Find(&slots).Error
+	return slots, err
+}
+
+// DeleteAvailableSlots - удаляет слоты по ID, только если они все еще свободны
+func (r *appointmentRepository) DeleteAvailableSlots(ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Where("id IN ? AND status = ?", ids, "available").
+		Delete(&models.Appointment{})
+	return result.RowsAffected, result.Error
+}
+
+// GetDoctorBusyTime - занятое время врача, пересекающее интервал [from, to)
+func (r *appointmentRepository) GetDoctorBusyTime(doctorID uuid.UUID, from, to time.Time) ([]*models.ScheduleException, error) {
+	var exceptions []*models.ScheduleException
+	err := r.db.Where("doctor_id = ? AND type = ? AND busy_start < ? AND busy_end > ?",
+		doctorID, "busy", to, from).
+		Order("busy_start ASC").
+		Find(&exceptions).Error
+	return exceptions, err
+}
+
+// SyncImportedExceptions - сохраняет исключения источника source, сопоставляя их по внешнему UID:
+// новые создаются, изменившиеся обновляются, неизменные не трогаются. При removeMissing
+// исключения источника, которых нет в exceptions, удаляются. Все в одной транзакции
+func (r *appointmentRepository) SyncImportedExceptions(doctorID uuid.UUID, source string, exceptions []*models.ScheduleException, removeMissing bool) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []*models.ScheduleException
+		if err := tx.Where("doctor_id = ? AND source = ?", doctorID, source).
+			Find(&existing).Error; err != nil {
+			return err
+		}
+
+		byUID := make(map[string]*models.ScheduleException, len(existing))
+		for _, exception := range existing {
+			byUID[exception.ExternalUID] = exception
+		}
+
+		for _, exception := range exceptions {
+			current, ok := byUID[exception.ExternalUID]
+			if !ok {
+				exception.DoctorID = doctorID
+				exception.Source = source
+				if err := tx.Create(exception).Error; err != nil {
+					return err
+				}
+				result.Created++
+				result.Changed = append(result.Changed, exception)
+				continue
+			}
+			delete(byUID, exception.ExternalUID)
+
+			if sameBusyTime(current, exception) {
+				continue
+			}
+			current.Date = exception.Date
+			current.EndDate = exception.EndDate
+			current.BusyStart = exception.BusyStart
+			current.BusyEnd = exception.BusyEnd
+			current.Reason = exception.Reason
+			if err := tx.Save(current).Error; err != nil {
+				return err
+			}
+			result.Updated++
+			result.Changed = append(result.Changed, current)
+		}
+
+		if !removeMissing || len(byUID) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(byUID))
+		for _, exception := range byUID {
+			ids = append(ids, exception.ID)
+		}
+		deleted := tx.Where("id IN ?", ids).Delete(&models.ScheduleException{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		result.Removed = int(deleted.RowsAffected)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// sameBusyTime - совпадает ли сохраненное занятое время с импортируемым
+func sameBusyTime(current, imported *models.ScheduleException) bool {
+	if current.BusyStart == nil || current.BusyEnd == nil || imported.BusyStart == nil || imported.BusyEnd == nil {
+		return false
+	}
+	return current.BusyStart.Equal(*imported.BusyStart) &&
+		current.BusyEnd.Equal(*imported.BusyEnd) &&
+		current.Reason == imported.Reason
+}
+
+// === EXTERNAL CALENDARS ===
+
+func (r *appointmentRepository) CreateExternalCalendar(calendar *models.ExternalCalendar) error {
+	return r.db.Create(calendar).Error
+}
+
+func (r *appointmentRepository) GetExternalCalendarByID(id uuid.UUID) (*models.ExternalCalendar, error) {
+	var calendar models.ExternalCalendar
+	err := r.db.Where("id = ?", id).First(&calendar).Error
+	if err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+func (r *appointmentRepository) GetDoctorExternalCalendars(doctorID uuid.UUID) ([]*models.ExternalCalendar, error) {
+	var calendars []*models.ExternalCalendar
+	err := r.db.Where("doctor_id = ?", doctorID).
+		Order("created_at ASC").
+		Find(&calendars).Error
+	return calendars, err
+}
+
+func (r *appointmentRepository) GetAllExternalCalendars() ([]*models.ExternalCalendar, error) {
+	var calendars []*models.ExternalCalendar
+	err := r.db.Order("last_synced_at ASC NULLS FIRST").Find(&calendars).Error
+	return calendars, err
+}
+
+func (r *appointmentRepository) UpdateExternalCalendar(calendar *models.ExternalCalendar) error {
+	return r.db.Save(calendar).Error
+}
+
+// DeleteExternalCalendar - удаляет календарь вместе с импортированным из него занятым временем
+func (r *appointmentRepository) DeleteExternalCalendar(calendar *models.ExternalCalendar) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("doctor_id = ? AND source = ?", calendar.DoctorID, calendar.Source()).
+			Delete(&models.ScheduleException{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ExternalCalendar{}, "id = ?", calendar.ID).Error
+	})
+}
+
 // === NEW METHODS ===
 
 func (r *appointmentRepository) DeleteScheduleSlots(scheduleID uuid.UUID) error {
@@ -180,3 +1069,432 @@ func (r *appointmentRepository) UpdateScheduleAppointmentType(scheduleID uuid.UU
 		Where("schedule_id = ? AND status = ?", scheduleID, "available").
 		Update("appointment_type", appointmentType).Error
 }
+
+// GetAutoGenerateSchedules - активные расписания с включенной автогенерацией слотов
+func (r *appointmentRepository) GetAutoGenerateSchedules() ([]*models.DoctorSchedule, error) {
+	var schedules []*models.DoctorSchedule
+	err := r.db.Where("is_active = ? AND generate_days_ahead > 0", true).
+		Order("created_at ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// AdvanceScheduleGeneratedUntil - сдвигает отметку автогенерации на сгенерированный день,
+// только если ее не сдвинул другой процесс. Возвращает false, если отметка уже изменилась
+func (r *appointmentRepository) AdvanceScheduleGeneratedUntil(scheduleID uuid.UUID, previous *time.Time, next time.Time) (bool, error) {
+	query := r.db.Model(&models.DoctorSchedule{}).Where("id = ?", scheduleID)
+	if previous == nil {
+		query = query.Where("generated_until IS NULL")
+	} else {
+		query = query.Where("generated_until = ?", previous.Format("2006-01-02"))
+	}
+
+	result := query.Update("generated_until", next.Format("2006-01-02"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// === CALENDAR FEEDS ===
+
+func (r *appointmentRepository) GetCalendarFeedToken(userID uuid.UUID) (*models.CalendarFeedToken, error) {
+	var feedToken models.CalendarFeedToken
+	err := r.db.Where("user_id = ?", userID).First(&feedToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+func (r *appointmentRepository) GetCalendarFeedTokenByToken(token string) (*models.CalendarFeedToken, error) {
+	var feedToken models.CalendarFeedToken
+	err := r.db.Where("token = ?", token).First(&feedToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+func (r *appointmentRepository) SaveCalendarFeedToken(feedToken *models.CalendarFeedToken) error {
+	return r.db.Save(feedToken).Error
+}
+
+// SaveMeeting - сохраняет комнату онлайн-встречи, созданную уже после записи
+func (r *appointmentRepository) SaveMeeting(appointment *models.Appointment) error {
+	return r.db.Model(appointment).
+		Select("meeting_id", "meeting_link").
+		Updates(appointment).Error
+}
+
+// === GROUP SESSIONS ===
+
+// seatColumns - поля группового занятия, которые меняются при записи и отмене участника
+var seatColumns = []string{"status", "seats_taken", "meeting_id", "meeting_link", "updated_at"}
+
+// JoinGroupSession - занимает место на групповом занятии. Строка занятия блокируется до конца
+// транзакции, поэтому параллельные записи занимают места по очереди и не превышают capacity.
+// Возвращает ErrSlotTaken, если мест нет, и ErrAlreadyParticipant, если пациент уже записан
+func (r *appointmentRepository) JoinGroupSession(appointmentID uuid.UUID, participant *models.AppointmentParticipant, limits *BookingLimits) (*models.Appointment, error) {
+	var appointment models.Appointment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Правила врача проверяются до блокировки строки занятия: advisory-блокировка
+		// всегда берется первой, как в BookSlot и CreateVisit
+		if limits != nil {
+			if err := tx.Where("id = ?", appointmentID).First(&appointment).Error; err != nil {
+				return err
+			}
+			if err := checkBookingLimits(tx, &appointment, limits); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", appointmentID).First(&appointment).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.AppointmentParticipant{}).
+			Where("appointment_id = ? AND patient_id = ? AND status = ?", appointmentID, participant.PatientID, models.ParticipantBooked).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyParticipant
+		}
+
+		if err := appointment.JoinGroup(participant.PatientID); err != nil {
+			return ErrSlotTaken
+		}
+		if err := updateStatus(tx, &appointment, seatColumns, ""); err != nil {
+			return err
+		}
+
+		participant.AppointmentID = appointmentID
+		participant.Status = models.ParticipantBooked
+		return tx.Create(participant).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// LeaveGroupSession - освобождает место пациента на групповом занятии.
+// Возвращает gorm.ErrRecordNotFound, если пациент на занятие не записан
+func (r *appointmentRepository) LeaveGroupSession(appointmentID, patientID uuid.UUID) (*models.Appointment, error) {
+	var appointment models.Appointment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", appointmentID).First(&appointment).Error; err != nil {
+			return err
+		}
+
+		var participant models.AppointmentParticipant
+		if err := tx.Where("appointment_id = ? AND patient_id = ? AND status = ?", appointmentID, patientID, models.ParticipantBooked).
+			First(&participant).Error; err != nil {
+			return err
+		}
+
+		if err := appointment.LeaveGroup(patientID); err != nil {
+			return err
+		}
+		if err := updateStatus(tx, &appointment, seatColumns, ""); err != nil {
+			return err
+		}
+
+		participant.Cancel()
+		return tx.Model(&participant).Select("status", "canceled_at", "updated_at").Updates(&participant).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// GetParticipants - пациенты, записанные на групповое занятие, в порядке записи
+func (r *appointmentRepository) GetParticipants(appointmentID uuid.UUID) ([]*models.AppointmentParticipant, error) {
+	var participants []*models.AppointmentParticipant
+	err := r.db.Where("appointment_id = ? AND status = ?", appointmentID, models.ParticipantBooked).
+		Order("created_at ASC").
+		Find(&participants).Error
+	return participants, err
+}
+
+// GetParticipant - место пациента на групповом занятии
+func (r *appointmentRepository) GetParticipant(appointmentID, patientID uuid.UUID) (*models.AppointmentParticipant, error) {
+	var participant models.AppointmentParticipant
+	err := r.db.Where("appointment_id = ? AND patient_id = ? AND status = ?", appointmentID, patientID, models.ParticipantBooked).
+		First(&participant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+// === FOLLOW-UPS ===
+
+// visitChainMaxDepth - предел длины цепочки визитов при обходе ссылок
+const visitChainMaxDepth = 100
+
+// followUpColumns - поля записи, которые меняются, когда врач записывает пациента на повторный прием
+var followUpColumns = append(append([]string{}, bookingColumns...), "doctor_notes")
+
+// BookFollowUpSlot - сохраняет запись на повторный прием, только если слот все еще свободен (как BookSlot)
+func (r *appointmentRepository) BookFollowUpSlot(appointment *models.Appointment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateStatus(tx, appointment, followUpColumns, "patient_id IS NULL")
+	})
+}
+
+// GetAppointmentsByIDs - записи с перечисленными ID в порядке начала
+func (r *appointmentRepository) GetAppointmentsByIDs(ids []uuid.UUID) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	if len(ids) == 0 {
+		return appointments, nil
+	}
+	err := r.db.Where("id IN ?", ids).
+		Order("start_time ASC").
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// GetVisitChain - цепочка визитов, в которую входит запись: от первого приема по ссылкам
+// previous_appointment_id вверх и все повторные приемы от него вниз, в порядке начала
+func (r *appointmentRepository) GetVisitChain(appointmentID uuid.UUID) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	err := r.db.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, previous_appointment_id, 0 AS depth
+			FROM appointments WHERE id = ?
+			UNION ALL
+			SELECT a.id, a.previous_appointment_id, ancestors.depth + 1
+			FROM appointments a JOIN ancestors ON a.id = ancestors.previous_appointment_id
+			WHERE ancestors.depth < ?
+		), chain AS (
+			SELECT id, 0 AS depth
+			FROM (SELECT id FROM ancestors ORDER BY depth DESC LIMIT 1) root
+			UNION ALL
+			SELECT a.id, chain.depth + 1
+			FROM appointments a JOIN chain ON a.previous_appointment_id = chain.id
+			WHERE chain.depth < ?
+		)
+		SELECT * FROM appointments
+		WHERE id IN (SELECT id FROM chain)
+		ORDER BY start_time ASC`,
+		appointmentID, visitChainMaxDepth, visitChainMaxDepth).
+		Scan(&appointments).Error
+	return appointments, err
+}
+
+func (r *appointmentRepository) CreateFollowUpProposal(proposal *models.FollowUpProposal) error {
+	return r.db.Create(proposal).Error
+}
+
+func (r *appointmentRepository) GetFollowUpProposalByID(id uuid.UUID) (*models.FollowUpProposal, error) {
+	var proposal models.FollowUpProposal
+	err := r.db.Where("id = ?", id).First(&proposal).Error
+	if err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// GetPatientFollowUpProposals - предложения повторного приема пациенту, новые первыми; пустой status - все
+func (r *appointmentRepository) GetPatientFollowUpProposals(patientID uuid.UUID, status string) ([]*models.FollowUpProposal, error) {
+	var proposals []*models.FollowUpProposal
+	query := r.db.Where("patient_id = ?", patientID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&proposals).Error
+	return proposals, err
+}
+
+// ClaimFollowUpProposal - отмечает предложение принятым, только если оно еще ждет выбора.
+// Из параллельных запросов пациента выигрывает один, остальные получают ErrSlotTaken
+func (r *appointmentRepository) ClaimFollowUpProposal(proposal *models.FollowUpProposal, appointmentID uuid.UUID) error {
+	result := r.db.Model(proposal).
+		Where("status = ?", models.ProposalPending).
+		Updates(map[string]interface{}{
+			"status":         models.ProposalAccepted,
+			"appointment_id": appointmentID,
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSlotTaken
+	}
+	proposal.Status = models.ProposalAccepted
+	proposal.AppointmentID = &appointmentID
+	return nil
+}
+
+// ReopenFollowUpProposal - возвращает предложение к выбору, если запись на выбранный слот не удалась
+func (r *appointmentRepository) ReopenFollowUpProposal(proposal *models.FollowUpProposal) error {
+	err := r.db.Model(proposal).
+		Updates(map[string]interface{}{
+			"status":         models.ProposalPending,
+			"appointment_id": nil,
+			"updated_at":     time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	proposal.Status = models.ProposalPending
+	proposal.AppointmentID = nil
+	return nil
+}
+
+// ReopenAcceptedFollowUp - возвращает к выбору предложение, принятое пациентом patientID записью appointmentID
+// по итогам приема previousAppointmentID, если эта запись сорвалась. false - такого принятого предложения нет
+func (r *appointmentRepository) ReopenAcceptedFollowUp(appointmentID, patientID, previousAppointmentID uuid.UUID) (bool, error) {
+	result := r.db.Model(&models.FollowUpProposal{}).
+		Where("status = ? AND appointment_id = ? AND patient_id = ? AND previous_appointment_id = ?",
+			models.ProposalAccepted, appointmentID, patientID, previousAppointmentID).
+		Updates(map[string]interface{}{
+			"status":         models.ProposalPending,
+			"appointment_id": nil,
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// === ATTACHMENTS ===
+
+// CreateAttachment - прикрепляет файл к записи; ErrAlreadyAttached, если он уже прикреплен
+func (r *appointmentRepository) CreateAttachment(attachment *models.AppointmentAttachment) error {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(attachment)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyAttached
+	}
+	return nil
+}
+
+// GetAttachments - файлы записи в порядке прикрепления
+func (r *appointmentRepository) GetAttachments(appointmentID uuid.UUID) ([]*models.AppointmentAttachment, error) {
+	var attachments []*models.AppointmentAttachment
+	err := r.db.Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC").
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *appointmentRepository) GetAttachment(appointmentID, fileID uuid.UUID) (*models.AppointmentAttachment, error) {
+	var attachment models.AppointmentAttachment
+	err := r.db.Where("appointment_id = ? AND file_id = ?", appointmentID, fileID).First(&attachment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *appointmentRepository) DeleteAttachment(id uuid.UUID) error {
+	return r.db.Delete(&models.AppointmentAttachment{}, "id = ?", id).Error
+}
+
+// === ANALYTICS ===
+
+// Части даты для GetDoctorLoad
+const (
+	LoadByWeekday = "isodow"
+	LoadByHour    = "hour"
+)
+
+// bookedStatuses - записи, время которых занял пациент (в т.ч. не пришедший)
+var bookedStatuses = []string{models.StatusBooked, models.StatusCheckedIn, models.StatusInProgress, models.StatusCompleted, models.StatusNoShow}
+
+// GetDoctorAnalytics - агрегаты по записям врача в [From, To), сгруппированные по дням, неделям или месяцам.
+// Свободные слоты, закрытые врачом (canceled без пациента), не считаются предложенными
+func (r *appointmentRepository) GetDoctorAnalytics(filter AnalyticsFilter) ([]*AnalyticsCounts, error) {
+	var counts []*AnalyticsCounts
+	err := r.db.Raw(`
+		SELECT date_trunc(?, start_time AT TIME ZONE ?) AS bucket,
+			COUNT(*) FILTER (WHERE NOT (status = ? AND patient_id IS NULL)) AS offered,
+			COUNT(*) FILTER (WHERE status IN ?) AS booked,
+			COUNT(*) FILTER (WHERE status = ?) AS completed,
+			COUNT(*) FILTER (WHERE status = ? AND patient_id IS NOT NULL) AS canceled,
+			COUNT(*) FILTER (WHERE status = ?) AS no_show,
+			COUNT(*) FILTER (WHERE status IN ? AND appointment_type = 'online') AS online,
+			COUNT(*) FILTER (WHERE status IN ? AND appointment_type = 'offline') AS offline,
+			COALESCE(SUM(CASE WHEN payment_status = 'paid' THEN payment_amount ELSE ?::numeric END)
+				FILTER (WHERE status = ?), 0) AS revenue
+		FROM appointments
+		WHERE doctor_id = ? AND start_time >= ? AND start_time < ?
+		GROUP BY 1
+		ORDER BY 1`,
+		filter.Bucket, filter.Timezone,
+		models.StatusCanceled,
+		bookedStatuses,
+		models.StatusCompleted,
+		models.StatusCanceled,
+		models.StatusNoShow,
+		bookedStatuses,
+		bookedStatuses,
+		filter.Price, models.StatusCompleted,
+		filter.DoctorID, filter.From, filter.To).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// GetDoctorLoad - занятые приемы врача в [From, To) по дню недели (LoadByWeekday) или часу (LoadByHour),
+// от самых загруженных
+func (r *appointmentRepository) GetDoctorLoad(filter AnalyticsFilter, part string) ([]*AnalyticsLoad, error) {
+	if part != LoadByWeekday && part != LoadByHour {
+		return nil, errors.New("unknown load part: " + part)
+	}
+
+	var load []*AnalyticsLoad
+	err := r.db.Raw(`
+		SELECT EXTRACT(`+part+` FROM start_time AT TIME ZONE ?)::int AS key, COUNT(*) AS count
+		FROM appointments
+		WHERE doctor_id = ? AND status IN ? AND start_time >= ? AND start_time < ?
+		GROUP BY 1
+		ORDER BY 2 DESC, 1`,
+		filter.Timezone, filter.DoctorID, bookedStatuses, filter.From, filter.To).
+		Scan(&load).Error
+	return load, err
+}
+
+// === REMINDERS ===
+
+// GetAppointmentsForReminder - забронированные записи, начинающиеся в (from, to],
+// пациенту которых (или хотя бы одному участнику группового занятия, или врачу занятия
+// с участниками) напоминание с указанным смещением еще не отправлялось
+func (r *appointmentRepository) GetAppointmentsForReminder(from, to time.Time, offsetMinutes int) ([]*models.Appointment, error) {
+	var appointments []*models.Appointment
+	err := r.db.Where("status = ? AND start_time > ? AND start_time <= ?", models.StatusBooked, from, to).
+		Where(`(patient_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM appointment_reminders ar WHERE ar.appointment_id = appointments.id AND ar.patient_id = appointments.patient_id AND ar.offset_minutes = ?))
+			OR EXISTS (SELECT 1 FROM appointment_participants ap WHERE ap.appointment_id = appointments.id AND ap.status = ?
+				AND NOT EXISTS (SELECT 1 FROM appointment_reminders ar WHERE ar.appointment_id = ap.appointment_id AND ar.patient_id = ap.patient_id AND ar.offset_minutes = ?))
+			OR (EXISTS (SELECT 1 FROM appointment_participants ap WHERE ap.appointment_id = appointments.id AND ap.status = ?)
+				AND NOT EXISTS (SELECT 1 FROM appointment_reminders ar WHERE ar.appointment_id = appointments.id AND ar.patient_id = appointments.doctor_id AND ar.offset_minutes = ?))`,
+			offsetMinutes, models.ParticipantBooked, offsetMinutes, models.ParticipantBooked, offsetMinutes).
+		Order("start_time ASC").
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// ClaimReminder - атомарно резервирует отправку напоминания.
+// Возвращает false, если напоминание уже зарезервировано другим процессом
+func (r *appointmentRepository) ClaimReminder(reminder *models.AppointmentReminder) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseReminder - снимает резервирование, если напоминание не удалось отправить
+func (r *appointmentRepository) ReleaseReminder(id uuid.UUID) error {
+	return r.db.Delete(&models.AppointmentReminder{}, "id = ?", id).Error
+}
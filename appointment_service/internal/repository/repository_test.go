@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/database"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB - тестовая база PostgreSQL из APPOINTMENT_TEST_DATABASE_URL со всеми миграциями.
+// Без переменной тест пропускается: проверка условного UPDATE имеет смысл только на настоящей базе
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("APPOINTMENT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("APPOINTMENT_TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+func TestBookSlotConcurrentOnlyOneWins(t *testing.T) {
+	db := testDB(t)
+	repo := NewAppointmentRepository(db)
+
+	slot := &models.Appointment{
+		DoctorID:        uuid.New(),
+		StartTime:       time.Now().Add(48 * time.Hour).Truncate(time.Second),
+		EndTime:         time.Now().Add(48*time.Hour + 30*time.Minute).Truncate(time.Second),
+		Title:           "Консультация терапевта",
+		Status:          models.StatusAvailable,
+		AppointmentType: "offline",
+		Capacity:        1,
+	}
+	if err := repo.CreateAppointment(slot); err != nil {
+		t.Fatalf("failed to create slot: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("appointment_id = ?", slot.ID).Delete(&models.AppointmentStatusHistory{})
+		db.Delete(&models.Appointment{}, "id = ?", slot.ID)
+	})
+
+	const patients = 20
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([]error, patients)
+	)
+	for i := 0; i < patients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			appointment, err := repo.GetAppointmentByID(slot.ID)
+			if err != nil {
+				results[i] = err
+				return
+			}
+			if err := appointment.Book(uuid.New(), "offline", ""); err != nil {
+				results[i] = err
+				return
+			}
+			<-start
+			results[i] = repo.BookSlot(appointment, nil)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrSlotTaken):
+			t.Fatalf("booking %d: expected ErrSlotTaken, got %v", i, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one successful booking, got %d", succeeded)
+	}
+
+	var history int64
+	db.Model(&models.AppointmentStatusHistory{}).Where("appointment_id = ?", slot.ID).Count(&history)
+	if history != 1 {
+		t.Fatalf("expected one status history entry, got %d", history)
+	}
+}
+
+func TestBookSlotActiveBookingLimitUnderConcurrency(t *testing.T) {
+	db := testDB(t)
+	repo := NewAppointmentRepository(db)
+
+	doctorID, patientID := uuid.New(), uuid.New()
+	const slots, maxActive = 6, 2
+
+	var ids []uuid.UUID
+	for i := 0; i < slots; i++ {
+		start := time.Now().Add(48*time.Hour + time.Duration(i)*time.Hour).Truncate(time.Second)
+		slot := &models.Appointment{
+			DoctorID:        doctorID,
+			StartTime:       start,
+			EndTime:         start.Add(30 * time.Minute),
+			Title:           "Консультация терапевта",
+			Status:          models.StatusAvailable,
+			AppointmentType: "offline",
+			Capacity:        1,
+		}
+		if err := repo.CreateAppointment(slot); err != nil {
+			t.Fatalf("failed to create slot: %v", err)
+		}
+		ids = append(ids, slot.ID)
+	}
+	t.Cleanup(func() {
+		db.Where("appointment_id IN ?", ids).Delete(&models.AppointmentStatusHistory{})
+		db.Delete(&models.Appointment{}, "id IN ?", ids)
+	})
+
+	limits := &BookingLimits{PatientID: patientID, MaxActiveBookings: maxActive}
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([]error, slots)
+	)
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id uuid.UUID) {
+			defer wg.Done()
+			appointment, err := repo.GetAppointmentByID(id)
+			if err != nil {
+				results[i] = err
+				return
+			}
+			if err := appointment.Book(patientID, "offline", ""); err != nil {
+				results[i] = err
+				return
+			}
+			<-start
+			results[i] = repo.BookSlot(appointment, limits)
+		}(i, id)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrBookingLimitReached):
+			t.Fatalf("booking %d: expected ErrBookingLimitReached, got %v", i, err)
+		}
+	}
+	if succeeded != maxActive {
+		t.Fatalf("expected %d successful bookings, got %d", maxActive, succeeded)
+	}
+}
@@ -23,6 +23,12 @@ func SetupRoutes(e *echo.Echo, handler *handlers.AppointmentHandler, jwtSecret s
 	// Health check (без авторизации)
 	e.GET("/health", handler.HealthCheck)
 
+	// ICS-лента по секретному токену (без JWT - ее опрашивают календарные приложения)
+	e.GET("/appointments/calendar/feeds/:token", handler.GetCalendarFeedICS)
+
+	// Уведомления платежного провайдера (без JWT - подлинность проверяется подписью)
+	e.POST("/appointments/payments/webhook", handler.PaymentWebhook)
+
 	// Основная группа с JWT middleware
 	protected := e.Group("")
 	protected.Use(utilsMiddleware.JWTMiddleware(jwtSecret))
@@ -48,6 +54,40 @@ func SetupRoutes(e *echo.Echo, handler *handlers.AppointmentHandler, jwtSecret s
 	{
 		doctorExceptions.POST("", handler.AddException)
 		doctorExceptions.GET("", handler.GetDoctorExceptions)
+		doctorExceptions.PUT("/:id", handler.UpdateException)
+		doctorExceptions.DELETE("/:id", handler.DeleteException)
+
+		// Занятое время из внешних календарей (.ics)
+		doctorExceptions.POST("/import", handler.ImportCalendar)                   // Импорт .ics файла
+		doctorExceptions.POST("/calendars", handler.AddExternalCalendar)           // Подключить календарь по ссылке
+		doctorExceptions.GET("/calendars", handler.GetExternalCalendars)           // Подключенные календари
+		doctorExceptions.DELETE("/calendars/:id", handler.DeleteExternalCalendar)  // Отключить календарь
+		doctorExceptions.POST("/calendars/:id/sync", handler.SyncExternalCalendar) // Синхронизировать сейчас
+	}
+
+	// Doctor visit types (только для врачей) - каталог видов приема
+	doctorVisitTypes := protected.Group("/appointments/visit-types")
+	doctorVisitTypes.Use(utilsMiddleware.RequireDoctor())
+	{
+		doctorVisitTypes.POST("", handler.CreateVisitType)
+		doctorVisitTypes.GET("", handler.GetDoctorVisitTypes)
+		doctorVisitTypes.PUT("/:id", handler.UpdateVisitType)
+		doctorVisitTypes.DELETE("/:id", handler.DeleteVisitType)
+	}
+
+	// Doctor booking policy (только для врачей) - правила записи к врачу
+	doctorPolicy := protected.Group("/appointments/policy")
+	doctorPolicy.Use(utilsMiddleware.RequireDoctor())
+	{
+		doctorPolicy.GET("", handler.GetBookingPolicy)
+		doctorPolicy.PUT("", handler.UpdateBookingPolicy)
+	}
+
+	// Doctor analytics (только для врачей) - показатели приема за период
+	doctorAnalytics := protected.Group("/appointments/analytics")
+	doctorAnalytics.Use(utilsMiddleware.RequireDoctor())
+	{
+		doctorAnalytics.GET("", handler.GetDoctorAnalytics)
 	}
 
 	// All appointments (для всех авторизованных пользователей)
@@ -69,6 +109,8 @@ func SetupRoutes(e *echo.Echo, handler *handlers.AppointmentHandler, jwtSecret s
 			}
 		})
 
+		appointments.GET("/search", handler.SearchAvailableSlots) // Поиск ближайших свободных слотов по нескольким врачам
+
 		appointments.GET("/:id", func(c echo.Context) error {
 			// Проверяем роль пользователя
 			role, ok := c.Get("role").(string)
@@ -83,8 +125,49 @@ func SetupRoutes(e *echo.Echo, handler *handlers.AppointmentHandler, jwtSecret s
 			}
 		})
 
-		appointments.POST("/:id/book", handler.BookAppointment)                     // Бронирование записи
-		appointments.POST("/:id/cancel", handler.CancelAppointment)                 // Отмена записи
-		appointments.GET("/doctors/:id/available-slots", handler.GetAvailableSlots) // Доступные слоты
+		appointments.POST("/:id/book", handler.BookAppointment) // Бронирование записи
+		appointments.POST("/:id/cancel", func(c echo.Context) error {
+			// Врач отменяет запись с причиной, пациент - свою запись
+			role, ok := c.Get("role").(string)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Role not found")
+			}
+
+			if role == "doctor" {
+				return handler.DoctorCancelAppointment(c)
+			} else {
+				return handler.CancelAppointment(c)
+			}
+		})
+
+		appointments.POST("/cancel-range", handler.DoctorCancelAppointments, utilsMiddleware.RequireDoctor()) // Отмена записей врача за период
+		appointments.POST("/:id/reschedule", handler.RescheduleAppointment, utilsMiddleware.RequirePatient()) // Перенос записи
+		appointments.POST("/:id/complete", handler.CompleteAppointment, utilsMiddleware.RequireDoctor())      // Завершение приема врачом
+		appointments.POST("/:id/check-in", handler.CheckInAppointment, utilsMiddleware.RequireDoctor())       // Пациент пришел
+		appointments.POST("/:id/start", handler.StartAppointment, utilsMiddleware.RequireDoctor())            // Начало приема
+		appointments.POST("/:id/no-show", handler.MarkNoShow, utilsMiddleware.RequireDoctor())                // Неявка пациента
+		appointments.GET("/:id/history", handler.GetAppointmentStatusHistory)                                 // Журнал статусов
+		appointments.GET("/:id/meeting", handler.GetMeetingJoin)                                              // Ссылка на вход во встречу онлайн-приема
+		appointments.GET("/:id/participants", handler.GetParticipants, utilsMiddleware.RequireDoctor())       // Участники группового занятия
+		appointments.GET("/doctors/:id/available-slots", handler.GetAvailableSlots)                           // Доступные слоты
+		appointments.GET("/doctors/:id/policy", handler.GetDoctorBookingPolicy)                               // Правила записи врача
+		appointments.GET("/doctors/:id/visit-types", handler.GetVisitTypes)                                   // Виды приема врача
+		appointments.GET("/doctors/:id/visit-slots", handler.GetVisitSlots)                                   // Свободное время под вид приема
+		appointments.POST("/doctors/:id/visits", handler.BookVisit, utilsMiddleware.RequirePatient())         // Запись по виду приема
+		appointments.GET("/:id/ics", handler.GetAppointmentICS)                                               // Запись в формате .ics
+		appointments.GET("/calendar/feed", handler.GetCalendarFeed)                                           // Ссылка на ICS-ленту
+		appointments.POST("/calendar/feed/rotate", handler.RotateCalendarFeed)                                // Новая ссылка на ICS-ленту
+
+		// Повторные приемы и цепочки визитов
+		appointments.POST("/:id/follow-up", handler.BookFollowUp, utilsMiddleware.RequireDoctor())
+		appointments.POST("/:id/follow-up/proposals", handler.ProposeFollowUp, utilsMiddleware.RequireDoctor())
+		appointments.GET("/:id/chain", handler.GetVisitChain)
+		appointments.GET("/follow-ups", handler.GetFollowUpProposals, utilsMiddleware.RequirePatient())
+		appointments.POST("/follow-ups/:id/accept", handler.AcceptFollowUpProposal, utilsMiddleware.RequirePatient())
+
+		// Файлы, прикрепленные к записи участниками приема
+		appointments.POST("/:id/attachments", handler.AttachFile)
+		appointments.GET("/:id/attachments", handler.GetAttachments)
+		appointments.DELETE("/:id/attachments/:fileId", handler.DetachFile)
 	}
 }
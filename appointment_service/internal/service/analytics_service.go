@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// analyticsMaxDays - самый длинный период аналитики за один запрос
+const analyticsMaxDays = 366
+
+// GetDoctorAnalytics - загрузка расписания врача, отмены, неявки, форматы, пиковые дни и часы
+// и оценка выручки за период. Все считается агрегатами в базе; выручка по неоплаченным
+// приемам оценивается по цене врача из specialist_service
+func (s *appointmentService) GetDoctorAnalytics(doctorID uuid.UUID, req *models.DoctorAnalyticsRequest) (*models.DoctorAnalyticsResponse, error) {
+	location := time.UTC
+	if req.Timezone != "" {
+		loc, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, req.Timezone)
+		}
+		location = loc
+	}
+
+	dateFrom, err := time.ParseInLocation("2006-01-02", req.DateFrom, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date_from: %v", ErrInvalidInput, err)
+	}
+	dateTo, err := time.ParseInLocation("2006-01-02", req.DateTo, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date_to: %v", ErrInvalidInput, err)
+	}
+	if dateTo.Before(dateFrom) {
+		return nil, fmt.Errorf("%w: date_to must not be before date_from", ErrInvalidInput)
+	}
+	if dateTo.Sub(dateFrom) >= analyticsMaxDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: analytics period must not exceed %d days", ErrInvalidInput, analyticsMaxDays)
+	}
+
+	bucket := req.Bucket
+	if bucket == "" {
+		bucket = "day"
+	}
+
+	filter := repository.AnalyticsFilter{
+		DoctorID: doctorID,
+		From:     dateFrom,
+		To:       dateTo.AddDate(0, 0, 1),
+		Timezone: location.String(),
+		Bucket:   bucket,
+		Price:    s.analyticsPrice(doctorID),
+	}
+
+	counts, err := s.repo.GetDoctorAnalytics(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor analytics: %w", err)
+	}
+	weekdays, err := s.repo.GetDoctorLoad(filter, repository.LoadByWeekday)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weekday load: %w", err)
+	}
+	hours, err := s.repo.GetDoctorLoad(filter, repository.LoadByHour)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly load: %w", err)
+	}
+
+	response := &models.DoctorAnalyticsResponse{
+		DateFrom:    req.DateFrom,
+		DateTo:      req.DateTo,
+		Bucket:      bucket,
+		Timezone:    location.String(),
+		DoctorPrice: filter.Price,
+		Buckets:     make([]models.AnalyticsBucket, 0, len(counts)),
+		Weekdays:    make([]models.WeekdayLoad, 0, len(weekdays)),
+		Hours:       make([]models.HourLoad, 0, len(hours)),
+	}
+
+	var totals repository.AnalyticsCounts
+	for _, c := range counts {
+		totals.Offered += c.Offered
+		totals.Booked += c.Booked
+		totals.Completed += c.Completed
+		totals.Canceled += c.Canceled
+		totals.NoShow += c.NoShow
+		totals.Online += c.Online
+		totals.Offline += c.Offline
+		totals.Revenue += c.Revenue
+
+		response.Buckets = append(response.Buckets, models.AnalyticsBucket{
+			// Начало периода приходит из базы уже в поясе отчета, без смещения
+			Start:          c.Bucket.Format("2006-01-02"),
+			AnalyticsStats: analyticsStats(c),
+		})
+	}
+	response.Totals = analyticsStats(&totals)
+
+	for _, w := range weekdays {
+		response.Weekdays = append(response.Weekdays, models.WeekdayLoad{Weekday: w.Key, Appointments: w.Count})
+	}
+	for _, h := range hours {
+		response.Hours = append(response.Hours, models.HourLoad{Hour: h.Key, Appointments: h.Count})
+	}
+
+	return response, nil
+}
+
+// analyticsPrice - цена приема врача для оценки выручки.
+// Аналитика не должна ломаться из-за specialist_service, поэтому при ошибке цена 0
+func (s *appointmentService) analyticsPrice(doctorID uuid.UUID) float64 {
+	if s.options.Prices == nil {
+		return 0
+	}
+
+	price, err := s.options.Prices.DoctorPrice(context.Background(), doctorID)
+	if err != nil {
+		s.logError("Failed to get doctor price for analytics", map[string]interface{}{
+			"doctorID": doctorID.String(),
+			"error":    err.Error(),
+		})
+		return 0
+	}
+	return price
+}
+
+// analyticsStats переводит агрегаты в показатели ответа и считает доли
+func analyticsStats(c *repository.AnalyticsCounts) models.AnalyticsStats {
+	return models.AnalyticsStats{
+		SlotsOffered:     c.Offered,
+		Booked:           c.Booked,
+		Completed:        c.Completed,
+		Canceled:         c.Canceled,
+		NoShow:           c.NoShow,
+		Online:           c.Online,
+		Offline:          c.Offline,
+		Utilization:      ratio(c.Booked, c.Offered),
+		CancellationRate: ratio(c.Canceled, c.Booked+c.Canceled),
+		NoShowRate:       ratio(c.NoShow, c.Completed+c.NoShow),
+		Revenue:          c.Revenue,
+	}
+}
+
+// ratio - доля part от whole, 0 при пустом whole
+func ratio(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/files"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// defaultFileGrantTTL - срок ссылки на файл другого участника, если он не задан в настройках
+const defaultFileGrantTTL = 15 * time.Minute
+
+// AttachFile - участник приема прикрепляет к записи свой файл из FileServer.
+// Пока прием активен, другой участник может читать файл, даже если он не публичный.
+// token - токен пользователя, с которым FileServer подтверждает, что файл принадлежит ему
+func (s *appointmentService) AttachFile(userID uuid.UUID, role string, appointmentID uuid.UUID, token string, req *models.AttachFileRequest) (*models.AttachmentResponse, error) {
+	if s.options.Files == nil || s.options.FileOwners == nil {
+		return nil, fmt.Errorf("%w: file attachments are not configured", ErrAttachmentsUnavailable)
+	}
+
+	appointment, err := s.attachmentAppointment(userID, role, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if !appointment.IsActive() {
+		return nil, fmt.Errorf("%w: cannot attach files to appointment with status '%s'", ErrInvalidStatus, appointment.Status)
+	}
+
+	// Разрешение на чтение подписывается от имени прикрепившего, поэтому прикрепить можно только свой файл
+	ownerID, err := s.options.FileOwners.FileOwner(context.Background(), req.FileID, token)
+	if err != nil {
+		if errors.Is(err, files.ErrFileNotFound) {
+			return nil, fmt.Errorf("%w: file not found", ErrInvalidInput)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrAttachmentsUnavailable, err)
+	}
+	if ownerID != userID {
+		return nil, fmt.Errorf("%w: only own files can be attached", ErrForbidden)
+	}
+
+	attachment := &models.AppointmentAttachment{
+		AppointmentID:  appointment.ID,
+		FileID:         req.FileID,
+		AttachedBy:     userID,
+		AttachedByRole: role,
+		Note:           req.Note,
+	}
+	if err := s.repo.CreateAttachment(attachment); err != nil {
+		if errors.Is(err, repository.ErrAlreadyAttached) {
+			return nil, ErrAlreadyAttached
+		}
+		return nil, fmt.Errorf("failed to attach file: %w", err)
+	}
+
+	s.logInfo("File attached to appointment", map[string]interface{}{
+		"appointmentID": appointment.ID.String(),
+		"fileID":        req.FileID.String(),
+		"attachedBy":    userID.String(),
+		"role":          role,
+	})
+
+	return s.attachmentToResponse(appointment, attachment, userID)
+}
+
+// GetAttachments - файлы записи со ссылками на скачивание для текущего участника
+func (s *appointmentService) GetAttachments(userID uuid.UUID, role string, appointmentID uuid.UUID) ([]*models.AttachmentResponse, error) {
+	appointment, err := s.attachmentAppointment(userID, role, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.repo.GetAttachments(appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	response := make([]*models.AttachmentResponse, len(attachments))
+	for i, attachment := range attachments {
+		response[i], err = s.attachmentToResponse(appointment, attachment, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// DetachFile - открепляет файл от активной записи; открепить можно только свой файл.
+// Файлы завершенного приема остаются в записи как часть истории визита
+func (s *appointmentService) DetachFile(userID uuid.UUID, role string, appointmentID, fileID uuid.UUID) error {
+	appointment, err := s.attachmentAppointment(userID, role, appointmentID)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := s.repo.GetAttachment(appointment.ID, fileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAttachmentNotFound
+		}
+		return fmt.Errorf("failed to get attachment: %w", err)
+	}
+	if attachment.AttachedBy != userID {
+		return fmt.Errorf("%w: file was attached by another participant", ErrForbidden)
+	}
+	if !appointment.IsActive() {
+		return fmt.Errorf("%w: cannot detach files from appointment with status '%s'", ErrInvalidStatus, appointment.Status)
+	}
+
+	if err := s.repo.DeleteAttachment(attachment.ID); err != nil {
+		return fmt.Errorf("failed to detach file: %w", err)
+	}
+
+	s.logInfo("File detached from appointment", map[string]interface{}{
+		"appointmentID": appointment.ID.String(),
+		"fileID":        fileID.String(),
+		"userID":        userID.String(),
+	})
+
+	return nil
+}
+
+// attachmentAppointment - личная запись, участником которой является пользователь
+func (s *appointmentService) attachmentAppointment(userID uuid.UUID, role string, appointmentID uuid.UUID) (*models.Appointment, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	isDoctor := role == "doctor" && appointment.DoctorID == userID
+	isPatient := role == "patient" && appointment.PatientID != nil && *appointment.PatientID == userID
+	if !isDoctor && !isPatient {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this user", ErrForbidden)
+	}
+
+	// Файлы одного пациента не должны открываться остальным участникам занятия
+	if appointment.IsGroup() {
+		return nil, fmt.Errorf("%w: files cannot be attached to a group session", ErrInvalidInput)
+	}
+
+	return appointment, nil
+}
+
+// attachmentToResponse - свой файл скачивается по обычной ссылке, файл другого участника -
+// по ссылке с разрешением, которое выдается только пока прием активен
+func (s *appointmentService) attachmentToResponse(appointment *models.Appointment, attachment *models.AppointmentAttachment, viewerID uuid.UUID) (*models.AttachmentResponse, error) {
+	response := &models.AttachmentResponse{
+		FileID:         attachment.FileID,
+		AttachedBy:     attachment.AttachedBy,
+		AttachedByRole: attachment.AttachedByRole,
+		Note:           attachment.Note,
+		CreatedAt:      attachment.CreatedAt,
+	}
+
+	switch {
+	case s.options.Files == nil:
+	case attachment.AttachedBy == viewerID:
+		response.DownloadURL = s.options.Files.DownloadURL(attachment.FileID)
+	case appointment.IsActive():
+		ttl := s.options.FileGrantTTL
+		if ttl <= 0 {
+			ttl = defaultFileGrantTTL
+		}
+
+		url, err := s.options.Files.GrantURL(files.Grant{
+			FileID:    attachment.FileID,
+			OwnerID:   attachment.AttachedBy,
+			GranteeID: viewerID,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file link: %w", err)
+		}
+		response.DownloadURL = url
+	}
+
+	return response, nil
+}
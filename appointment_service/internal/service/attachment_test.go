@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/files"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// attachmentRepository - одна запись и прикрепленные к ней файлы в памяти
+type attachmentRepository struct {
+	repository.AppointmentRepository
+
+	appointment *models.Appointment
+	attachments []*models.AppointmentAttachment
+}
+
+func (r *attachmentRepository) GetAppointmentByID(id uuid.UUID) (*models.Appointment, error) {
+	return r.appointment, nil
+}
+
+func (r *attachmentRepository) CreateAttachment(attachment *models.AppointmentAttachment) error {
+	r.attachments = append(r.attachments, attachment)
+	return nil
+}
+
+// fileOwners - владельцы файлов FileServer; файла нет в карте - FileServer его не отдает
+type fileOwners map[uuid.UUID]uuid.UUID
+
+func (o fileOwners) FileOwner(ctx context.Context, fileID uuid.UUID, token string) (uuid.UUID, error) {
+	owner, ok := o[fileID]
+	if !ok {
+		return uuid.Nil, files.ErrFileNotFound
+	}
+	return owner, nil
+}
+
+// staticLinker - ссылки на файлы без подписи
+type staticLinker struct{}
+
+func (staticLinker) DownloadURL(fileID uuid.UUID) string {
+	return "/files/" + fileID.String() + "/download"
+}
+
+func (staticLinker) GrantURL(grant files.Grant) (string, error) {
+	return "/files/" + grant.FileID.String() + "/download?grant=test", nil
+}
+
+func TestAttachFileRequiresOwnFile(t *testing.T) {
+	doctorID := uuid.New()
+	patientID := uuid.New()
+	ownFile := uuid.New()
+	doctorFile := uuid.New()
+
+	repo := &attachmentRepository{appointment: &models.Appointment{
+		ID:        uuid.New(),
+		DoctorID:  doctorID,
+		PatientID: &patientID,
+		Status:    models.StatusBooked,
+		Capacity:  1,
+		StartTime: time.Now().Add(time.Hour),
+		EndTime:   time.Now().Add(90 * time.Minute),
+	}}
+	svc := NewAppointmentService(repo, nil, nil, Options{
+		Files:      staticLinker{},
+		FileOwners: fileOwners{ownFile: patientID, doctorFile: doctorID},
+	})
+
+	cases := []struct {
+		name   string
+		fileID uuid.UUID
+		want   error
+	}{
+		{"file of another participant", doctorFile, ErrForbidden},
+		{"unknown file", uuid.New(), ErrInvalidInput},
+	}
+	for _, tc := range cases {
+		_, err := svc.AttachFile(patientID, "patient", repo.appointment.ID, "token", &models.AttachFileRequest{FileID: tc.fileID})
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+	if len(repo.attachments) != 0 {
+		t.Fatalf("expected no attachments to be stored, got %d", len(repo.attachments))
+	}
+
+	if _, err := svc.AttachFile(patientID, "patient", repo.appointment.ID, "token", &models.AttachFileRequest{FileID: ownFile}); err != nil {
+		t.Fatalf("attach own file: %v", err)
+	}
+	if len(repo.attachments) != 1 || repo.attachments[0].FileID != ownFile {
+		t.Fatalf("expected own file to be attached, got %+v", repo.attachments)
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/payment"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// slotRepository - репозиторий с одним слотом в памяти. BookSlot повторяет условный UPDATE
+// репозитория: запись сохраняется, только если слот все еще свободен.
+// Остальные методы не реализованы - их вызов в тесте упадет на nil-интерфейсе
+type slotRepository struct {
+	repository.AppointmentRepository
+
+	mu   sync.Mutex
+	slot models.Appointment
+}
+
+func (r *slotRepository) GetAppointmentByID(id uuid.UUID) (*models.Appointment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id != r.slot.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	slot := r.slot
+	return &slot, nil
+}
+
+func (r *slotRepository) GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicy, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *slotRepository) BookSlot(appointment *models.Appointment, limits *repository.BookingLimits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.slot.Status != appointment.PersistedStatus() || r.slot.PatientID != nil {
+		return repository.ErrSlotTaken
+	}
+	r.slot = *appointment
+	return nil
+}
+
+func TestBookAppointmentConcurrentOnlyOneWins(t *testing.T) {
+	slot := models.Appointment{
+		ID:              uuid.New(),
+		DoctorID:        uuid.New(),
+		StartTime:       time.Now().Add(48 * time.Hour),
+		EndTime:         time.Now().Add(48*time.Hour + 30*time.Minute),
+		Title:           "Консультация терапевта",
+		Status:          models.StatusAvailable,
+		AppointmentType: "offline",
+		Capacity:        1,
+	}
+	repo := &slotRepository{slot: slot}
+	svc := NewAppointmentService(repo, nil, nil, Options{})
+
+	const patients = 50
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([]error, patients)
+		winners = make([]uuid.UUID, patients)
+	)
+	for i := 0; i < patients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			patientID := uuid.New()
+			winners[i] = patientID
+			<-start
+			_, results[i] = svc.BookAppointment(patientID, slot.ID, &models.BookAppointmentRequest{})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var winner *uuid.UUID
+	for i, err := range results {
+		switch {
+		case err == nil:
+			if winner != nil {
+				t.Fatalf("slot booked twice: by %s and %s", *winner, winners[i])
+			}
+			winner = &winners[i]
+		case !errors.Is(err, ErrSlotTaken):
+			t.Fatalf("booking %d: expected ErrSlotTaken, got %v", i, err)
+		}
+	}
+	if winner == nil {
+		t.Fatal("no booking succeeded")
+	}
+
+	booked, _ := repo.GetAppointmentByID(slot.ID)
+	if booked.Status != models.StatusBooked || booked.PatientID == nil || *booked.PatientID != *winner {
+		t.Fatalf("slot should be booked by the winner %s, got status %s patient %v", *winner, booked.Status, booked.PatientID)
+	}
+}
+
+// limitRepository - слот и правила врача с лимитом записей. Быстрая проверка лимита проходит,
+// а транзакция бронирования видит параллельную запись пациента и отказывает
+type limitRepository struct {
+	slotRepository
+
+	policy *models.BookingPolicy
+	limits *repository.BookingLimits
+}
+
+func (r *limitRepository) GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicy, error) {
+	return r.policy, nil
+}
+
+func (r *limitRepository) CountPatientActiveBookings(doctorID, patientID uuid.UUID, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *limitRepository) GetDoctorOccupiedTime(doctorID uuid.UUID, from, to time.Time) ([]*models.Appointment, error) {
+	return nil, nil
+}
+
+func (r *limitRepository) BookSlot(appointment *models.Appointment, limits *repository.BookingLimits) error {
+	r.limits = limits
+	return repository.ErrBookingLimitReached
+}
+
+func TestBookAppointmentLimitRecheckedInTransaction(t *testing.T) {
+	doctorID := uuid.New()
+	repo := &limitRepository{
+		slotRepository: slotRepository{slot: models.Appointment{
+			ID:              uuid.New(),
+			DoctorID:        doctorID,
+			StartTime:       time.Now().Add(48 * time.Hour),
+			EndTime:         time.Now().Add(48*time.Hour + 30*time.Minute),
+			Status:          models.StatusAvailable,
+			AppointmentType: "offline",
+			Capacity:        1,
+		}},
+		policy: &models.BookingPolicy{DoctorID: doctorID, MaxActiveBookings: 1, BufferMinutes: 10},
+	}
+	svc := NewAppointmentService(repo, nil, nil, Options{})
+
+	patientID := uuid.New()
+	_, err := svc.BookAppointment(patientID, repo.slot.ID, &models.BookAppointmentRequest{})
+
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || policyErr.Code != PolicyActiveBookingLimit {
+		t.Fatalf("expected %s policy error, got %v", PolicyActiveBookingLimit, err)
+	}
+	if repo.limits == nil || repo.limits.PatientID != patientID || repo.limits.MaxActiveBookings != 1 || repo.limits.Buffer != 10*time.Minute {
+		t.Fatalf("booking transaction got limits %+v", repo.limits)
+	}
+}
+
+// fixedPrice - цена приема любого врача
+type fixedPrice float64
+
+func (p fixedPrice) DoctorPrice(ctx context.Context, doctorID uuid.UUID) (float64, error) {
+	return float64(p), nil
+}
+
+func TestBookAppointmentRejectsPaidGroupSession(t *testing.T) {
+	repo := &slotRepository{slot: models.Appointment{
+		ID:              uuid.New(),
+		DoctorID:        uuid.New(),
+		StartTime:       time.Now().Add(48 * time.Hour),
+		EndTime:         time.Now().Add(48*time.Hour + time.Hour),
+		Status:          models.StatusAvailable,
+		AppointmentType: "offline",
+		Capacity:        10,
+	}}
+	svc := NewAppointmentService(repo, nil, nil, Options{
+		Payments: payment.NewFakeProvider("test-secret"),
+		Prices:   fixedPrice(5000),
+	})
+
+	_, err := svc.BookAppointment(uuid.New(), repo.slot.ID, &models.BookAppointmentRequest{})
+	if !errors.Is(err, ErrPaidGroupSession) {
+		t.Fatalf("expected ErrPaidGroupSession, got %v", err)
+	}
+	if repo.slot.SeatsTaken != 0 {
+		t.Fatalf("seat was taken without payment")
+	}
+}
+
+func TestDoctorCancelAppointmentRejectsSlotsWithoutBooking(t *testing.T) {
+	doctorID, patientID := uuid.New(), uuid.New()
+	for _, status := range []string{models.StatusAvailable, models.StatusHeld} {
+		slot := models.Appointment{
+			ID:        uuid.New(),
+			DoctorID:  doctorID,
+			StartTime: time.Now().Add(48 * time.Hour),
+			EndTime:   time.Now().Add(48*time.Hour + 30*time.Minute),
+			Status:    status,
+			Capacity:  1,
+		}
+		if status == models.StatusHeld {
+			// Удерживаемый слот ждет оплаты пациента
+			slot.PatientID = &patientID
+			slot.PaymentStatus = "pending"
+		}
+		repo := &slotRepository{slot: slot}
+		svc := NewAppointmentService(repo, nil, nil, Options{})
+
+		_, err := svc.DoctorCancelAppointment(doctorID, slot.ID, &models.DoctorCancelRequest{Reason: "Болезнь"})
+		if !errors.Is(err, ErrInvalidStatus) {
+			t.Fatalf("%s: expected ErrInvalidStatus, got %v", status, err)
+		}
+		if repo.slot.Status != status {
+			t.Fatalf("%s: slot must stay untouched, got %s", status, repo.slot.Status)
+		}
+	}
+}
+
+// brokenRepository - база недоступна: любое чтение записи завершается ошибкой
+type brokenRepository struct {
+	repository.AppointmentRepository
+}
+
+func (r *brokenRepository) GetAppointmentByID(id uuid.UUID) (*models.Appointment, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestAppointmentLookupKeepsDatabaseErrorsInternal(t *testing.T) {
+	svc := NewAppointmentService(&brokenRepository{}, nil, nil, Options{})
+	doctorID, appointmentID := uuid.New(), uuid.New()
+
+	if _, err := svc.CompleteAppointment(doctorID, appointmentID, &models.CompleteAppointmentRequest{}); err == nil || errors.Is(err, ErrAppointmentNotFound) {
+		t.Fatalf("complete: expected an internal error, got %v", err)
+	}
+	if _, err := svc.CheckInAppointment(doctorID, appointmentID); err == nil || errors.Is(err, ErrAppointmentNotFound) {
+		t.Fatalf("check-in: expected an internal error, got %v", err)
+	}
+
+	// Отсутствующая запись по-прежнему дает 404
+	missing := &slotRepository{}
+	if _, err := NewAppointmentService(missing, nil, nil, Options{}).CompleteAppointment(doctorID, appointmentID, &models.CompleteAppointmentRequest{}); !errors.Is(err, ErrAppointmentNotFound) {
+		t.Fatalf("expected ErrAppointmentNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/ical"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+const (
+	// uploadSource - источник исключений из загруженных вручную .ics файлов
+	uploadSource = "ics_upload"
+	// maxCalendarSize - предельный размер импортируемого календаря
+	maxCalendarSize = 5 << 20
+	// calendarFetchTimeout - сколько ждем ответа сервера внешнего календаря
+	calendarFetchTimeout = 15 * time.Second
+	// calendarMaxRedirects - сколько перенаправлений допускается при скачивании календаря
+	calendarMaxRedirects = 5
+	// busyTimeHorizon - на сколько вперед разворачиваются повторяющиеся события календаря;
+	// синхронизация внешних календарей каждый раз продлевает горизонт
+	busyTimeHorizon = 180 * 24 * time.Hour
+)
+
+// errCalendarHostBlocked - ссылка на календарь ведет во внутреннюю сеть
+var errCalendarHostBlocked = errors.New("calendar host is not allowed")
+
+// sharedAddressSpace - 100.64.0.0/10 (RFC 6598), адреса за NAT провайдера
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// calendarFetcher - HTTP клиент для внешних календарей. Ссылку задает врач, а скачивает сервер,
+// поэтому адрес проверяется при каждом соединении (в том числе после перенаправлений и
+// повторного разрешения DNS): соединения с внутренними адресами запрещены, кроме хостов и сетей
+// из списка разрешенных (локальная заглушка в тестах, сервер календарей в docker-compose)
+type calendarFetcher struct {
+	client *http.Client
+	hosts  map[string]bool
+	nets   []*net.IPNet
+}
+
+// newCalendarFetcher - клиент календарей; allowed - имена хостов, IP-адреса и сети CIDR,
+// которые разрешены, даже если они во внутренней сети
+func newCalendarFetcher(allowed []string) *calendarFetcher {
+	f := &calendarFetcher{hosts: make(map[string]bool)}
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			f.nets = append(f.nets, network)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			f.nets = append(f.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		f.hosts[entry] = true
+	}
+
+	guarded := &net.Dialer{
+		Timeout: calendarFetchTimeout,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !f.allowsIP(ip) {
+				return fmt.Errorf("%w: %s", errCalendarHostBlocked, host)
+			}
+			return nil
+		},
+	}
+	trusted := &net.Dialer{Timeout: calendarFetchTimeout}
+
+	f.client = &http.Client{
+		Timeout: calendarFetchTimeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				// Разрешенное имя может указывать во внутреннюю сеть - его адреса не проверяем
+				if host, _, err := net.SplitHostPort(address); err == nil && f.hosts[strings.ToLower(host)] {
+					return trusted.DialContext(ctx, network, address)
+				}
+				return guarded.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout:   calendarFetchTimeout,
+			ResponseHeaderTimeout: calendarFetchTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= calendarMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", calendarMaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// allowsIP - адрес из интернета или из разрешенных сетей
+func (f *calendarFetcher) allowsIP(ip net.IP) bool {
+	if isPublicIP(ip) {
+		return true
+	}
+	for _, network := range f.nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHost - можно ли подключать календарь с хоста из ссылки. Внутренние адреса, указанные явно,
+// отклоняются сразу; остальные имена проверяются при соединении
+func (f *calendarFetcher) allowsHost(host string) bool {
+	if f.hosts[strings.ToLower(host)] {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return f.allowsIP(ip)
+	}
+	return !strings.EqualFold(host, "localhost")
+}
+
+// isPublicIP - адрес из интернета, а не loopback, частная, link-local или служебная сеть
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !sharedAddressSpace.Contains(ip) && !ip.Equal(net.IPv4bcast)
+}
+
+// ImportCalendar - импорт занятого времени из загруженного .ics файла.
+// Повторная загрузка того же файла обновляет уже импортированные события по UID и не создает дублей;
+// события, пропавшие из файла, не удаляются - загрузки разных файлов дополняют друг друга
+func (s *appointmentService) ImportCalendar(doctorID uuid.UUID, r io.Reader) (*models.CalendarImportResponse, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxCalendarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	if len(data) > maxCalendarSize {
+		return nil, fmt.Errorf("%w: calendar file is larger than %d bytes", ErrInvalidInput, maxCalendarSize)
+	}
+
+	exceptions, err := s.parseBusyTime(doctorID, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	return s.importBusyTime(doctorID, uploadSource, exceptions, false)
+}
+
+// AddExternalCalendar - подключение внешнего календаря по ссылке с первой синхронизацией.
+// Календарь сохраняется, только если его удалось скачать и разобрать
+func (s *appointmentService) AddExternalCalendar(doctorID uuid.UUID, req *models.RegisterExternalCalendarRequest) (*models.CalendarImportResponse, error) {
+	calendarURL, err := s.calendars.normalizeURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions, err := s.fetchBusyTime(doctorID, calendarURL)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	calendar := &models.ExternalCalendar{
+		DoctorID:     doctorID,
+		URL:          calendarURL,
+		LastSyncedAt: &now,
+	}
+	if err := s.repo.CreateExternalCalendar(calendar); err != nil {
+		return nil, fmt.Errorf("failed to save external calendar: %w", err)
+	}
+
+	response, err := s.importBusyTime(doctorID, calendar.Source(), exceptions, true)
+	if err != nil {
+		return nil, err
+	}
+	response.CalendarID = &calendar.ID
+
+	return response, nil
+}
+
+// GetExternalCalendars - внешние календари врача
+func (s *appointmentService) GetExternalCalendars(doctorID uuid.UUID) ([]*models.ExternalCalendarResponse, error) {
+	calendars, err := s.repo.GetDoctorExternalCalendars(doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get external calendars: %w", err)
+	}
+
+	responses := make([]*models.ExternalCalendarResponse, len(calendars))
+	for i, calendar := range calendars {
+		responses[i] = externalCalendarToResponse(calendar)
+	}
+
+	return responses, nil
+}
+
+// DeleteExternalCalendar - отключение календаря вместе с импортированным из него занятым временем.
+// Удаленные ранее слоты не восстанавливаются, их можно сгенерировать заново
+func (s *appointmentService) DeleteExternalCalendar(doctorID, calendarID uuid.UUID) error {
+	calendar, err := s.getDoctorExternalCalendar(doctorID, calendarID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteExternalCalendar(calendar); err != nil {
+		return fmt.Errorf("failed to delete external calendar: %w", err)
+	}
+
+	s.logInfo("External calendar deleted", map[string]interface{}{
+		"doctorID":   doctorID.String(),
+		"calendarID": calendarID.String(),
+	})
+
+	return nil
+}
+
+// SyncExternalCalendar - повторная синхронизация календаря: новые и измененные события
+// блокируют время, события, удаленные из календаря, перестают его блокировать
+func (s *appointmentService) SyncExternalCalendar(doctorID, calendarID uuid.UUID) (*models.CalendarImportResponse, error) {
+	calendar, err := s.getDoctorExternalCalendar(doctorID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions, fetchErr := s.fetchBusyTime(doctorID, calendar.URL)
+	if fetchErr != nil {
+		// Ошибку сохраняем, чтобы врач видел ее в списке календарей; занятое время остается прежним
+		calendar.LastError = fetchErr.Error()
+		if err := s.repo.UpdateExternalCalendar(calendar); err != nil {
+			s.logError("Failed to save external calendar sync error", map[string]interface{}{
+				"calendarID": calendar.ID.String(),
+				"error":      err.Error(),
+			})
+		}
+		return nil, fetchErr
+	}
+
+	response, err := s.importBusyTime(doctorID, calendar.Source(), exceptions, true)
+	if err != nil {
+		return nil, err
+	}
+	response.CalendarID = &calendar.ID
+
+	now := time.Now()
+	calendar.LastSyncedAt = &now
+	calendar.LastError = ""
+	if err := s.repo.UpdateExternalCalendar(calendar); err != nil {
+		return nil, fmt.Errorf("failed to update external calendar: %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *appointmentService) getDoctorExternalCalendar(doctorID, calendarID uuid.UUID) (*models.ExternalCalendar, error) {
+	calendar, err := s.repo.GetExternalCalendarByID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCalendarNotFound, err)
+	}
+
+	if calendar.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: calendar doesn't belong to this doctor", ErrForbidden)
+	}
+
+	return calendar, nil
+}
+
+// importBusyTime - сохраняет занятое время и освобождает расписание: свободные слоты,
+// пересекающиеся с новым или измененным занятым временем, удаляются, а забронированные
+// и удерживаемые до оплаты возвращаются врачу в отчете отдельными списками - их нужно отменить или перенести
+func (s *appointmentService) importBusyTime(doctorID uuid.UUID, source string, exceptions []*models.ScheduleException, removeMissing bool) (*models.CalendarImportResponse, error) {
+	result, err := s.repo.SyncImportedExceptions(doctorID, source, exceptions, removeMissing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save imported busy time: %w", err)
+	}
+
+	response := &models.CalendarImportResponse{
+		Created: result.Created,
+		Updated: result.Updated,
+		Removed: result.Removed,
+	}
+
+	if len(result.Changed) > 0 {
+		from, until := *result.Changed[0].BusyStart, *result.Changed[0].BusyEnd
+		for _, exception := range result.Changed[1:] {
+			if exception.BusyStart.Before(from) {
+				from = *exception.BusyStart
+			}
+			if exception.BusyEnd.After(until) {
+				until = *exception.BusyEnd
+			}
+		}
+		// Слот, начавшийся до занятого времени, тоже может его задевать; прошедшие слоты не трогаем
+		from = from.Add(-24 * time.Hour)
+		if now := time.Now(); from.Before(now) {
+			from = now
+		}
+
+		slots, err := s.repo.GetDoctorSlotsFrom(doctorID, from, &until)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slots affected by busy time: %w", err)
+		}
+
+		var freeSlotIDs []uuid.UUID
+		for _, slot := range slots {
+			if !overlapsBusyTime(result.Changed, slot.StartTime, slot.EndTime) {
+				continue
+			}
+			switch slot.Status {
+			case models.StatusAvailable:
+				freeSlotIDs = append(freeSlotIDs, slot.ID)
+			case models.StatusHeld:
+				// Удержание не снимаем: пациент, возможно, уже платит. Врач видит такие слоты отдельно
+				response.HeldAppointments = append(response.HeldAppointments, s.appointmentToResponse(slot))
+			default:
+				response.BookedAppointments = append(response.BookedAppointments, s.appointmentToResponse(slot))
+			}
+		}
+
+		removed, err := s.repo.DeleteAvailableSlots(freeSlotIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove slots affected by busy time: %w", err)
+		}
+		response.RemovedSlots = int(removed)
+	}
+
+	s.logInfo("Busy time imported", map[string]interface{}{
+		"doctorID":           doctorID.String(),
+		"source":             source,
+		"created":            response.Created,
+		"updated":            response.Updated,
+		"removed":            response.Removed,
+		"removedSlots":       response.RemovedSlots,
+		"bookedAppointments": len(response.BookedAppointments),
+		"heldAppointments":   len(response.HeldAppointments),
+	})
+
+	return response, nil
+}
+
+// overlapsBusyTime - пересекает ли интервал [start, end) хотя бы одно занятое время
+func overlapsBusyTime(busy []*models.ScheduleException, start, end time.Time) bool {
+	for _, exception := range busy {
+		if exception.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchBusyTime - скачивает календарь по ссылке и разбирает занятое время
+func (s *appointmentService) fetchBusyTime(doctorID uuid.UUID, calendarURL string) ([]*models.ScheduleException, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, calendarURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	resp, err := s.calendars.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errCalendarHostBlocked) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrCalendarUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: server responded with %s", ErrCalendarUnavailable, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCalendarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCalendarUnavailable, err)
+	}
+	if len(data) > maxCalendarSize {
+		return nil, fmt.Errorf("%w: calendar is larger than %d bytes", ErrCalendarUnavailable, maxCalendarSize)
+	}
+
+	exceptions, err := s.parseBusyTime(doctorID, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCalendarUnavailable, err)
+	}
+
+	return exceptions, nil
+}
+
+// parseBusyTime - превращает события календаря в исключения типа busy.
+// Прошедшие события пропускаются: на уже прошедшее время записей не бывает.
+// Повторяющиеся события разворачиваются на busyTimeHorizon вперед.
+// Время без часового пояса трактуется как местное время врача
+func (s *appointmentService) parseBusyTime(doctorID uuid.UUID, data []byte) ([]*models.ScheduleException, error) {
+	location := s.doctorLocation(doctorID)
+
+	now := time.Now()
+	events, err := ical.ParseBusy(strings.NewReader(string(data)), location, now, now.Add(busyTimeHorizon))
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions := make([]*models.ScheduleException, 0, len(events))
+	for _, event := range events {
+		start, end := event.Start.UTC(), event.End.UTC()
+		localStart, localEnd := event.Start.In(location), event.End.Add(-time.Nanosecond).In(location)
+		date := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, time.UTC)
+		endDate := time.Date(localEnd.Year(), localEnd.Month(), localEnd.Day(), 0, 0, 0, 0, time.UTC)
+
+		reason := event.Summary
+		if len([]rune(reason)) > 255 {
+			reason = string([]rune(reason)[:255])
+		}
+
+		exceptions = append(exceptions, &models.ScheduleException{
+			DoctorID:    doctorID,
+			Date:        date,
+			EndDate:     &endDate,
+			Type:        "busy",
+			BusyStart:   &start,
+			BusyEnd:     &end,
+			ExternalUID: event.UID,
+			Reason:      reason,
+		})
+	}
+
+	return exceptions, nil
+}
+
+// doctorLocation - часовой пояс врача: пояс активного расписания, иначе пояс по умолчанию
+func (s *appointmentService) doctorLocation(doctorID uuid.UUID) *time.Location {
+	return lookupDoctorLocation(s.repo, doctorID, s.options.DefaultTimezone)
+}
+
+// lookupDoctorLocation - пояс активного расписания врача, иначе defaultTimezone, иначе UTC
+func lookupDoctorLocation(repo repository.AppointmentRepository, doctorID uuid.UUID, defaultTimezone string) *time.Location {
+	if schedules, err := repo.GetDoctorSchedules(doctorID); err == nil {
+		for _, schedule := range schedules {
+			if !schedule.IsActive {
+				continue
+			}
+			if location, err := schedule.Location(); err == nil {
+				return location
+			}
+		}
+	}
+
+	if defaultTimezone != "" {
+		if location, err := time.LoadLocation(defaultTimezone); err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// normalizeURL - проверяет ссылку на календарь; webcal:// заменяется на https://
+func (f *calendarFetcher) normalizeURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid calendar url: %v", ErrInvalidInput, err)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "webcal", "webcals":
+		parsed.Scheme = "https"
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("%w: calendar url must use http, https or webcal scheme", ErrInvalidInput)
+	}
+
+	if parsed.Host == "" {
+		return "", fmt.Errorf("%w: calendar url has no host", ErrInvalidInput)
+	}
+	if !f.allowsHost(parsed.Hostname()) {
+		return "", fmt.Errorf("%w: calendar url must point to a public host", ErrInvalidInput)
+	}
+
+	return parsed.String(), nil
+}
+
+func externalCalendarToResponse(calendar *models.ExternalCalendar) *models.ExternalCalendarResponse {
+	return &models.ExternalCalendarResponse{
+		ID:           calendar.ID,
+		URL:          calendar.URL,
+		LastSyncedAt: calendar.LastSyncedAt,
+		LastError:    calendar.LastError,
+		CreatedAt:    calendar.CreatedAt,
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// calendarRepository - подключенные календари, занятое время и слоты врача в памяти
+type calendarRepository struct {
+	repository.AppointmentRepository
+
+	calendars []*models.ExternalCalendar
+	busy      []*models.ScheduleException
+	slots     []*models.Appointment
+	deleted   []uuid.UUID
+}
+
+func (r *calendarRepository) GetDoctorSchedules(doctorID uuid.UUID) ([]*models.DoctorSchedule, error) {
+	return nil, nil
+}
+
+func (r *calendarRepository) CreateExternalCalendar(calendar *models.ExternalCalendar) error {
+	calendar.ID = uuid.New()
+	r.calendars = append(r.calendars, calendar)
+	return nil
+}
+
+func (r *calendarRepository) SyncImportedExceptions(doctorID uuid.UUID, source string, exceptions []*models.ScheduleException, removeMissing bool) (*repository.ImportResult, error) {
+	r.busy = append(r.busy, exceptions...)
+	return &repository.ImportResult{Created: len(exceptions), Changed: exceptions}, nil
+}
+
+func (r *calendarRepository) GetDoctorSlotsFrom(doctorID uuid.UUID, from time.Time, until *time.Time) ([]*models.Appointment, error) {
+	return r.slots, nil
+}
+
+func (r *calendarRepository) DeleteAvailableSlots(ids []uuid.UUID) (int64, error) {
+	r.deleted = append(r.deleted, ids...)
+	return int64(len(ids)), nil
+}
+
+func TestAddExternalCalendarFromAllowedLocalHost(t *testing.T) {
+	start := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	calendar := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nUID:conference\r\nSUMMARY:Конференция\r\n" +
+		"DTSTART:" + start.Format("20060102T150405Z") + "\r\n" +
+		"DTEND:" + start.Add(3*time.Hour).Format("20060102T150405Z") + "\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	// Локальная заглушка внешнего календаря
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		w.Write([]byte(calendar))
+	}))
+	defer server.Close()
+
+	doctorID, patientID := uuid.New(), uuid.New()
+	slot := func(status string, offset time.Duration) *models.Appointment {
+		appointment := &models.Appointment{
+			ID:        uuid.New(),
+			DoctorID:  doctorID,
+			StartTime: start.Add(offset),
+			EndTime:   start.Add(offset + 30*time.Minute),
+			Status:    status,
+			Capacity:  1,
+		}
+		if status != models.StatusAvailable {
+			appointment.PatientID = &patientID
+		}
+		return appointment
+	}
+	repo := &calendarRepository{slots: []*models.Appointment{
+		slot(models.StatusAvailable, 0),
+		slot(models.StatusHeld, time.Hour),
+		slot(models.StatusBooked, 2*time.Hour),
+	}}
+
+	// Без разрешения внутренний адрес отклоняется
+	blocked := NewAppointmentService(repo, nil, nil, Options{})
+	if _, err := blocked.AddExternalCalendar(doctorID, &models.RegisterExternalCalendarRequest{URL: server.URL}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected loopback calendar to be rejected, got %v", err)
+	}
+
+	svc := NewAppointmentService(repo, nil, nil, Options{CalendarAllowedHosts: []string{"127.0.0.0/8"}})
+	response, err := svc.AddExternalCalendar(doctorID, &models.RegisterExternalCalendarRequest{URL: server.URL})
+	if err != nil {
+		t.Fatalf("add allowed calendar: %v", err)
+	}
+
+	if response.Created != 1 || len(repo.calendars) != 1 {
+		t.Fatalf("expected one imported event and a saved calendar, got %+v", response)
+	}
+	if response.RemovedSlots != 1 {
+		t.Fatalf("expected the free slot to be removed, got %d", response.RemovedSlots)
+	}
+	if len(response.HeldAppointments) != 1 || response.HeldAppointments[0].Status != models.StatusHeld {
+		t.Fatalf("expected the held slot to be reported separately, got %+v", response.HeldAppointments)
+	}
+	if len(response.BookedAppointments) != 1 || response.BookedAppointments[0].Status != models.StatusBooked {
+		t.Fatalf("expected only the booked appointment in booked_appointments, got %+v", response.BookedAppointments)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/ical"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+)
+
+// calendarUIDDomain - домен в UID событий: UID = <id записи>@vitalem.kz не меняется при правках записи
+const calendarUIDDomain = "vitalem.kz"
+
+// === CALENDAR FEEDS ===
+
+// GetCalendarFeed - ссылка на персональную ICS-ленту; токен создается при первом обращении
+func (s *appointmentService) GetCalendarFeed(userID uuid.UUID, role string) (*models.CalendarFeedResponse, error) {
+	feedToken, err := s.repo.GetCalendarFeedToken(userID)
+	if err == nil {
+		return s.calendarFeedToResponse(feedToken), nil
+	}
+
+	return s.RotateCalendarFeed(userID, role)
+}
+
+// RotateCalendarFeed - выпускает новый токен ленты; старая ссылка перестает работать
+func (s *appointmentService) RotateCalendarFeed(userID uuid.UUID, role string) (*models.CalendarFeedResponse, error) {
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+
+	feedToken := &models.CalendarFeedToken{
+		UserID: userID,
+		Role:   role,
+		Token:  token,
+	}
+	if existing, err := s.repo.GetCalendarFeedToken(userID); err == nil {
+		feedToken.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.repo.SaveCalendarFeedToken(feedToken); err != nil {
+		return nil, fmt.Errorf("failed to save calendar token: %w", err)
+	}
+
+	s.logInfo("Calendar feed token issued", map[string]interface{}{
+		"userID": userID.String(),
+		"role":   role,
+	})
+
+	return s.calendarFeedToResponse(feedToken), nil
+}
+
+// GetCalendarFeedICS - ICS-лента пользователя по секретному токену
+func (s *appointmentService) GetCalendarFeedICS(token string) (string, error) {
+	feedToken, err := s.repo.GetCalendarFeedTokenByToken(token)
+	if err != nil {
+		return "", ErrCalendarFeedNotFound
+	}
+
+	var appointments []*models.Appointment
+	if feedToken.Role == "doctor" {
+		appointments, err = s.repo.GetDoctorAppointments(feedToken.UserID)
+	} else {
+		appointments, err = s.repo.GetPatientAppointments(feedToken.UserID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get appointments for calendar: %w", err)
+	}
+
+	calendar := &ical.Calendar{Name: "Vitalem"}
+	for _, appointment := range appointments {
+		// В ленту попадают только записи с пациентами: свободные и закрытые слоты врачу не нужны
+		if !appointment.HasPatients() {
+			continue
+		}
+		calendar.Events = append(calendar.Events, appointmentToCalendarEvent(appointment, feedToken.Role))
+	}
+
+	return calendar.Encode(), nil
+}
+
+// GetAppointmentICS - .ics с одной записью для участника записи
+func (s *appointmentService) GetAppointmentICS(userID uuid.UUID, role string, appointmentID uuid.UUID) (string, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return "", err
+	}
+
+	isDoctor := role == "doctor" && appointment.DoctorID == userID
+	isPatient := role == "patient" && s.isAppointmentPatient(appointment, userID)
+	if !isDoctor && !isPatient {
+		return "", fmt.Errorf("%w: appointment doesn't belong to this user", ErrForbidden)
+	}
+
+	calendar := &ical.Calendar{
+		Events: []ical.Event{appointmentToCalendarEvent(appointment, role)},
+	}
+	return calendar.Encode(), nil
+}
+
+// appointmentToCalendarEvent - событие календаря по записи
+func appointmentToCalendarEvent(appointment *models.Appointment, role string) ical.Event {
+	event := ical.Event{
+		UID:          appointment.ID.String() + "@" + calendarUIDDomain,
+		Start:        appointment.StartTime,
+		End:          appointment.EndTime,
+		Summary:      appointment.Title,
+		LastModified: appointment.UpdatedAt,
+		Status:       "CONFIRMED",
+	}
+	if event.Summary == "" {
+		event.Summary = "Прием у врача"
+	}
+
+	var description []string
+	if appointment.AppointmentType == "online" {
+		description = append(description, "Онлайн-прием")
+		if appointment.MeetingLink != nil {
+			event.URL = *appointment.MeetingLink
+			event.Location = *appointment.MeetingLink
+			description = append(description, "Ссылка на встречу: "+*appointment.MeetingLink)
+		}
+	} else {
+		description = append(description, "Очный прием")
+	}
+
+	// Жалобы пациента видит только врач - лента пациента может оказаться на общем устройстве
+	if role == "doctor" && appointment.PatientNotes != "" {
+		description = append(description, "Жалобы: "+appointment.PatientNotes)
+	}
+
+	if appointment.Status == "canceled" {
+		event.Status = "CANCELLED"
+		if appointment.CancelReason != "" {
+			description = append(description, "Отменено: "+appointment.CancelReason)
+		}
+	}
+
+	event.Description = strings.Join(description, "\n")
+	return event
+}
+
+func (s *appointmentService) calendarFeedToResponse(feedToken *models.CalendarFeedToken) *models.CalendarFeedResponse {
+	baseURL := strings.TrimRight(s.options.CalendarFeedBaseURL, "/")
+	return &models.CalendarFeedResponse{
+		URL:       baseURL + "/appointments/calendar/feeds/" + feedToken.Token + ".ics",
+		UpdatedAt: feedToken.UpdatedAt,
+	}
+}
+
+// generateFeedToken - 256-битный случайный токен в hex
+func generateFeedToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"github.com/printprince/vitalem/logger_service/pkg/logger"
+)
+
+// CalendarSyncService - фоновая синхронизация подключенных внешних календарей врачей
+type CalendarSyncService struct {
+	repo     repository.AppointmentRepository
+	service  AppointmentService
+	logger   *logger.Client
+	interval time.Duration
+}
+
+// NewCalendarSyncService - создание планировщика синхронизации внешних календарей
+func NewCalendarSyncService(repo repository.AppointmentRepository, appointmentService AppointmentService, loggerClient *logger.Client, interval time.Duration) *CalendarSyncService {
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+
+	return &CalendarSyncService{
+		repo:     repo,
+		service:  appointmentService,
+		logger:   loggerClient,
+		interval: interval,
+	}
+}
+
+// Start - запускает планировщик, работает до отмены контекста
+func (c *CalendarSyncService) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Run(ctx)
+		}
+	}
+}
+
+// Run - синхронизирует все внешние календари; недоступный календарь не мешает остальным,
+// а его ошибка сохраняется в last_error
+func (c *CalendarSyncService) Run(ctx context.Context) {
+	calendars, err := c.repo.GetAllExternalCalendars()
+	if err != nil {
+		c.logError("Failed to get external calendars for sync", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	synced, failed := 0, 0
+	for _, calendar := range calendars {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if _, err := c.service.SyncExternalCalendar(calendar.DoctorID, calendar.ID); err != nil {
+			failed++
+			c.logError("External calendar sync failed", map[string]interface{}{
+				"doctorID":   calendar.DoctorID.String(),
+				"calendarID": calendar.ID.String(),
+				"error":      err.Error(),
+			})
+			continue
+		}
+		synced++
+	}
+
+	if len(calendars) > 0 {
+		c.logInfo("External calendars synchronized", map[string]interface{}{
+			"synced": synced,
+			"failed": failed,
+		})
+	}
+}
+
+func (c *CalendarSyncService) logInfo(message string, metadata map[string]interface{}) {
+	if c.logger != nil {
+		c.logger.Info(message, metadata)
+	}
+}
+
+func (c *CalendarSyncService) logError(message string, metadata map[string]interface{}) {
+	if c.logger != nil {
+		c.logger.Error(message, metadata)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+)
+
+var (
+	ErrAppointmentNotFound    = errors.New("appointment not found")
+	ErrExceptionNotFound      = errors.New("schedule exception not found")
+	ErrCalendarFeedNotFound   = errors.New("calendar feed not found")
+	ErrCalendarNotFound       = errors.New("external calendar not found")
+	ErrCalendarUnavailable    = errors.New("external calendar unavailable")
+	ErrForbidden              = errors.New("access forbidden")
+	ErrInvalidStatus          = errors.New("invalid appointment status")
+	ErrAppointmentNotStarted  = errors.New("appointment has not started yet")
+	ErrInvalidInput           = errors.New("invalid input")
+	ErrSlotTaken              = errors.New("slot already taken")
+	ErrNoticeTooShort         = errors.New("minimum notice period not met")
+	ErrPaymentUnavailable     = errors.New("payment provider unavailable")
+	ErrScheduleConflict       = errors.New("schedule conflicts with active schedules")
+	ErrVisitTypeNotFound      = errors.New("visit type not found")
+	ErrMeetingUnavailable     = errors.New("video meeting provider unavailable")
+	ErrPolicyViolation        = errors.New("booking policy violation")
+	ErrAlreadyParticipant     = errors.New("patient already booked this session")
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+	ErrAlreadyAttached        = errors.New("file already attached to this appointment")
+	ErrAttachmentsUnavailable = errors.New("file attachments unavailable")
+	ErrPaidGroupSession       = errors.New("group sessions are not available for doctors with paid appointments")
+)
+
+// Коды нарушений правил записи врача - по ним клиент показывает понятное сообщение
+const (
+	PolicyLeadTimeTooShort   = "lead_time_too_short"
+	PolicyBeyondHorizon      = "beyond_booking_horizon"
+	PolicyCancelCutoffPassed = "cancellation_cutoff_passed"
+	PolicyActiveBookingLimit = "active_booking_limit_reached"
+	PolicyBufferConflict     = "buffer_conflict"
+)
+
+// ScheduleConflictError - расписание пересекается с другими активными расписаниями врача
+type ScheduleConflictError struct {
+	Conflicts []models.ScheduleConflict
+}
+
+func (e *ScheduleConflictError) Error() string {
+	names := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		names[i] = fmt.Sprintf("'%s' (%s-%s, days %v)", conflict.Name, conflict.StartTime, conflict.EndTime, conflict.WorkDays)
+	}
+	return fmt.Sprintf("%s: %s", ErrScheduleConflict.Error(), strings.Join(names, ", "))
+}
+
+func (e *ScheduleConflictError) Unwrap() error {
+	return ErrScheduleConflict
+}
+
+// PolicyError - нарушение правила записи врача с машинным кодом
+type PolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPolicyViolation.Error(), e.Message)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrPolicyViolation
+}
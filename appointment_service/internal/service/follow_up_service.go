@@ -0,0 +1,365 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// followUpSourceStatuses - приемы, по итогам которых врач может назначить повторный:
+// пациент уже пришел или прием завершен
+var followUpSourceStatuses = []string{models.StatusCheckedIn, models.StatusInProgress, models.StatusCompleted}
+
+// BookFollowUp - врач записывает пациента приема на свой свободный слот.
+// Правила записи и онлайн-оплата не применяются: время выбирает сам врач
+func (s *appointmentService) BookFollowUp(doctorID, appointmentID uuid.UUID, req *models.BookFollowUpRequest) (*models.AppointmentResponse, error) {
+	previous, err := s.followUpSource(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	slot, err := s.getAppointment(req.SlotID)
+	if err != nil {
+		return nil, fmt.Errorf("slot: %w", err)
+	}
+	if err := checkFollowUpSlot(previous, slot, time.Now()); err != nil {
+		return nil, err
+	}
+
+	appointmentType, err := s.bookingType(slot.AppointmentType, req.AppointmentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := slot.BookFollowUp(doctorID, *previous.PatientID, previous.ID, appointmentType, req.DoctorNotes); err != nil {
+		return nil, ErrSlotTaken
+	}
+	s.assignMeeting(slot)
+
+	if err := s.repo.BookFollowUpSlot(slot); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, ErrSlotTaken
+		}
+		return nil, fmt.Errorf("failed to book follow-up: %w", err)
+	}
+
+	s.logInfo("Follow-up booked by doctor", map[string]interface{}{
+		"doctorID":              doctorID.String(),
+		"patientID":             previous.PatientID.String(),
+		"previousAppointmentID": previous.ID.String(),
+		"appointmentID":         slot.ID.String(),
+	})
+
+	s.publishEvent(models.EventAppointmentBooked, slot, slot.PatientID)
+
+	return s.appointmentToResponse(slot), nil
+}
+
+// ProposeFollowUp - врач предлагает пациенту приема несколько своих свободных слотов.
+// Слоты не удерживаются: пока пациент выбирает, на них может записаться кто-то другой
+func (s *appointmentService) ProposeFollowUp(doctorID, appointmentID uuid.UUID, req *models.ProposeFollowUpRequest) (*models.FollowUpProposalResponse, error) {
+	previous, err := s.followUpSource(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	slotIDs := uniqueIDs(req.SlotIDs)
+	slots, err := s.repo.GetAppointmentsByIDs(slotIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slots: %w", err)
+	}
+	if len(slots) != len(slotIDs) {
+		return nil, fmt.Errorf("%w: some of the proposed slots do not exist", ErrAppointmentNotFound)
+	}
+
+	now := time.Now()
+	for _, slot := range slots {
+		if err := checkFollowUpSlot(previous, slot, now); err != nil {
+			return nil, err
+		}
+		if req.AppointmentType != "" && !s.isAppointmentTypeCompatible(slot.AppointmentType, req.AppointmentType) {
+			return nil, fmt.Errorf("%w: appointment type '%s' is not compatible with slot %s type '%s'",
+				ErrInvalidInput, req.AppointmentType, slot.ID, slot.AppointmentType)
+		}
+	}
+
+	proposal := &models.FollowUpProposal{
+		DoctorID:              doctorID,
+		PatientID:             *previous.PatientID,
+		PreviousAppointmentID: previous.ID,
+		AppointmentType:       req.AppointmentType,
+		Message:               req.Message,
+		Status:                models.ProposalPending,
+	}
+	proposal.SetSlotIDs(slotIDs)
+
+	if err := s.repo.CreateFollowUpProposal(proposal); err != nil {
+		return nil, fmt.Errorf("failed to create follow-up proposal: %w", err)
+	}
+
+	s.logInfo("Follow-up proposed", map[string]interface{}{
+		"doctorID":              doctorID.String(),
+		"patientID":             proposal.PatientID.String(),
+		"previousAppointmentID": previous.ID.String(),
+		"proposalID":            proposal.ID.String(),
+		"slots":                 len(slots),
+	})
+
+	s.publishFollowUpProposal(previous, proposal, slots)
+
+	return s.proposalToResponse(proposal, slots, now), nil
+}
+
+// GetPatientFollowUpProposals - предложения повторного приема пациенту; пустой status - все
+func (s *appointmentService) GetPatientFollowUpProposals(patientID uuid.UUID, status string) ([]*models.FollowUpProposalResponse, error) {
+	if status != "" && status != models.ProposalPending && status != models.ProposalAccepted {
+		return nil, fmt.Errorf("%w: unknown proposal status '%s'", ErrInvalidInput, status)
+	}
+
+	proposals, err := s.repo.GetPatientFollowUpProposals(patientID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follow-up proposals: %w", err)
+	}
+
+	// Слоты всех предложений загружаются одним запросом
+	var slotIDs []uuid.UUID
+	for _, proposal := range proposals {
+		slotIDs = append(slotIDs, proposal.SlotIDs()...)
+	}
+	slots, err := s.repo.GetAppointmentsByIDs(uniqueIDs(slotIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slots: %w", err)
+	}
+	slotsByID := make(map[uuid.UUID]*models.Appointment, len(slots))
+	for _, slot := range slots {
+		slotsByID[slot.ID] = slot
+	}
+
+	now := time.Now()
+	response := make([]*models.FollowUpProposalResponse, 0, len(proposals))
+	for _, proposal := range proposals {
+		var proposed []*models.Appointment
+		for _, id := range proposal.SlotIDs() {
+			if slot, ok := slotsByID[id]; ok {
+				proposed = append(proposed, slot)
+			}
+		}
+		response = append(response, s.proposalToResponse(proposal, proposed, now))
+	}
+	return response, nil
+}
+
+// AcceptFollowUpProposal - пациент записывается на один из предложенных слотов.
+// Запись идет по обычным правилам (оплата, правила врача) и попадает в цепочку визитов
+func (s *appointmentService) AcceptFollowUpProposal(patientID, proposalID uuid.UUID, req *models.AcceptFollowUpRequest) (*models.AppointmentResponse, error) {
+	proposal, err := s.repo.GetFollowUpProposalByID(proposalID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: follow-up proposal: %v", ErrAppointmentNotFound, err)
+		}
+		return nil, fmt.Errorf("failed to get follow-up proposal: %w", err)
+	}
+	if proposal.PatientID != patientID {
+		return nil, fmt.Errorf("%w: proposal doesn't belong to this patient", ErrForbidden)
+	}
+	if proposal.Status != models.ProposalPending {
+		return nil, fmt.Errorf("%w: proposal is already %s", ErrInvalidStatus, proposal.Status)
+	}
+	if !proposal.HasSlot(req.SlotID) {
+		return nil, fmt.Errorf("%w: slot is not part of the proposal", ErrInvalidInput)
+	}
+
+	slot, err := s.getAppointment(req.SlotID)
+	if err != nil {
+		return nil, fmt.Errorf("slot: %w", err)
+	}
+	if slot.DoctorID != proposal.DoctorID || slot.IsGroup() {
+		return nil, fmt.Errorf("%w: slot can no longer be booked as a follow-up", ErrInvalidInput)
+	}
+
+	appointmentType := proposal.AppointmentType
+	if appointmentType == "" {
+		appointmentType = req.AppointmentType
+	}
+
+	// Предложение принимается до записи, чтобы параллельный выбор другого слота не записал пациента дважды
+	if err := s.repo.ClaimFollowUpProposal(proposal, slot.ID); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, fmt.Errorf("%w: proposal was already accepted", ErrInvalidStatus)
+		}
+		return nil, fmt.Errorf("failed to accept follow-up proposal: %w", err)
+	}
+
+	slot.PreviousAppointmentID = &proposal.PreviousAppointmentID
+	response, err := s.bookAppointment(patientID, slot, &models.BookAppointmentRequest{
+		AppointmentType: appointmentType,
+		PatientNotes:    req.PatientNotes,
+	})
+	if err != nil {
+		if reopenErr := s.repo.ReopenFollowUpProposal(proposal); reopenErr != nil {
+			s.logError("Failed to reopen follow-up proposal", map[string]interface{}{
+				"proposalID": proposal.ID.String(),
+				"error":      reopenErr.Error(),
+			})
+		}
+		return nil, err
+	}
+
+	s.logInfo("Follow-up proposal accepted", map[string]interface{}{
+		"patientID":     patientID.String(),
+		"proposalID":    proposal.ID.String(),
+		"appointmentID": slot.ID.String(),
+	})
+
+	return response, nil
+}
+
+// reopenFollowUpProposal - запись, сделанная по предложению повторного приема, сорвалась
+// (удержание до оплаты снято или пациент отменил запись): предложение снова ждет выбора пациента
+func (s *appointmentService) reopenFollowUpProposal(appointmentID uuid.UUID, patientID, previousAppointmentID *uuid.UUID) {
+	if patientID == nil || previousAppointmentID == nil {
+		return
+	}
+
+	reopened, err := s.repo.ReopenAcceptedFollowUp(appointmentID, *patientID, *previousAppointmentID)
+	if err != nil {
+		s.logError("Failed to reopen follow-up proposal", map[string]interface{}{
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return
+	}
+	if reopened {
+		s.logInfo("Follow-up proposal reopened", map[string]interface{}{
+			"patientID":     patientID.String(),
+			"appointmentID": appointmentID.String(),
+		})
+	}
+}
+
+// GetVisitChain - цепочка визитов записи: первый прием и все повторные, в порядке начала.
+// Доступна врачу и пациенту записи; пациент видит только свои записи цепочки
+func (s *appointmentService) GetVisitChain(userID uuid.UUID, role string, appointmentID uuid.UUID) ([]*models.AppointmentResponse, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	isDoctor := role == "doctor" && appointment.DoctorID == userID
+	isPatient := role == "patient" && s.isAppointmentPatient(appointment, userID)
+	if !isDoctor && !isPatient {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this user", ErrForbidden)
+	}
+
+	chain, err := s.repo.GetVisitChain(appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visit chain: %w", err)
+	}
+
+	response := make([]*models.AppointmentResponse, 0, len(chain))
+	for _, visit := range chain {
+		if isPatient && (visit.PatientID == nil || *visit.PatientID != userID) {
+			continue
+		}
+		response = append(response, s.appointmentToResponse(visit))
+	}
+	return response, nil
+}
+
+// followUpSource - прием врача, по итогам которого назначается повторный
+func (s *appointmentService) followUpSource(doctorID, appointmentID uuid.UUID) (*models.Appointment, error) {
+	previous, err := s.getDoctorAppointment(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous.IsGroup() || previous.PatientID == nil {
+		return nil, fmt.Errorf("%w: follow-up can be scheduled only for a personal appointment with a patient", ErrInvalidInput)
+	}
+
+	for _, status := range followUpSourceStatuses {
+		if previous.Status == status {
+			return previous, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: cannot schedule follow-up for appointment with status '%s'", ErrInvalidStatus, previous.Status)
+}
+
+// checkFollowUpSlot - слот врача подходит для повторного приема: личный, свободный и позже прошедшего приема
+func checkFollowUpSlot(previous, slot *models.Appointment, now time.Time) error {
+	if slot.DoctorID != previous.DoctorID {
+		return fmt.Errorf("%w: slot %s belongs to another doctor", ErrForbidden, slot.ID)
+	}
+	if slot.IsGroup() {
+		return fmt.Errorf("%w: slot %s is a group session", ErrInvalidInput, slot.ID)
+	}
+	if !slot.StartTime.After(now) || !slot.StartTime.After(previous.StartTime) {
+		return fmt.Errorf("%w: slot %s must start in the future and after the appointment", ErrInvalidInput, slot.ID)
+	}
+	if !slot.IsAvailable() {
+		return fmt.Errorf("%w: slot %s", ErrSlotTaken, slot.ID)
+	}
+	return nil
+}
+
+// publishFollowUpProposal - уведомляет пациента о предложенных слотах
+func (s *appointmentService) publishFollowUpProposal(previous *models.Appointment, proposal *models.FollowUpProposal, slots []*models.Appointment) {
+	if s.messages == nil {
+		return
+	}
+
+	event := newAppointmentEvent(models.EventFollowUpProposed, previous, &proposal.PatientID)
+	event.ProposalID = &proposal.ID
+	for _, slot := range slots {
+		event.AlternativeSlots = append(event.AlternativeSlots, models.SlotOption{
+			ID:        slot.ID,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+		})
+	}
+
+	s.sendEvent(event)
+}
+
+func (s *appointmentService) proposalToResponse(proposal *models.FollowUpProposal, slots []*models.Appointment, now time.Time) *models.FollowUpProposalResponse {
+	response := &models.FollowUpProposalResponse{
+		ID:                    proposal.ID,
+		DoctorID:              proposal.DoctorID,
+		PatientID:             proposal.PatientID,
+		PreviousAppointmentID: proposal.PreviousAppointmentID,
+		AppointmentType:       proposal.AppointmentType,
+		Message:               proposal.Message,
+		Status:                proposal.Status,
+		AppointmentID:         proposal.AppointmentID,
+		Slots:                 make([]models.FollowUpSlot, 0, len(slots)),
+		CreatedAt:             proposal.CreatedAt,
+	}
+	for _, slot := range slots {
+		response.Slots = append(response.Slots, models.FollowUpSlot{
+			ID:              slot.ID,
+			StartTime:       slot.StartTime,
+			EndTime:         slot.EndTime,
+			AppointmentType: slot.AppointmentType,
+			Available:       proposal.Status == models.ProposalPending && slot.IsAvailable() && slot.StartTime.After(now),
+		})
+	}
+	return response
+}
+
+// uniqueIDs - ID без повторов в исходном порядке
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// slotsRepository - расписание, слоты и исключения одного врача в памяти
+type slotsRepository struct {
+	repository.AppointmentRepository
+
+	schedule   *models.DoctorSchedule
+	slots      []*models.Appointment
+	exceptions []*models.ScheduleException
+}
+
+func (r *slotsRepository) GetScheduleByID(id uuid.UUID) (*models.DoctorSchedule, error) {
+	return r.schedule, nil
+}
+
+func (r *slotsRepository) GetDoctorSchedules(doctorID uuid.UUID) ([]*models.DoctorSchedule, error) {
+	return []*models.DoctorSchedule{r.schedule}, nil
+}
+
+func (r *slotsRepository) GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicy, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *slotsRepository) GetDoctorExceptions(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.ScheduleException, error) {
+	return r.exceptions, nil
+}
+
+func (r *slotsRepository) CreateException(exception *models.ScheduleException) error {
+	exception.ID = uuid.New()
+	r.exceptions = append(r.exceptions, exception)
+	return nil
+}
+
+func (r *slotsRepository) GetDoctorBusyTime(doctorID uuid.UUID, from, to time.Time) ([]*models.ScheduleException, error) {
+	return nil, nil
+}
+
+func (r *slotsRepository) CheckSlotExists(doctorID uuid.UUID, startTime, endTime time.Time) (bool, error) {
+	for _, slot := range r.slots {
+		if slot.Status != models.StatusCanceled && slot.StartTime.Before(endTime) && slot.EndTime.After(startTime) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *slotsRepository) CreateAppointment(appointment *models.Appointment) error {
+	r.slots = append(r.slots, appointment)
+	return nil
+}
+
+func (r *slotsRepository) CloseAvailableSlots(doctorID uuid.UUID, startDate, endDate time.Time, canceledBy uuid.UUID, reason string) (int64, error) {
+	var closed int64
+	for _, slot := range r.slots {
+		if slot.Status == models.StatusAvailable && !slot.StartTime.Before(startDate) && slot.StartTime.Before(endDate) {
+			slot.Status = models.StatusCanceled
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+func (r *slotsRepository) GetDoctorBookedAppointments(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error) {
+	return nil, nil
+}
+
+func (r *slotsRepository) GetAvailableSlots(doctorID uuid.UUID, startDate, endDate time.Time) ([]*models.Appointment, error) {
+	return nil, nil
+}
+
+func newSlotsFixture() (*slotsRepository, AppointmentService, string) {
+	schedule := newTestSchedule("UTC", "09:00", "11:00", 1, 2, 3, 4, 5, 6, 7)
+	schedule.DoctorID = uuid.New()
+	schedule.SlotDuration = 30
+	schedule.SlotCapacity = 1
+	repo := &slotsRepository{schedule: schedule}
+	day := time.Now().UTC().AddDate(0, 0, 10).Format("2006-01-02")
+	return repo, NewAppointmentService(repo, nil, nil, Options{}), day
+}
+
+func TestGenerateSlotsAbortsOnConflictUnlessSkipping(t *testing.T) {
+	repo, svc, day := newSlotsFixture()
+	doctorID, scheduleID := repo.schedule.DoctorID, repo.schedule.ID
+
+	first, err := svc.GenerateSlots(doctorID, scheduleID, &models.GenerateSlotsRequest{StartDate: day, EndDate: day})
+	if err != nil || first.SlotsCreated != 4 {
+		t.Fatalf("expected 4 slots, got %+v, %v", first, err)
+	}
+
+	// По умолчанию повторная генерация того же дня отменяется целиком
+	if _, err := svc.GenerateSlots(doctorID, scheduleID, &models.GenerateSlotsRequest{StartDate: day, EndDate: day}); err == nil {
+		t.Fatal("expected conflict error without skip_conflicts")
+	}
+	if len(repo.slots) != 4 {
+		t.Fatalf("conflicting generation must not create slots, got %d", len(repo.slots))
+	}
+
+	skipped, err := svc.GenerateSlots(doctorID, scheduleID, &models.GenerateSlotsRequest{StartDate: day, EndDate: day, SkipConflicts: true})
+	if err != nil || skipped.SlotsCreated != 0 || skipped.SlotsSkipped != 4 {
+		t.Fatalf("expected all slots to be skipped, got %+v, %v", skipped, err)
+	}
+}
+
+func TestGenerateSlotsKeepsBulkCanceledPeriodClosed(t *testing.T) {
+	repo, svc, day := newSlotsFixture()
+	doctorID, scheduleID := repo.schedule.DoctorID, repo.schedule.ID
+
+	if _, err := svc.GenerateSlots(doctorID, scheduleID, &models.GenerateSlotsRequest{StartDate: day, EndDate: day}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	canceled, err := svc.DoctorCancelAppointments(doctorID, &models.DoctorBulkCancelRequest{StartDate: day, EndDate: day, Reason: "Больничный"})
+	if err != nil || canceled.ClosedSlots != 4 || canceled.ExceptionID == uuid.Nil {
+		t.Fatalf("expected 4 closed slots and a closure exception, got %+v, %v", canceled, err)
+	}
+
+	regenerated, err := svc.GenerateSlots(doctorID, scheduleID, &models.GenerateSlotsRequest{StartDate: day, EndDate: day, SkipConflicts: true})
+	if err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	if regenerated.SlotsCreated != 0 {
+		t.Fatalf("closed period must stay without bookable slots, %d created", regenerated.SlotsCreated)
+	}
+}
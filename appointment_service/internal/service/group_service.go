@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// === ГРУППОВЫЕ ЗАНЯТИЯ ===
+
+// checkGroupPricing - групповые занятия доступны только врачам без оплаты приема:
+// иначе места на занятии раздавались бы бесплатно
+func (s *appointmentService) checkGroupPricing(doctorID uuid.UUID) error {
+	price, err := s.bookingPrice(doctorID)
+	if err != nil {
+		return err
+	}
+	if price > 0 {
+		return ErrPaidGroupSession
+	}
+	return nil
+}
+
+// joinGroupSession - занимает место пациента на групповом занятии.
+// Оплата мест пока не поддерживается: место занимается сразу, без удержания до оплаты,
+// поэтому к врачу с платным приемом сюда не попадают (см. checkGroupPricing)
+func (s *appointmentService) joinGroupSession(patientID uuid.UUID, appointment *models.Appointment, appointmentType, notes string, limits *repository.BookingLimits) (*models.AppointmentResponse, error) {
+	participant := &models.AppointmentParticipant{
+		PatientID:       patientID,
+		AppointmentType: appointmentType,
+		PatientNotes:    notes,
+	}
+
+	session, err := s.repo.JoinGroupSession(appointment.ID, participant, limits)
+	if err != nil {
+		if policyErr := bookingLimitsError(limits, err); policyErr != nil {
+			return nil, policyErr
+		}
+		switch {
+		case errors.Is(err, repository.ErrSlotTaken):
+			return nil, ErrSlotTaken
+		case errors.Is(err, repository.ErrAlreadyParticipant):
+			return nil, ErrAlreadyParticipant
+		}
+		return nil, fmt.Errorf("failed to book group session: %w", err)
+	}
+
+	// Комната встречи общая для всех участников и создается для первого, кто выбрал онлайн
+	if appointmentType == "online" && session.MeetingID == nil && s.options.Meetings != nil {
+		if err := s.createMeetingRoom(session); err == nil {
+			err = s.repo.SaveMeeting(session)
+		}
+		if err != nil {
+			s.logError("Failed to create group meeting room", map[string]interface{}{
+				"appointmentID": session.ID.String(),
+				"error":         err.Error(),
+			})
+		}
+	}
+
+	s.logInfo("Seat booked in group session", map[string]interface{}{
+		"patientID":     patientID.String(),
+		"appointmentID": session.ID.String(),
+		"seatsTaken":    session.SeatsTaken,
+		"capacity":      session.Capacity,
+	})
+
+	s.publishEvent(models.EventAppointmentBooked, session, &patientID)
+
+	return s.appointmentToResponse(session), nil
+}
+
+// leaveGroupSession - освобождает место пациента на групповом занятии с учетом срока отмены врача
+func (s *appointmentService) leaveGroupSession(patientID uuid.UUID, appointment *models.Appointment) error {
+	policy, err := s.bookingPolicy(appointment.DoctorID)
+	if err != nil {
+		return err
+	}
+	if err := checkCancelCutoff(policy, appointment.StartTime, time.Now()); err != nil {
+		return err
+	}
+
+	session, err := s.repo.LeaveGroupSession(appointment.ID, patientID)
+	if err != nil {
+		var transitionErr *models.IllegalTransitionError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return fmt.Errorf("%w: patient is not booked for this session", ErrForbidden)
+		case errors.Is(err, repository.ErrSlotTaken), errors.As(err, &transitionErr):
+			return fmt.Errorf("%w: cannot cancel a seat in session with status '%s'", ErrInvalidStatus, appointment.Status)
+		}
+		return fmt.Errorf("failed to cancel group session seat: %w", err)
+	}
+
+	s.logInfo("Seat canceled in group session", map[string]interface{}{
+		"patientID":     patientID.String(),
+		"appointmentID": session.ID.String(),
+		"seatsTaken":    session.SeatsTaken,
+	})
+
+	s.publishEvent(models.EventAppointmentCanceled, session, &patientID)
+
+	return nil
+}
+
+// GetParticipants - пациенты, записанные на групповое занятие врача
+func (s *appointmentService) GetParticipants(doctorID, appointmentID uuid.UUID) ([]*models.ParticipantResponse, error) {
+	appointment, err := s.getDoctorAppointment(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := s.repo.GetParticipants(appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participants: %w", err)
+	}
+
+	response := make([]*models.ParticipantResponse, len(participants))
+	for i, participant := range participants {
+		response[i] = &models.ParticipantResponse{
+			PatientID:       participant.PatientID,
+			AppointmentType: participant.AppointmentType,
+			PatientNotes:    participant.PatientNotes,
+			BookedAt:        participant.CreatedAt,
+		}
+	}
+	return response, nil
+}
+
+// participantIDs - пациенты группового занятия для рассылки событий
+func (s *appointmentService) participantIDs(appointment *models.Appointment) []uuid.UUID {
+	participants, err := s.repo.GetParticipants(appointment.ID)
+	if err != nil {
+		s.logError("Failed to get group session participants", map[string]interface{}{
+			"appointmentID": appointment.ID.String(),
+			"error":         err.Error(),
+		})
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(participants))
+	for i, participant := range participants {
+		ids[i] = participant.PatientID
+	}
+	return ids
+}
+
+// isAppointmentPatient - пациент записан на прием: лично или местом на групповом занятии
+func (s *appointmentService) isAppointmentPatient(appointment *models.Appointment, patientID uuid.UUID) bool {
+	if appointment.PatientID != nil && *appointment.PatientID == patientID {
+		return true
+	}
+	if !appointment.IsGroup() {
+		return false
+	}
+	_, err := s.repo.GetParticipant(appointment.ID, patientID)
+	return err == nil
+}
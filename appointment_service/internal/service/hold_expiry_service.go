@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/printprince/vitalem/logger_service/pkg/logger"
+)
+
+// HoldExpiryService - фоновое освобождение слотов, удерживаемых до оплаты, когда время оплаты истекло
+type HoldExpiryService struct {
+	service  AppointmentService
+	logger   *logger.Client
+	interval time.Duration
+}
+
+// NewHoldExpiryService - создание планировщика освобождения истекших удержаний
+func NewHoldExpiryService(appointmentService AppointmentService, loggerClient *logger.Client, interval time.Duration) *HoldExpiryService {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	return &HoldExpiryService{
+		service:  appointmentService,
+		logger:   loggerClient,
+		interval: interval,
+	}
+}
+
+// Start - запускает планировщик, работает до отмены контекста
+func (h *HoldExpiryService) Start(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	h.Run()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Run()
+		}
+	}
+}
+
+// Run - один проход освобождения истекших удержаний
+func (h *HoldExpiryService) Run() {
+	released, err := h.service.ReleaseExpiredHolds()
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Error("Failed to release expired holds", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	if released > 0 && h.logger != nil {
+		h.logger.Info("Expired slot holds released", map[string]interface{}{
+			"released": released,
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/meeting"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+)
+
+const (
+	// defaultMeetingJoinBefore - за сколько до начала приема можно войти во встречу
+	defaultMeetingJoinBefore = 15 * time.Minute
+	// defaultMeetingJoinAfter - сколько после окончания приема ссылка еще действует
+	defaultMeetingJoinAfter = 30 * time.Minute
+)
+
+// assignMeeting - создает комнату для онлайн-записи перед ее сохранением.
+// Сбой провайдера не мешает записи: комната будет создана при первом запросе ссылки на вход
+func (s *appointmentService) assignMeeting(appointment *models.Appointment) {
+	if !appointment.IsOnline() || s.options.Meetings == nil {
+		return
+	}
+
+	if err := s.createMeetingRoom(appointment); err != nil {
+		s.logError("Failed to create meeting room", map[string]interface{}{
+			"appointmentID": appointment.ID.String(),
+			"error":         err.Error(),
+		})
+	}
+}
+
+func (s *appointmentService) createMeetingRoom(appointment *models.Appointment) error {
+	room, err := s.options.Meetings.CreateRoom(context.Background(), meeting.RoomRequest{
+		AppointmentID: appointment.ID,
+		StartTime:     appointment.StartTime,
+		EndTime:       appointment.EndTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	appointment.SetMeeting(room.ID, room.URL)
+	return nil
+}
+
+// GetMeetingJoin - личная ссылка врача или пациента на вход во встречу онлайн-приема.
+// Ссылка действует только от MeetingJoinBefore до начала до MeetingJoinAfter после окончания приема
+func (s *appointmentService) GetMeetingJoin(userID uuid.UUID, role string, appointmentID uuid.UUID) (*models.MeetingJoinResponse, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	isDoctor := role == "doctor" && appointment.DoctorID == userID
+	isPatient := role == "patient" && s.isAppointmentPatient(appointment, userID)
+	if !isDoctor && !isPatient {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this user", ErrForbidden)
+	}
+
+	// Групповое занятие формата "both" получает комнату, когда записывается первый онлайн-участник
+	if !appointment.IsOnline() && appointment.MeetingID == nil {
+		return nil, fmt.Errorf("%w: appointment is not online", ErrInvalidStatus)
+	}
+	if !appointment.IsActive() {
+		return nil, fmt.Errorf("%w: cannot join meeting of appointment with status '%s'", ErrInvalidStatus, appointment.Status)
+	}
+	if s.options.Meetings == nil {
+		return nil, fmt.Errorf("%w: video meetings are not configured", ErrMeetingUnavailable)
+	}
+
+	validFrom, validUntil := s.meetingWindow(appointment)
+	if time.Now().After(validUntil) {
+		return nil, fmt.Errorf("%w: meeting is over", ErrInvalidStatus)
+	}
+
+	if appointment.MeetingID == nil {
+		if err := s.createMeetingRoom(appointment); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMeetingUnavailable, err)
+		}
+		if err := s.repo.SaveMeeting(appointment); err != nil {
+			return nil, fmt.Errorf("failed to save meeting: %w", err)
+		}
+	}
+
+	name := "Пациент"
+	if isDoctor {
+		name = "Врач"
+	}
+
+	joinURL, err := s.options.Meetings.JoinURL(context.Background(), meeting.JoinRequest{
+		RoomID: *appointment.MeetingID,
+		Participant: meeting.Participant{
+			UserID:    userID,
+			Name:      name,
+			Moderator: isDoctor,
+		},
+		NotBefore: validFrom,
+		ExpiresAt: validUntil,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMeetingUnavailable, err)
+	}
+
+	return &models.MeetingJoinResponse{
+		AppointmentID: appointment.ID,
+		MeetingID:     *appointment.MeetingID,
+		JoinURL:       joinURL,
+		Moderator:     isDoctor,
+		ValidFrom:     validFrom,
+		ValidUntil:    validUntil,
+	}, nil
+}
+
+// meetingWindow - интервал, в котором действует ссылка на вход во встречу
+func (s *appointmentService) meetingWindow(appointment *models.Appointment) (time.Time, time.Time) {
+	before := s.options.MeetingJoinBefore
+	if before <= 0 {
+		before = defaultMeetingJoinBefore
+	}
+	after := s.options.MeetingJoinAfter
+	if after <= 0 {
+		after = defaultMeetingJoinAfter
+	}
+	return appointment.StartTime.Add(-before), appointment.EndTime.Add(after)
+}
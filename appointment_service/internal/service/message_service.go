@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/logger_service/pkg/logger"
+	"github.com/streadway/amqp"
+)
+
+// MessageService представляет сервис для публикации событий записей
+type MessageService interface {
+	// PublishAppointmentEvent публикует событие записи (EventType - ключ маршрутизации)
+	PublishAppointmentEvent(ctx context.Context, event *models.AppointmentEvent) error
+	// Close закрывает соединение с RabbitMQ
+	Close() error
+}
+
+// messageService реализация сервиса сообщений
+type messageService struct {
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	exchange string
+	logger   *logger.Client
+}
+
+// NewMessageService создает новый сервис сообщений
+func NewMessageService(rabbitMQURL string, exchange string, logger *logger.Client) (MessageService, error) {
+	// Подключаемся к RabbitMQ
+	conn, err := amqp.Dial(rabbitMQURL)
+	if err != nil {
+		if logger != nil {
+			logger.Error("Failed to connect to RabbitMQ", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	// Создаем канал
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		if logger != nil {
+			logger.Error("Failed to open RabbitMQ channel", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	// Объявляем exchange (параметры совпадают с identity_service и NotificationService)
+	err = channel.ExchangeDeclare(
+		exchange, // имя
+		"topic",  // тип
+		true,     // durable
+		false,    // auto-deleted
+		false,    // internal
+		false,    // no-wait
+		nil,      // аргументы
+	)
+	if err != nil {
+		channel.Close()
+		conn.Close()
+		if logger != nil {
+			logger.Error("Failed to declare exchange", map[string]interface{}{
+				"error":    err.Error(),
+				"exchange": exchange,
+			})
+		}
+		return nil, fmt.Errorf("failed to declare exchange: %w", err)
+	}
+
+	if logger != nil {
+		logger.Info("RabbitMQ connection established", map[string]interface{}{
+			"exchange": exchange,
+		})
+	} else {
+		log.Printf("RabbitMQ connection established: %s", exchange)
+	}
+
+	return &messageService{
+		conn:     conn,
+		channel:  channel,
+		exchange: exchange,
+		logger:   logger,
+	}, nil
+}
+
+// PublishAppointmentEvent публикует событие записи, EventType используется как routing key
+func (s *messageService) PublishAppointmentEvent(ctx context.Context, event *models.AppointmentEvent) error {
+	// Кодируем событие в JSON
+	body, err := json.Marshal(event)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to marshal appointment event", map[string]interface{}{
+				"error":         err.Error(),
+				"eventType":     event.EventType,
+				"appointmentID": event.AppointmentID.String(),
+			})
+		}
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// Публикуем сообщение
+	err = s.channel.Publish(
+		s.exchange,      // exchange
+		event.EventType, // routing key
+		false,           // mandatory
+		false,           // immediate
+		amqp.Publishing{
+			ContentType:  "application/json",
+			Body:         body,
+			DeliveryMode: amqp.Persistent, // сообщение будет сохранено при перезапуске RabbitMQ
+			Timestamp:    event.OccurredAt,
+		})
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Failed to publish appointment event", map[string]interface{}{
+				"error":         err.Error(),
+				"eventType":     event.EventType,
+				"appointmentID": event.AppointmentID.String(),
+			})
+		}
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("Appointment event published", map[string]interface{}{
+			"eventType":     event.EventType,
+			"appointmentID": event.AppointmentID.String(),
+			"doctorID":      event.DoctorID.String(),
+		})
+	} else {
+		log.Printf("Appointment event published: %s, %s", event.EventType, event.AppointmentID)
+	}
+
+	return nil
+}
+
+// Close закрывает соединение с RabbitMQ
+func (s *messageService) Close() error {
+	if s.channel != nil {
+		if err := s.channel.Close(); err != nil {
+			if s.logger != nil {
+				s.logger.Error("Failed to close RabbitMQ channel", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return fmt.Errorf("failed to close channel: %w", err)
+		}
+	}
+
+	if s.conn != nil {
+		if err := s.conn.Close(); err != nil {
+			if s.logger != nil {
+				s.logger.Error("Failed to close RabbitMQ connection", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return fmt.Errorf("failed to close connection: %w", err)
+		}
+	}
+
+	if s.logger != nil {
+		s.logger.Info("RabbitMQ connection closed", nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/payment"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+const (
+	// defaultHoldDuration - время на оплату, если в настройках не задано
+	defaultHoldDuration = 15 * time.Minute
+	// paymentCurrency - валюта платежей за прием
+	paymentCurrency = "KZT"
+)
+
+// PriceResolver - источник цены приема врача (specialist_service)
+type PriceResolver interface {
+	DoctorPrice(ctx context.Context, doctorID uuid.UUID) (float64, error)
+}
+
+// bookingPrice - цена записи к врачу; 0, если оплата не настроена
+func (s *appointmentService) bookingPrice(doctorID uuid.UUID) (float64, error) {
+	if s.options.Payments == nil || s.options.Prices == nil {
+		return 0, nil
+	}
+
+	price, err := s.options.Prices.DoctorPrice(context.Background(), doctorID)
+	if err != nil {
+		s.logError("Failed to get doctor price", map[string]interface{}{
+			"doctorID": doctorID.String(),
+			"error":    err.Error(),
+		})
+		return 0, fmt.Errorf("%w: failed to get doctor price: %v", ErrPaymentUnavailable, err)
+	}
+
+	return price, nil
+}
+
+// holdDeadline - до какого момента удерживать запись, если оплата создается сейчас
+func (s *appointmentService) holdDeadline() time.Time {
+	holdDuration := s.options.HoldDuration
+	if holdDuration <= 0 {
+		holdDuration = defaultHoldDuration
+	}
+	return time.Now().Add(holdDuration)
+}
+
+// holdAppointment - первая фаза платной записи: слот удерживается за пациентом на время оплаты
+// и у провайдера создается платеж. Запись подтверждается уведомлением об оплате
+func (s *appointmentService) holdAppointment(patientID uuid.UUID, appointment *models.Appointment, appointmentType, notes string, price float64, limits *repository.BookingLimits) (*models.AppointmentResponse, error) {
+	heldUntil := s.holdDeadline()
+	if err := appointment.Hold(patientID, appointmentType, notes, price, heldUntil); err != nil {
+		return nil, ErrSlotTaken
+	}
+
+	if err := s.repo.HoldSlot(appointment, limits); err != nil {
+		if policyErr := bookingLimitsError(limits, err); policyErr != nil {
+			return nil, policyErr
+		}
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, ErrSlotTaken
+		}
+		return nil, fmt.Errorf("failed to hold slot: %w", err)
+	}
+
+	return s.requestPayment(patientID, appointment, price, heldUntil)
+}
+
+// requestPayment - создает у провайдера платеж за удерживаемую запись;
+// если провайдер недоступен, удержание снимается
+func (s *appointmentService) requestPayment(patientID uuid.UUID, appointment *models.Appointment, price float64, heldUntil time.Time) (*models.AppointmentResponse, error) {
+	created, err := s.options.Payments.CreatePayment(context.Background(), payment.CreatePaymentRequest{
+		AppointmentID: appointment.ID,
+		PatientID:     patientID,
+		Amount:        price,
+		Currency:      paymentCurrency,
+		Description:   appointment.Title,
+		ExpiresAt:     heldUntil,
+	})
+	if err != nil {
+		s.logError("Failed to create payment, releasing hold", map[string]interface{}{
+			"patientID":     patientID.String(),
+			"appointmentID": appointment.ID.String(),
+			"error":         err.Error(),
+		})
+		if releaseErr := s.releaseHold(appointment, nil, "system", "payment creation failed", "failed"); releaseErr != nil {
+			s.logError("Failed to release hold", map[string]interface{}{
+				"appointmentID": appointment.ID.String(),
+				"error":         releaseErr.Error(),
+			})
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPaymentUnavailable, err)
+	}
+
+	appointment.PaymentID = &created.ID
+	appointment.PaymentURL = created.PaymentURL
+	record := &models.AppointmentPayment{
+		PaymentID:     created.ID,
+		AppointmentID: appointment.ID,
+		PatientID:     patientID,
+		Amount:        price,
+		Status:        "pending",
+	}
+	if err := s.repo.SavePayment(appointment, record); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, ErrSlotTaken
+		}
+		return nil, fmt.Errorf("failed to save payment: %w", err)
+	}
+
+	s.logInfo("Slot held pending payment", map[string]interface{}{
+		"patientID":     patientID.String(),
+		"appointmentID": appointment.ID.String(),
+		"paymentID":     created.ID,
+		"amount":        price,
+		"heldUntil":     heldUntil.Format(time.RFC3339),
+	})
+
+	return s.appointmentToResponse(appointment), nil
+}
+
+// HandlePaymentConfirmation - вторая фаза: уведомление провайдера о результате оплаты.
+// Повторные уведомления об уже обработанном платеже безопасны
+func (s *appointmentService) HandlePaymentConfirmation(body []byte, header http.Header) error {
+	if s.options.Payments == nil {
+		return fmt.Errorf("%w: payments are not configured", ErrPaymentUnavailable)
+	}
+
+	confirmation, err := s.options.Payments.ParseConfirmation(body, header)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			return fmt.Errorf("%w: %v", ErrForbidden, err)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	record, err := s.repo.GetPayment(confirmation.PaymentID)
+	if err != nil {
+		return fmt.Errorf("%w: payment %s: %v", ErrAppointmentNotFound, confirmation.PaymentID, err)
+	}
+
+	appointment, err := s.repo.GetAppointmentByID(record.AppointmentID)
+	if err != nil {
+		return fmt.Errorf("%w: payment %s: %v", ErrAppointmentNotFound, confirmation.PaymentID, err)
+	}
+
+	// Слот все еще принадлежит этому платежу: после снятия удержания ссылка на платеж очищается,
+	// и слот может удерживаться или быть записан за другим пациентом
+	ownsSlot := appointment.PaymentID != nil && *appointment.PaymentID == record.PaymentID &&
+		appointment.PatientID != nil && *appointment.PatientID == record.PatientID
+
+	if confirmation.Status == payment.StatusFailed {
+		if !ownsSlot || appointment.Status != models.StatusHeld {
+			return nil
+		}
+		return s.releaseHold(appointment, nil, "system", "payment failed", "failed")
+	}
+
+	if !ownsSlot {
+		if record.Status == "paid" || record.Status == "refund_required" {
+			return nil
+		}
+		// Удержание уже снято, слот мог занять другой пациент - деньги нужно вернуть
+		if err := s.repo.UpdatePaymentStatus(record.PaymentID, "refund_required"); err != nil {
+			return fmt.Errorf("failed to mark payment for refund: %w", err)
+		}
+		s.logError("Payment received for released hold, refund required", map[string]interface{}{
+			"appointmentID": appointment.ID.String(),
+			"patientID":     record.PatientID.String(),
+			"paymentID":     confirmation.PaymentID,
+		})
+		return nil
+	}
+
+	if appointment.Status != models.StatusHeld {
+		// Уже подтверждено ранее
+		return nil
+	}
+
+	// Оплата пришла - подтверждаем, даже если планировщик еще не успел снять истекшее удержание
+	if err := appointment.ConfirmPayment(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+	s.assignMeeting(appointment)
+
+	if err := s.repo.ConfirmPayment(appointment, record); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			// Удержание сняли параллельно; повторное уведомление провайдера отметит возврат
+			return fmt.Errorf("%w: appointment was changed concurrently", ErrInvalidStatus)
+		}
+		return fmt.Errorf("failed to confirm appointment: %w", err)
+	}
+
+	s.logInfo("Payment confirmed, appointment booked", map[string]interface{}{
+		"appointmentID": appointment.ID.String(),
+		"paymentID":     confirmation.PaymentID,
+	})
+
+	s.publishEvent(models.EventAppointmentBooked, appointment, appointment.PatientID)
+
+	return nil
+}
+
+// ReleaseExpiredHolds - освобождает слоты, оплата которых не пришла вовремя
+func (s *appointmentService) ReleaseExpiredHolds() (int, error) {
+	holds, err := s.repo.GetExpiredHolds(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired holds: %w", err)
+	}
+
+	released := 0
+	for _, appointment := range holds {
+		if err := s.releaseHold(appointment, nil, "system", "hold expired", "expired"); err != nil {
+			// Оплата могла прийти параллельно - тогда запись уже подтверждена
+			continue
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+// releaseHold - снимает удержание слота и сохраняет итоговый статус платежа
+func (s *appointmentService) releaseHold(appointment *models.Appointment, actorID *uuid.UUID, role, reason, paymentStatus string) error {
+	patientID := appointment.PatientID
+	paymentID := appointment.PaymentID
+	previousAppointmentID := appointment.PreviousAppointmentID
+	if patientID == nil {
+		return fmt.Errorf("%w: appointment is not held by a patient", ErrInvalidStatus)
+	}
+
+	if err := appointment.ReleaseHold(actorID, role, reason); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+	appointment.PaymentStatus = paymentStatus
+
+	if err := s.repo.ReleaseHold(appointment, *patientID, paymentID); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return fmt.Errorf("%w: appointment was changed concurrently", ErrInvalidStatus)
+		}
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	metadata := map[string]interface{}{
+		"appointmentID": appointment.ID.String(),
+		"patientID":     patientID.String(),
+		"reason":        reason,
+	}
+	if paymentID != nil {
+		metadata["paymentID"] = *paymentID
+	}
+	s.logInfo("Slot hold released", metadata)
+
+	s.reopenFollowUpProposal(appointment.ID, patientID, previousAppointmentID)
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/payment"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// paymentRepository - слот и история платежей в памяти. ConfirmPayment и ReleaseHold
+// повторяют условия репозитория: слот меняется, только если он удерживается по этому платежу
+type paymentRepository struct {
+	repository.AppointmentRepository
+
+	slot     models.Appointment
+	payments map[string]*models.AppointmentPayment
+	reopened []uuid.UUID // записи, по которым предложение повторного приема вернулось к выбору
+}
+
+func (r *paymentRepository) GetAppointmentByID(id uuid.UUID) (*models.Appointment, error) {
+	if id != r.slot.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	slot := r.slot
+	return &slot, nil
+}
+
+func (r *paymentRepository) GetPayment(paymentID string) (*models.AppointmentPayment, error) {
+	record, ok := r.payments[paymentID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *paymentRepository) UpdatePaymentStatus(paymentID, status string) error {
+	r.payments[paymentID].Status = status
+	return nil
+}
+
+func (r *paymentRepository) ConfirmPayment(appointment *models.Appointment, record *models.AppointmentPayment) error {
+	if !r.heldBy(record.PatientID, &record.PaymentID) {
+		return repository.ErrSlotTaken
+	}
+	r.slot = *appointment
+	r.payments[record.PaymentID].Status = "paid"
+	return nil
+}
+
+func (r *paymentRepository) ReleaseHold(appointment *models.Appointment, patientID uuid.UUID, paymentID *string) error {
+	if !r.heldBy(patientID, paymentID) {
+		return repository.ErrSlotTaken
+	}
+	r.slot = *appointment
+	if paymentID != nil {
+		r.payments[*paymentID].Status = appointment.PaymentStatus
+	}
+	return nil
+}
+
+func (r *paymentRepository) ReopenAcceptedFollowUp(appointmentID, patientID, previousAppointmentID uuid.UUID) (bool, error) {
+	r.reopened = append(r.reopened, appointmentID)
+	return true, nil
+}
+
+func (r *paymentRepository) heldBy(patientID uuid.UUID, paymentID *string) bool {
+	if r.slot.Status != models.StatusHeld || r.slot.PatientID == nil || *r.slot.PatientID != patientID {
+		return false
+	}
+	if paymentID == nil {
+		return r.slot.PaymentID == nil
+	}
+	return r.slot.PaymentID != nil && *r.slot.PaymentID == *paymentID
+}
+
+// TestLatePaymentOfReleasedHoldDoesNotTouchNextHold - пациент A не успел оплатить, удержание сняли,
+// слот удерживает пациент B. Позднее уведомление по платежу A не должно ни подтвердить,
+// ни снять удержание B: оплата A помечается к возврату
+func TestLatePaymentOfReleasedHoldDoesNotTouchNextHold(t *testing.T) {
+	patientA, patientB := uuid.New(), uuid.New()
+	paymentA, paymentB := "pay_a", "pay_b"
+	heldUntil := time.Now().Add(10 * time.Minute)
+
+	slot := models.Appointment{
+		ID:              uuid.New(),
+		DoctorID:        uuid.New(),
+		StartTime:       time.Now().Add(48 * time.Hour),
+		EndTime:         time.Now().Add(48*time.Hour + 30*time.Minute),
+		Status:          models.StatusHeld,
+		PatientID:       &patientB,
+		AppointmentType: "offline",
+		Capacity:        1,
+		HeldUntil:       &heldUntil,
+		PaymentID:       &paymentB,
+		PaymentAmount:   5000,
+		PaymentStatus:   "pending",
+	}
+	repo := &paymentRepository{
+		slot: slot,
+		payments: map[string]*models.AppointmentPayment{
+			paymentA: {PaymentID: paymentA, AppointmentID: slot.ID, PatientID: patientA, Amount: 5000, Status: "expired"},
+			paymentB: {PaymentID: paymentB, AppointmentID: slot.ID, PatientID: patientB, Amount: 5000, Status: "pending"},
+		},
+	}
+	provider := payment.NewFakeProvider("test-secret")
+	svc := NewAppointmentService(repo, nil, nil, Options{Payments: provider})
+
+	notify := func(paymentID, status string) error {
+		body := []byte(`{"payment_id":"` + paymentID + `","status":"` + status + `"}`)
+		header := http.Header{}
+		header.Set(payment.FakeSignatureHeader, provider.Sign(body))
+		return svc.HandlePaymentConfirmation(body, header)
+	}
+
+	if err := notify(paymentA, payment.StatusFailed); err != nil {
+		t.Fatalf("failed notification for released hold: %v", err)
+	}
+	if repo.slot.Status != models.StatusHeld || *repo.slot.PatientID != patientB {
+		t.Fatalf("failed payment of A released hold of B: status %s", repo.slot.Status)
+	}
+
+	if err := notify(paymentA, payment.StatusSucceeded); err != nil {
+		t.Fatalf("late payment of A: %v", err)
+	}
+	if repo.slot.Status != models.StatusHeld || *repo.slot.PatientID != patientB {
+		t.Fatalf("payment of A changed hold of B: status %s", repo.slot.Status)
+	}
+	if status := repo.payments[paymentA].Status; status != "refund_required" {
+		t.Fatalf("payment of A status = %s, want refund_required", status)
+	}
+
+	if err := notify(paymentB, payment.StatusSucceeded); err != nil {
+		t.Fatalf("payment of B: %v", err)
+	}
+	if repo.slot.Status != models.StatusBooked || *repo.slot.PatientID != patientB {
+		t.Fatalf("payment of B did not book the slot: status %s", repo.slot.Status)
+	}
+	if status := repo.payments[paymentB].Status; status != "paid" {
+		t.Fatalf("payment of B status = %s, want paid", status)
+	}
+}
+
+// TestFailedPaymentReopensFollowUpProposal - удержание слота, выбранного из предложения
+// повторного приема, снято: предложение снова ждет выбора пациента
+func TestFailedPaymentReopensFollowUpProposal(t *testing.T) {
+	patientID, previousID := uuid.New(), uuid.New()
+	paymentID := "pay_follow_up"
+	heldUntil := time.Now().Add(10 * time.Minute)
+
+	slot := models.Appointment{
+		ID:                    uuid.New(),
+		DoctorID:              uuid.New(),
+		StartTime:             time.Now().Add(48 * time.Hour),
+		EndTime:               time.Now().Add(48*time.Hour + 30*time.Minute),
+		Status:                models.StatusHeld,
+		PatientID:             &patientID,
+		PreviousAppointmentID: &previousID,
+		AppointmentType:       "offline",
+		Capacity:              1,
+		HeldUntil:             &heldUntil,
+		PaymentID:             &paymentID,
+		PaymentStatus:         "pending",
+	}
+	repo := &paymentRepository{
+		slot: slot,
+		payments: map[string]*models.AppointmentPayment{
+			paymentID: {PaymentID: paymentID, AppointmentID: slot.ID, PatientID: patientID, Amount: 5000, Status: "pending"},
+		},
+	}
+	provider := payment.NewFakeProvider("test-secret")
+	svc := NewAppointmentService(repo, nil, nil, Options{Payments: provider})
+
+	body := []byte(`{"payment_id":"` + paymentID + `","status":"` + payment.StatusFailed + `"}`)
+	header := http.Header{}
+	header.Set(payment.FakeSignatureHeader, provider.Sign(body))
+	if err := svc.HandlePaymentConfirmation(body, header); err != nil {
+		t.Fatalf("failed payment: %v", err)
+	}
+
+	if repo.slot.Status != models.StatusAvailable || repo.slot.PatientID != nil {
+		t.Fatalf("hold was not released: status %s", repo.slot.Status)
+	}
+	if len(repo.reopened) != 1 || repo.reopened[0] != slot.ID {
+		t.Fatalf("follow-up proposal was not reopened: %v", repo.reopened)
+	}
+}
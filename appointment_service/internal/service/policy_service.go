@@ -0,0 +1,254 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// GetBookingPolicy - правила записи к врачу; врач без настроенных правил получает нулевые правила
+func (s *appointmentService) GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicyResponse, error) {
+	policy, err := s.bookingPolicy(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	return bookingPolicyToResponse(policy), nil
+}
+
+// UpdateBookingPolicy - заменяет правила записи врача. Уже сделанные записи правила не затрагивают
+func (s *appointmentService) UpdateBookingPolicy(doctorID uuid.UUID, req *models.UpdateBookingPolicyRequest) (*models.BookingPolicyResponse, error) {
+	policy, err := s.bookingPolicy(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy.MinLeadMinutes = req.MinLeadMinutes
+	policy.MaxHorizonDays = req.MaxHorizonDays
+	policy.CancelCutoffMinutes = req.CancelCutoffMinutes
+	policy.MaxActiveBookings = req.MaxActiveBookings
+	policy.BufferMinutes = req.BufferMinutes
+
+	if err := s.repo.SaveBookingPolicy(policy); err != nil {
+		return nil, fmt.Errorf("failed to save booking policy: %w", err)
+	}
+
+	s.logInfo("Booking policy updated", map[string]interface{}{
+		"doctorID":            doctorID.String(),
+		"minLeadMinutes":      policy.MinLeadMinutes,
+		"maxHorizonDays":      policy.MaxHorizonDays,
+		"cancelCutoffMinutes": policy.CancelCutoffMinutes,
+		"maxActiveBookings":   policy.MaxActiveBookings,
+		"bufferMinutes":       policy.BufferMinutes,
+	})
+
+	return bookingPolicyToResponse(policy), nil
+}
+
+// bookingPolicy - правила записи врача; без сохраненных правил - правила без ограничений
+func (s *appointmentService) bookingPolicy(doctorID uuid.UUID) (*models.BookingPolicy, error) {
+	policy, err := s.repo.GetBookingPolicy(doctorID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.BookingPolicy{DoctorID: doctorID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking policy: %w", err)
+	}
+	return policy, nil
+}
+
+// enforceBookingPolicy - проверяет новую запись пациента на прием [start, end) по правилам врача.
+// exclude - записи, которые не считаются соседними при проверке перерыва (сам бронируемый слот).
+// Лимит записей и перерыв здесь проверяются для быстрого отказа; окончательно их перепроверяет
+// транзакция бронирования по возвращенным limits
+func (s *appointmentService) enforceBookingPolicy(doctorID, patientID uuid.UUID, start, end time.Time, exclude ...uuid.UUID) (*repository.BookingLimits, error) {
+	policy, err := s.bookingPolicy(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := checkBookingTime(policy, start, now); err != nil {
+		return nil, err
+	}
+
+	if policy.MaxActiveBookings > 0 {
+		active, err := s.repo.CountPatientActiveBookings(doctorID, patientID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count patient bookings: %w", err)
+		}
+		if active >= int64(policy.MaxActiveBookings) {
+			return nil, activeBookingLimitError(policy.MaxActiveBookings)
+		}
+	}
+
+	if err := s.checkBuffer(policy, start, end, exclude...); err != nil {
+		return nil, err
+	}
+
+	return &repository.BookingLimits{
+		PatientID:         patientID,
+		MaxActiveBookings: policy.MaxActiveBookings,
+		Buffer:            policy.Buffer(),
+	}, nil
+}
+
+// bookingLimitsError - нарушение правил врача, обнаруженное транзакцией бронирования; nil для других ошибок
+func bookingLimitsError(limits *repository.BookingLimits, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrBookingLimitReached):
+		return activeBookingLimitError(limits.MaxActiveBookings)
+	case errors.Is(err, repository.ErrBufferConflict):
+		return bufferConflictError(int(limits.Buffer / time.Minute))
+	}
+	return nil
+}
+
+func activeBookingLimitError(maxActiveBookings int) error {
+	return &PolicyError{
+		Code:    PolicyActiveBookingLimit,
+		Message: fmt.Sprintf("patient may have at most %d upcoming appointments with this doctor", maxActiveBookings),
+	}
+}
+
+func bufferConflictError(bufferMinutes int) error {
+	return &PolicyError{
+		Code:    PolicyBufferConflict,
+		Message: fmt.Sprintf("doctor needs at least %d minutes between appointments", bufferMinutes),
+	}
+}
+
+// checkBookingTime - запись не ближе минимального времени до приема и не дальше горизонта записи
+func checkBookingTime(policy *models.BookingPolicy, start, now time.Time) error {
+	if start.Sub(now) < policy.MinLead() {
+		return &PolicyError{
+			Code:    PolicyLeadTimeTooShort,
+			Message: fmt.Sprintf("appointment must be booked at least %d minutes before it starts", policy.MinLeadMinutes),
+		}
+	}
+	if horizon := policy.HorizonEnd(now); horizon != nil && start.After(*horizon) {
+		return &PolicyError{
+			Code:    PolicyBeyondHorizon,
+			Message: fmt.Sprintf("appointment can be booked at most %d days ahead", policy.MaxHorizonDays),
+		}
+	}
+	return nil
+}
+
+// checkBuffer - между приемом [start, end) и другими записями врача должен оставаться перерыв
+func (s *appointmentService) checkBuffer(policy *models.BookingPolicy, start, end time.Time, exclude ...uuid.UUID) error {
+	if policy.BufferMinutes <= 0 {
+		return nil
+	}
+
+	occupied, err := s.repo.GetDoctorOccupiedTime(policy.DoctorID, start.Add(-policy.Buffer()), end.Add(policy.Buffer()))
+	if err != nil {
+		return fmt.Errorf("failed to get doctor appointments: %w", err)
+	}
+	if overlapsAppointments(withoutAppointments(occupied, exclude), start.Add(-policy.Buffer()), end.Add(policy.Buffer())) {
+		return bufferConflictError(policy.BufferMinutes)
+	}
+	return nil
+}
+
+// checkCancelCutoff - пациент может отменить запись не позже срока, заданного врачом
+func checkCancelCutoff(policy *models.BookingPolicy, start, now time.Time) error {
+	if start.Sub(now) < policy.CancelCutoff() {
+		return &PolicyError{
+			Code:    PolicyCancelCutoffPassed,
+			Message: fmt.Sprintf("appointment can be canceled no later than %d minutes before it starts", policy.CancelCutoffMinutes),
+		}
+	}
+	return nil
+}
+
+// bookableByPolicy - можно ли сейчас записаться на слот по правилам врача (без учета лимита пациента)
+func bookableByPolicy(policy *models.BookingPolicy, slot *models.Appointment, occupied []*models.Appointment, now time.Time) bool {
+	if checkBookingTime(policy, slot.StartTime, now) != nil {
+		return false
+	}
+	if policy.BufferMinutes <= 0 {
+		return true
+	}
+	return !overlapsAppointments(withoutAppointments(occupied, []uuid.UUID{slot.ID}),
+		slot.StartTime.Add(-policy.Buffer()), slot.EndTime.Add(policy.Buffer()))
+}
+
+// filterByBookingPolicy - убирает из списка слоты, на которые сейчас нельзя записаться по правилам врача
+func (s *appointmentService) filterByBookingPolicy(doctorID uuid.UUID, slots []*models.Appointment) ([]*models.Appointment, error) {
+	if len(slots) == 0 {
+		return slots, nil
+	}
+
+	policy, err := s.bookingPolicy(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var occupied []*models.Appointment
+	if policy.BufferMinutes > 0 {
+		from, to := slots[0].StartTime, slots[0].EndTime
+		for _, slot := range slots {
+			if slot.StartTime.Before(from) {
+				from = slot.StartTime
+			}
+			if slot.EndTime.After(to) {
+				to = slot.EndTime
+			}
+		}
+		occupied, err = s.repo.GetDoctorOccupiedTime(doctorID, from.Add(-policy.Buffer()), to.Add(policy.Buffer()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get doctor appointments: %w", err)
+		}
+	}
+
+	now := time.Now()
+	bookable := slots[:0]
+	for _, slot := range slots {
+		if bookableByPolicy(policy, slot, occupied, now) {
+			bookable = append(bookable, slot)
+		}
+	}
+	return bookable, nil
+}
+
+// withoutAppointments - записи, кроме перечисленных в exclude
+func withoutAppointments(appointments []*models.Appointment, exclude []uuid.UUID) []*models.Appointment {
+	if len(exclude) == 0 {
+		return appointments
+	}
+
+	filtered := make([]*models.Appointment, 0, len(appointments))
+	for _, appointment := range appointments {
+		excluded := false
+		for _, id := range exclude {
+			if appointment.ID == id {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, appointment)
+		}
+	}
+	return filtered
+}
+
+func bookingPolicyToResponse(policy *models.BookingPolicy) *models.BookingPolicyResponse {
+	response := &models.BookingPolicyResponse{
+		DoctorID:            policy.DoctorID,
+		MinLeadMinutes:      policy.MinLeadMinutes,
+		MaxHorizonDays:      policy.MaxHorizonDays,
+		CancelCutoffMinutes: policy.CancelCutoffMinutes,
+		MaxActiveBookings:   policy.MaxActiveBookings,
+		BufferMinutes:       policy.BufferMinutes,
+	}
+	if !policy.UpdatedAt.IsZero() {
+		response.UpdatedAt = &policy.UpdatedAt
+	}
+	return response
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"github.com/printprince/vitalem/logger_service/pkg/logger"
+)
+
+// ReminderService - периодическая рассылка напоминаний о предстоящих приемах
+type ReminderService struct {
+	repo     repository.AppointmentRepository
+	messages MessageService
+	logger   *logger.Client
+	offsets  []time.Duration
+	interval time.Duration
+	lastRun  time.Time
+	// Пояс по умолчанию для врачей без активного расписания
+	defaultTimezone string
+}
+
+// NewReminderService - создание планировщика напоминаний
+// offsets - за сколько до начала приема отправлять напоминания (например 24h и 1h)
+func NewReminderService(repo repository.AppointmentRepository, messageService MessageService, loggerClient *logger.Client, offsets []time.Duration, interval time.Duration, defaultTimezone string) *ReminderService {
+	// Сортируем по возрастанию: ближайшие напоминания отправляются первыми
+	sorted := make([]time.Duration, 0, len(offsets))
+	for _, offset := range offsets {
+		if offset > 0 {
+			sorted = append(sorted, offset)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	return &ReminderService{
+		repo:     repo,
+		messages: messageService,
+		logger:   loggerClient,
+		offsets:  sorted,
+		interval: interval,
+
+		defaultTimezone: defaultTimezone,
+	}
+}
+
+// Start - запускает планировщик, работает до отмены контекста
+func (r *ReminderService) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.SendDueReminders(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.SendDueReminders(ctx)
+		}
+	}
+}
+
+// SendDueReminders - отправляет все напоминания, время которых наступило.
+// Напоминание со смещением offset наступает в start - offset; берутся записи, у которых этот момент
+// пришелся на время с прошлого прохода (но не раньше одного интервала назад). Если запись создана
+// позже, чем наступило время длинного напоминания, оно пропускается и уходит только ближайшее -
+// пациент не получит "прием через 24 ч" за 5 ч до приема.
+// Отмененные и перенесенные записи не попадают в выборку, т.к. у них уже не статус booked
+func (r *ReminderService) SendDueReminders(ctx context.Context) {
+	now := time.Now()
+	since := now.Add(-r.interval)
+	// Проход задержался - продолжаем с прошлого, чтобы между окнами не осталось разрыва
+	if !r.lastRun.IsZero() && r.lastRun.Before(since) {
+		since = r.lastRun
+	}
+	r.lastRun = now
+	sent := 0
+
+	for _, offset := range r.offsets {
+		offsetMinutes := int(offset.Minutes())
+
+		appointments, err := r.repo.GetAppointmentsForReminder(since.Add(offset), now.Add(offset), offsetMinutes)
+		if err != nil {
+			r.logError("Failed to get appointments for reminder", map[string]interface{}{
+				"offsetMinutes": offsetMinutes,
+				"error":         err.Error(),
+			})
+			continue
+		}
+
+		for _, appointment := range appointments {
+			if ctx.Err() != nil {
+				return
+			}
+			sent += r.remind(ctx, appointment, offsetMinutes)
+		}
+	}
+
+	if sent > 0 {
+		r.logInfo("Appointment reminders sent", map[string]interface{}{
+			"count": sent,
+		})
+	}
+}
+
+// remind - напоминания по одной записи: пациенту и врачу личного приема - одно событие,
+// каждому участнику группового занятия - свое, врачу занятия - одно на занятие.
+// Возвращает число отправленных напоминаний
+func (r *ReminderService) remind(ctx context.Context, appointment *models.Appointment, offsetMinutes int) int {
+	if !appointment.IsGroup() {
+		if appointment.PatientID != nil && r.sendReminder(ctx, appointment, *appointment.PatientID, "", offsetMinutes) {
+			return 1
+		}
+		return 0
+	}
+
+	participants := r.participants(appointment)
+	if len(participants) == 0 {
+		return 0
+	}
+
+	sent := 0
+	for _, patientID := range participants {
+		if r.sendReminder(ctx, appointment, patientID, models.RecipientPatient, offsetMinutes) {
+			sent++
+		}
+	}
+	if r.sendReminder(ctx, appointment, appointment.DoctorID, models.RecipientDoctor, offsetMinutes) {
+		sent++
+	}
+	return sent
+}
+
+// participants - пациенты, записанные на групповое занятие
+func (r *ReminderService) participants(appointment *models.Appointment) []uuid.UUID {
+	participants, err := r.repo.GetParticipants(appointment.ID)
+	if err != nil {
+		r.logError("Failed to get group session participants for reminder", map[string]interface{}{
+			"appointmentID": appointment.ID.String(),
+			"error":         err.Error(),
+		})
+		return nil
+	}
+
+	patientIDs := make([]uuid.UUID, 0, len(participants))
+	for _, participant := range participants {
+		patientIDs = append(patientIDs, participant.PatientID)
+	}
+	return patientIDs
+}
+
+// sendReminder - резервирует и публикует одно напоминание получателю recipientID.
+// recipient - кому адресовано событие (models.RecipientPatient, models.RecipientDoctor или пусто - обоим)
+func (r *ReminderService) sendReminder(ctx context.Context, appointment *models.Appointment, recipientID uuid.UUID, recipient string, offsetMinutes int) bool {
+	// Резервирование через уникальный индекс (запись, получатель, смещение): другая реплика
+	// или повторный проход не отправят то же напоминание, остальным участникам оно уйдет
+	reminder := &models.AppointmentReminder{
+		AppointmentID: appointment.ID,
+		PatientID:     recipientID,
+		OffsetMinutes: offsetMinutes,
+		StartTime:     appointment.StartTime,
+		SentAt:        time.Now(),
+	}
+	claimed, err := r.repo.ClaimReminder(reminder)
+	if err != nil {
+		r.logError("Failed to claim reminder", map[string]interface{}{
+			"appointmentID": appointment.ID.String(),
+			"recipientID":   recipientID.String(),
+			"offsetMinutes": offsetMinutes,
+			"error":         err.Error(),
+		})
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	var patientID *uuid.UUID
+	if recipient != models.RecipientDoctor {
+		patientID = &recipientID
+	}
+	event := newAppointmentEvent(models.EventAppointmentReminder, appointment, patientID)
+	event.ReminderOffsetMinutes = offsetMinutes
+	event.Recipient = recipient
+	event.Timezone = lookupDoctorLocation(r.repo, appointment.DoctorID, r.defaultTimezone).String()
+
+	if err := r.messages.PublishAppointmentEvent(ctx, event); err != nil {
+		r.logError("Failed to publish reminder, releasing claim", map[string]interface{}{
+			"appointmentID": appointment.ID.String(),
+			"offsetMinutes": offsetMinutes,
+			"error":         err.Error(),
+		})
+		// Снимаем резервирование, чтобы напоминание ушло на следующем проходе
+		if err := r.repo.ReleaseReminder(reminder.ID); err != nil {
+			r.logError("Failed to release reminder claim", map[string]interface{}{
+				"reminderID": reminder.ID.String(),
+				"error":      err.Error(),
+			})
+		}
+		return false
+	}
+
+	return true
+}
+
+func (r *ReminderService) logInfo(message string, metadata map[string]interface{}) {
+	if r.logger != nil {
+		r.logger.Info(message, metadata)
+	}
+}
+
+func (r *ReminderService) logError(message string, metadata map[string]interface{}) {
+	if r.logger != nil {
+		r.logger.Error(message, metadata)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// reminderRepository - записи для напоминаний и резервирования в памяти
+// (уникальность по записи, пациенту и смещению, как индекс idx_reminders_unique)
+type reminderRepository struct {
+	repository.AppointmentRepository
+
+	appointments []*models.Appointment
+	participants map[uuid.UUID][]*models.AppointmentParticipant
+	claimed      map[string]bool
+}
+
+func (r *reminderRepository) GetAppointmentsForReminder(from, to time.Time, offsetMinutes int) ([]*models.Appointment, error) {
+	var due []*models.Appointment
+	for _, appointment := range r.appointments {
+		if appointment.StartTime.After(from) && !appointment.StartTime.After(to) {
+			due = append(due, appointment)
+		}
+	}
+	return due, nil
+}
+
+func (r *reminderRepository) GetDoctorSchedules(doctorID uuid.UUID) ([]*models.DoctorSchedule, error) {
+	return nil, nil
+}
+
+func (r *reminderRepository) GetParticipants(appointmentID uuid.UUID) ([]*models.AppointmentParticipant, error) {
+	return r.participants[appointmentID], nil
+}
+
+func (r *reminderRepository) ClaimReminder(reminder *models.AppointmentReminder) (bool, error) {
+	key := reminder.AppointmentID.String() + "/" + reminder.PatientID.String() + "/" + strconv.Itoa(reminder.OffsetMinutes)
+	if r.claimed[key] {
+		return false, nil
+	}
+	r.claimed[key] = true
+	return true, nil
+}
+
+// recordingMessages - запоминает опубликованные события
+type recordingMessages struct {
+	events []*models.AppointmentEvent
+}
+
+func (m *recordingMessages) PublishAppointmentEvent(ctx context.Context, event *models.AppointmentEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *recordingMessages) Close() error {
+	return nil
+}
+
+func TestSendDueRemindersNotifiesEveryGroupParticipant(t *testing.T) {
+	patientID := uuid.New()
+	single := &models.Appointment{
+		ID:        uuid.New(),
+		DoctorID:  uuid.New(),
+		StartTime: time.Now().Add(time.Hour - 20*time.Second),
+		Status:    models.StatusBooked,
+		PatientID: &patientID,
+		Capacity:  1,
+	}
+	group := &models.Appointment{
+		ID:         uuid.New(),
+		DoctorID:   uuid.New(),
+		StartTime:  time.Now().Add(time.Hour - 30*time.Second),
+		Status:     models.StatusBooked,
+		Capacity:   5,
+		SeatsTaken: 3,
+	}
+
+	participants := map[uuid.UUID]bool{}
+	repo := &reminderRepository{
+		appointments: []*models.Appointment{single, group},
+		participants: map[uuid.UUID][]*models.AppointmentParticipant{},
+		claimed:      map[string]bool{},
+	}
+	for i := 0; i < 3; i++ {
+		participant := &models.AppointmentParticipant{AppointmentID: group.ID, PatientID: uuid.New(), Status: models.ParticipantBooked}
+		repo.participants[group.ID] = append(repo.participants[group.ID], participant)
+		participants[participant.PatientID] = true
+	}
+
+	messages := &recordingMessages{}
+	reminders := NewReminderService(repo, messages, nil, []time.Duration{time.Hour}, time.Minute, "Asia/Almaty")
+
+	reminders.SendDueReminders(context.Background())
+
+	if len(messages.events) != 5 {
+		t.Fatalf("expected 5 reminders (1 patient + 3 participants + group doctor), got %d", len(messages.events))
+	}
+	doctorReminders := 0
+	for _, event := range messages.events {
+		if event.Timezone != "Asia/Almaty" {
+			t.Fatalf("expected the default doctor timezone in the reminder, got %q", event.Timezone)
+		}
+		if event.Recipient == models.RecipientDoctor {
+			doctorReminders++
+			if event.AppointmentID != group.ID || event.PatientID != nil {
+				t.Fatalf("unexpected doctor reminder %+v", event)
+			}
+			continue
+		}
+		if event.PatientID == nil {
+			t.Fatalf("reminder for %s has no patient", event.AppointmentID)
+		}
+		if event.AppointmentID == group.ID && (event.Recipient != models.RecipientPatient || !participants[*event.PatientID]) {
+			t.Fatalf("group reminder sent to non-participant %s", *event.PatientID)
+		}
+	}
+	if doctorReminders != 1 {
+		t.Fatalf("expected one doctor reminder per group session, got %d", doctorReminders)
+	}
+
+	// Повторный проход не отправляет уже зарезервированные напоминания
+	reminders.SendDueReminders(context.Background())
+	if len(messages.events) != 5 {
+		t.Fatalf("reminders sent twice: %d events", len(messages.events))
+	}
+}
+
+func TestSendDueRemindersSkipsLongReminderForLateBooking(t *testing.T) {
+	patientID := uuid.New()
+	// Запись сделана за 5 ч до приема: время 24-часового напоминания уже прошло
+	late := &models.Appointment{
+		ID:        uuid.New(),
+		DoctorID:  uuid.New(),
+		StartTime: time.Now().Add(5 * time.Hour),
+		Status:    models.StatusBooked,
+		PatientID: &patientID,
+		Capacity:  1,
+	}
+	repo := &reminderRepository{
+		appointments: []*models.Appointment{late},
+		claimed:      map[string]bool{},
+	}
+
+	messages := &recordingMessages{}
+	reminders := NewReminderService(repo, messages, nil, []time.Duration{time.Hour, 24 * time.Hour}, time.Minute, "Asia/Almaty")
+
+	reminders.SendDueReminders(context.Background())
+	if len(messages.events) != 0 {
+		t.Fatalf("expected no reminders 5h before the visit, got %d (offset %d)", len(messages.events), messages.events[0].ReminderOffsetMinutes)
+	}
+
+	// Когда наступает время часового напоминания, уходит только оно
+	late.StartTime = time.Now().Add(time.Hour - 20*time.Second)
+	reminders.SendDueReminders(context.Background())
+	if len(messages.events) != 1 || messages.events[0].ReminderOffsetMinutes != 60 {
+		t.Fatalf("expected a single 1h reminder, got %+v", messages.events)
+	}
+}
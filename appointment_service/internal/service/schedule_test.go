@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// schedulesRepository - расписания врача в памяти
+type schedulesRepository struct {
+	repository.AppointmentRepository
+
+	schedules []*models.DoctorSchedule
+}
+
+func (r *schedulesRepository) GetDoctorSchedules(doctorID uuid.UUID) ([]*models.DoctorSchedule, error) {
+	return r.schedules, nil
+}
+
+func newTestSchedule(timezone, start, end string, days ...int) *models.DoctorSchedule {
+	schedule := &models.DoctorSchedule{
+		ID:        uuid.New(),
+		Name:      timezone,
+		StartTime: start,
+		EndTime:   end,
+		Timezone:  timezone,
+		IsActive:  true,
+	}
+	schedule.SetWorkDays(days)
+	return schedule
+}
+
+func TestCheckScheduleConflictsComparesTimezones(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  *models.DoctorSchedule
+		schedule  *models.DoctorSchedule
+		conflict  bool
+		days      []int
+		startTime string
+		endTime   string
+	}{
+		{
+			name:     "same hours in different zones do not overlap",
+			existing: newTestSchedule("Asia/Tokyo", "09:00", "12:00", 1),
+			schedule: newTestSchedule("Europe/Berlin", "09:00", "12:00", 1),
+			conflict: false,
+		},
+		{
+			name:      "different hours overlap in absolute time",
+			existing:  newTestSchedule("UTC", "01:00", "02:00", 1),
+			schedule:  newTestSchedule("Asia/Tokyo", "09:00", "13:00", 1),
+			conflict:  true,
+			days:      []int{1},
+			startTime: "10:00",
+			endTime:   "11:00",
+		},
+		{
+			name:      "monday in one zone overlaps sunday evening in another",
+			existing:  newTestSchedule("Asia/Tokyo", "01:00", "03:00", 1),
+			schedule:  newTestSchedule("UTC", "15:00", "23:00", 7),
+			conflict:  true,
+			days:      []int{7},
+			startTime: "16:00",
+			endTime:   "18:00",
+		},
+		{
+			name:      "same zone keeps common days and hours",
+			existing:  newTestSchedule("Asia/Tokyo", "09:00", "13:00", 1, 2, 3),
+			schedule:  newTestSchedule("Asia/Tokyo", "12:00", "18:00", 2, 3, 4),
+			conflict:  true,
+			days:      []int{2, 3},
+			startTime: "12:00",
+			endTime:   "13:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &schedulesRepository{schedules: []*models.DoctorSchedule{tt.existing}}
+			svc := NewAppointmentService(repo, nil, nil, Options{}).(*appointmentService)
+
+			err := svc.checkScheduleConflicts(uuid.New(), tt.schedule)
+			if !tt.conflict {
+				if err != nil {
+					t.Fatalf("unexpected conflict: %v", err)
+				}
+				return
+			}
+
+			var conflictErr *ScheduleConflictError
+			if !errors.As(err, &conflictErr) || len(conflictErr.Conflicts) != 1 {
+				t.Fatalf("expected one conflict, got %v", err)
+			}
+			conflict := conflictErr.Conflicts[0]
+			if len(conflict.WorkDays) != len(tt.days) {
+				t.Fatalf("work days = %v, want %v", conflict.WorkDays, tt.days)
+			}
+			for i := range tt.days {
+				if conflict.WorkDays[i] != tt.days[i] {
+					t.Fatalf("work days = %v, want %v", conflict.WorkDays, tt.days)
+				}
+			}
+			if conflict.StartTime != tt.startTime || conflict.EndTime != tt.endTime {
+				t.Fatalf("overlap = %s-%s, want %s-%s", conflict.StartTime, conflict.EndTime, tt.startTime, tt.endTime)
+			}
+		})
+	}
+}
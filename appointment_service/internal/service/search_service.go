@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+const (
+	// defaultPageLimit - размер страницы списков и поиска по умолчанию
+	defaultPageLimit = 20
+	// searchMaxDays - на сколько дней максимум можно искать за один запрос
+	searchMaxDays = 31
+)
+
+// SearchAvailableSlots - свободные слоты нескольких врачей (или всех), отсортированные от ближайших.
+// Даты и окно времени суток трактуются в часовом поясе пациента; req.Page и req.Limit
+// нормализуются, чтобы обработчик вернул их в ответе
+func (s *appointmentService) SearchAvailableSlots(req *models.SlotSearchRequest) ([]*models.SlotSearchItem, int64, error) {
+	location := time.UTC
+	if req.Timezone != "" {
+		loc, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, req.Timezone)
+		}
+		location = loc
+	}
+
+	dateFrom, err := time.ParseInLocation("2006-01-02", req.DateFrom, location)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: invalid date_from: %v", ErrInvalidInput, err)
+	}
+	dateTo, err := time.ParseInLocation("2006-01-02", req.DateTo, location)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: invalid date_to: %v", ErrInvalidInput, err)
+	}
+	if dateTo.Before(dateFrom) {
+		return nil, 0, fmt.Errorf("%w: date_to must not be before date_from", ErrInvalidInput)
+	}
+	if dateTo.Sub(dateFrom) >= searchMaxDays*24*time.Hour {
+		return nil, 0, fmt.Errorf("%w: search range must not exceed %d days", ErrInvalidInput, searchMaxDays)
+	}
+
+	if err := validateClock(req.TimeFrom, "time_from"); err != nil {
+		return nil, 0, err
+	}
+	if err := validateClock(req.TimeTo, "time_to"); err != nil {
+		return nil, 0, err
+	}
+	if req.TimeFrom != "" && req.TimeTo != "" && req.TimeFrom >= req.TimeTo {
+		return nil, 0, fmt.Errorf("%w: time_from must be before time_to", ErrInvalidInput)
+	}
+
+	var doctorIDs []uuid.UUID
+	for _, value := range strings.Split(req.DoctorIDs, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		doctorID, err := uuid.Parse(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: invalid doctor id '%s'", ErrInvalidInput, value)
+		}
+		doctorIDs = append(doctorIDs, doctorID)
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = defaultPageLimit
+	}
+
+	// Прошедшие слоты не предлагаем
+	now := time.Now()
+	from := dateFrom
+	if from.Before(now) {
+		from = now
+	}
+
+	appointments, total, err := s.repo.SearchAvailableSlots(repository.SlotSearchFilter{
+		DoctorIDs: doctorIDs,
+		From:      from,
+		To:        dateTo.AddDate(0, 0, 1),
+		Format:    req.Format,
+		TimeFrom:  req.TimeFrom,
+		TimeTo:    req.TimeTo,
+		Timezone:  location.String(),
+		Now:       now,
+		Offset:    (req.Page - 1) * req.Limit,
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search available slots: %w", err)
+	}
+
+	items := make([]*models.SlotSearchItem, len(appointments))
+	for i, appointment := range appointments {
+		items[i] = &models.SlotSearchItem{
+			ID:              appointment.ID,
+			DoctorID:        appointment.DoctorID,
+			StartTime:       appointment.StartTime.In(location),
+			EndTime:         appointment.EndTime.In(location),
+			Duration:        int(appointment.EndTime.Sub(appointment.StartTime).Minutes()),
+			Title:           appointment.Title,
+			AppointmentType: appointment.AppointmentType,
+			Capacity:        appointment.Capacity,
+			SeatsLeft:       appointment.SeatsLeft(),
+		}
+	}
+
+	return items, total, nil
+}
+
+// validateClock проверяет время суток в формате "15:04"
+func validateClock(value, field string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse("15:04", value); err != nil {
+		return fmt.Errorf("%w: invalid %s '%s', expected HH:MM", ErrInvalidInput, field, value)
+	}
+	return nil
+}
@@ -1,14 +1,23 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/files"
+	"github.com/printprince/vitalem/appointment_service/internal/meeting"
 	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/payment"
 	"github.com/printprince/vitalem/appointment_service/internal/repository"
 	"github.com/printprince/vitalem/logger_service/pkg/logger"
+	"gorm.io/gorm"
 )
 
 // AppointmentService - интерфейс сервиса
@@ -23,33 +32,132 @@ type AppointmentService interface {
 	GetGeneratedSlots(doctorID, scheduleID uuid.UUID, startDate, endDate string) (*models.GeneratedSlotsResponse, error)
 
 	// Appointments
-	GetAvailableSlots(doctorID uuid.UUID, date string) ([]*models.AvailableSlot, error)
+	GetAvailableSlots(doctorID uuid.UUID, date, timezone string) ([]*models.AvailableSlot, error)
 	BookAppointment(patientID, appointmentID uuid.UUID, req *models.BookAppointmentRequest) (*models.AppointmentResponse, error)
 	CancelAppointment(patientID, appointmentID uuid.UUID) error
-	GetDoctorAppointments(doctorID uuid.UUID) ([]*models.AppointmentResponse, error)
+	RescheduleAppointment(patientID, appointmentID uuid.UUID, req *models.RescheduleAppointmentRequest) (*models.AppointmentResponse, error)
+	DoctorCancelAppointment(doctorID, appointmentID uuid.UUID, req *models.DoctorCancelRequest) (*models.AppointmentResponse, error)
+	DoctorCancelAppointments(doctorID uuid.UUID, req *models.DoctorBulkCancelRequest) (*models.DoctorBulkCancelResponse, error)
+	CompleteAppointment(doctorID, appointmentID uuid.UUID, req *models.CompleteAppointmentRequest) (*models.AppointmentResponse, error)
+	CheckInAppointment(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error)
+	StartAppointment(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error)
+	MarkNoShow(doctorID, appointmentID uuid.UUID, req *models.MarkNoShowRequest) (*models.AppointmentResponse, error)
+	GetAppointmentStatusHistory(userID uuid.UUID, role string, appointmentID uuid.UUID) ([]*models.AppointmentStatusHistory, error)
+	GetMeetingJoin(userID uuid.UUID, role string, appointmentID uuid.UUID) (*models.MeetingJoinResponse, error)
+	GetParticipants(doctorID, appointmentID uuid.UUID) ([]*models.ParticipantResponse, error)
+
+	// Повторные приемы
+	BookFollowUp(doctorID, appointmentID uuid.UUID, req *models.BookFollowUpRequest) (*models.AppointmentResponse, error)
+	ProposeFollowUp(doctorID, appointmentID uuid.UUID, req *models.ProposeFollowUpRequest) (*models.FollowUpProposalResponse, error)
+	GetPatientFollowUpProposals(patientID uuid.UUID, status string) ([]*models.FollowUpProposalResponse, error)
+	AcceptFollowUpProposal(patientID, proposalID uuid.UUID, req *models.AcceptFollowUpRequest) (*models.AppointmentResponse, error)
+	GetVisitChain(userID uuid.UUID, role string, appointmentID uuid.UUID) ([]*models.AppointmentResponse, error)
+
+	// Файлы, прикрепленные к записи
+	AttachFile(userID uuid.UUID, role string, appointmentID uuid.UUID, token string, req *models.AttachFileRequest) (*models.AttachmentResponse, error)
+	GetAttachments(userID uuid.UUID, role string, appointmentID uuid.UUID) ([]*models.AttachmentResponse, error)
+	DetachFile(userID uuid.UUID, role string, appointmentID, fileID uuid.UUID) error
+
+	// Visit types (записи, вырезаемые из свободного времени)
+	CreateVisitType(doctorID uuid.UUID, req *models.CreateVisitTypeRequest) (*models.VisitTypeResponse, error)
+	GetVisitTypes(doctorID uuid.UUID, activeOnly bool) ([]*models.VisitTypeResponse, error)
+	UpdateVisitType(doctorID, visitTypeID uuid.UUID, req *models.UpdateVisitTypeRequest) (*models.VisitTypeResponse, error)
+	DeleteVisitType(doctorID, visitTypeID uuid.UUID) error
+	GetVisitSlots(doctorID, visitTypeID uuid.UUID, date, timezone string) ([]*models.VisitSlot, error)
+	BookVisit(patientID, doctorID uuid.UUID, req *models.BookVisitRequest) (*models.AppointmentResponse, error)
+
+	// Booking policy (правила записи к врачу)
+	GetBookingPolicy(doctorID uuid.UUID) (*models.BookingPolicyResponse, error)
+	UpdateBookingPolicy(doctorID uuid.UUID, req *models.UpdateBookingPolicyRequest) (*models.BookingPolicyResponse, error)
+
+	// Search
+	SearchAvailableSlots(req *models.SlotSearchRequest) ([]*models.SlotSearchItem, int64, error)
+
+	// Analytics
+	GetDoctorAnalytics(doctorID uuid.UUID, req *models.DoctorAnalyticsRequest) (*models.DoctorAnalyticsResponse, error)
+
+	// Payments
+	HandlePaymentConfirmation(body []byte, header http.Header) error
+	ReleaseExpiredHolds() (int, error)
+	GetDoctorAppointments(doctorID uuid.UUID, req *models.AppointmentListRequest) ([]*models.AppointmentResponse, int64, error)
 	GetDoctorAppointmentByID(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error)
-	GetPatientAppointments(patientID uuid.UUID) ([]*models.AppointmentResponse, error)
+	GetPatientAppointments(patientID uuid.UUID, req *models.AppointmentListRequest) ([]*models.AppointmentResponse, int64, error)
 	GetPatientAppointmentByID(patientID, appointmentID uuid.UUID) (*models.AppointmentResponse, error)
 
 	// Exceptions
 	AddException(doctorID uuid.UUID, req *models.AddExceptionRequest) (*models.ExceptionResponse, error)
+	UpdateException(doctorID, exceptionID uuid.UUID, req *models.UpdateExceptionRequest) (*models.ExceptionResponse, error)
+	DeleteException(doctorID, exceptionID uuid.UUID) error
 	GetDoctorExceptions(doctorID uuid.UUID, startDate, endDate string) ([]*models.ExceptionResponse, error)
 
+	// External calendars (занятое время врача из .ics)
+	ImportCalendar(doctorID uuid.UUID, r io.Reader) (*models.CalendarImportResponse, error)
+	AddExternalCalendar(doctorID uuid.UUID, req *models.RegisterExternalCalendarRequest) (*models.CalendarImportResponse, error)
+	GetExternalCalendars(doctorID uuid.UUID) ([]*models.ExternalCalendarResponse, error)
+	DeleteExternalCalendar(doctorID, calendarID uuid.UUID) error
+	SyncExternalCalendar(doctorID, calendarID uuid.UUID) (*models.CalendarImportResponse, error)
+
+	// Calendar feeds
+	GetCalendarFeed(userID uuid.UUID, role string) (*models.CalendarFeedResponse, error)
+	RotateCalendarFeed(userID uuid.UUID, role string) (*models.CalendarFeedResponse, error)
+	GetCalendarFeedICS(token string) (string, error)
+	GetAppointmentICS(userID uuid.UUID, role string, appointmentID uuid.UUID) (string, error)
+
 	// New method for forcing clean slots of schedule
 	DeleteScheduleSlots(doctorID, scheduleID uuid.UUID) error
 }
 
+// Options - настройки бизнес-правил сервиса записей
+type Options struct {
+	// RescheduleMinNotice - за сколько до начала приема еще можно перенести запись
+	RescheduleMinNotice time.Duration
+	// DefaultTimezone - часовой пояс новых расписаний, если врач не указал свой
+	DefaultTimezone string
+	// CalendarFeedBaseURL - внешний адрес API, от которого строятся ссылки на ICS-ленты
+	CalendarFeedBaseURL string
+	// CalendarAllowedHosts - внутренние хосты и сети (CIDR), с которых можно подключать внешние календари;
+	// остальные адреса внутренней сети запрещены
+	CalendarAllowedHosts []string
+	// CheckInWindow - за сколько до начала приема врач может отметить приход пациента
+	CheckInWindow time.Duration
+	// HoldDuration - сколько слот удерживается за пациентом в ожидании оплаты
+	HoldDuration time.Duration
+	// Payments - платежный провайдер; без него (или без Prices) запись бесплатная и мгновенная
+	Payments payment.Provider
+	// Prices - источник цены приема врача (оплата записи, оценка выручки в аналитике)
+	Prices PriceResolver
+	// Meetings - провайдер видеовстреч; без него онлайн-записи остаются без комнаты
+	Meetings meeting.Provider
+	// MeetingJoinBefore - за сколько до начала приема начинает действовать ссылка на встречу
+	MeetingJoinBefore time.Duration
+	// MeetingJoinAfter - сколько после окончания приема ссылка на встречу еще действует
+	MeetingJoinAfter time.Duration
+	// Files - ссылки на файлы FileServer; без него файлы к записям не прикрепляются
+	Files files.Linker
+	// FileOwners - проверка владельца файла в FileServer; без него файлы к записям не прикрепляются
+	FileOwners files.OwnerResolver
+	// FileGrantTTL - сколько действует ссылка на файл другого участника приема
+	FileGrantTTL time.Duration
+}
+
 // appointmentService - реализация сервиса
 type appointmentService struct {
-	repo   repository.AppointmentRepository
-	logger *logger.Client
+	repo      repository.AppointmentRepository
+	messages  MessageService
+	logger    *logger.Client
+	options   Options
+	calendars *calendarFetcher
 }
 
 // NewAppointmentService - создание нового сервиса
-func NewAppointmentService(repo repository.AppointmentRepository, loggerClient *logger.Client) AppointmentService {
+// messageService может быть nil - тогда события не публикуются
+func NewAppointmentService(repo repository.AppointmentRepository, messageService MessageService, loggerClient *logger.Client, options Options) AppointmentService {
 	return &appointmentService{
-		repo:   repo,
-		logger: loggerClient,
+		repo:      repo,
+		messages:  messageService,
+		logger:    loggerClient,
+		options:   options,
+		calendars: newCalendarFetcher(options.CalendarAllowedHosts),
 	}
 }
 
@@ -72,6 +180,70 @@ func (s *appointmentService) logError(message string, metadata map[string]interf
 	}
 }
 
+// publishEvent - публикует событие записи в RabbitMQ
+// Ошибка публикации только логируется: состояние записи уже сохранено в БД
+func (s *appointmentService) publishEvent(eventType string, appointment *models.Appointment, patientID *uuid.UUID) {
+	if s.messages == nil {
+		return
+	}
+
+	// Событие группового занятия без конкретного пациента получает каждый участник
+	if patientID == nil && appointment.IsGroup() {
+		for _, participantID := range s.participantIDs(appointment) {
+			s.sendEvent(newAppointmentEvent(eventType, appointment, &participantID))
+		}
+		return
+	}
+
+	s.sendEvent(newAppointmentEvent(eventType, appointment, patientID))
+}
+
+// sendEvent - отправляет готовое событие, логируя ошибку публикации.
+// Время в уведомлении показывается в поясе врача
+func (s *appointmentService) sendEvent(event *models.AppointmentEvent) {
+	event.Timezone = s.doctorLocation(event.DoctorID).String()
+	if err := s.messages.PublishAppointmentEvent(context.Background(), event); err != nil {
+		s.logError("Failed to publish appointment event", map[string]interface{}{
+			"eventType":     event.EventType,
+			"appointmentID": event.AppointmentID.String(),
+			"error":         err.Error(),
+		})
+	}
+}
+
+// newAppointmentEvent - формирует событие по записи
+// patientID передается отдельно, т.к. Cancel() пациентом очищает PatientID у записи
+func newAppointmentEvent(eventType string, appointment *models.Appointment, patientID *uuid.UUID) *models.AppointmentEvent {
+	return &models.AppointmentEvent{
+		EventType:       eventType,
+		AppointmentID:   appointment.ID,
+		DoctorID:        appointment.DoctorID,
+		PatientID:       patientID,
+		StartTime:       appointment.StartTime,
+		EndTime:         appointment.EndTime,
+		Title:           appointment.Title,
+		Status:          appointment.Status,
+		AppointmentType: appointment.AppointmentType,
+		MeetingLink:     appointment.MeetingLink,
+		CanceledByRole:  appointment.CanceledByRole,
+		CancelReason:    appointment.CancelReason,
+		OccurredAt:      time.Now(),
+	}
+}
+
+// getAppointment - запись по ID; не найдена только при gorm.ErrRecordNotFound,
+// прочие ошибки БД возвращаются как внутренние
+func (s *appointmentService) getAppointment(id uuid.UUID) (*models.Appointment, error) {
+	appointment, err := s.repo.GetAppointmentByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrAppointmentNotFound, err)
+		}
+		return nil, fmt.Errorf("failed to get appointment: %w", err)
+	}
+	return appointment, nil
+}
+
 // === SCHEDULES ===
 
 func (s *appointmentService) CreateSchedule(doctorID uuid.UUID, req *models.CreateScheduleRequest) (*models.ScheduleResponse, error) {
@@ -80,20 +252,6 @@ func (s *appointmentService) CreateSchedule(doctorID uuid.UUID, req *models.Crea
 		"scheduleName": req.Name,
 	})
 
-	// При создании нового расписания ВСЕГДА деактивируем все существующие
-	// чтобы у врача было только одно активное расписание
-	s.logInfo("Deactivating all existing schedules for single active schedule policy", map[string]interface{}{
-		"doctorID": doctorID.String(),
-	})
-
-	if err := s.deactivateOtherSchedules(doctorID); err != nil {
-		s.logError("Failed to deactivate existing schedules", map[string]interface{}{
-			"doctorID": doctorID.String(),
-			"error":    err.Error(),
-		})
-		return nil, fmt.Errorf("failed to deactivate existing schedules: %w", err)
-	}
-
 	schedule := &models.DoctorSchedule{
 		DoctorID:          doctorID,
 		Name:              req.Name,
@@ -104,12 +262,55 @@ func (s *appointmentService) CreateSchedule(doctorID uuid.UUID, req *models.Crea
 		SlotDuration:      req.SlotDuration,
 		SlotTitle:         req.SlotTitle,
 		AppointmentFormat: req.AppointmentFormat,
+		SlotCapacity:      req.SlotCapacity,
 		IsActive:          true, // Новое расписание всегда активно
+		Timezone:          req.Timezone,
+		GenerateDaysAhead: req.GenerateDaysAhead,
+	}
+
+	if schedule.SlotCapacity < 1 {
+		schedule.SlotCapacity = 1
+	}
+	if schedule.SlotCapacity > 1 {
+		if err := s.checkGroupPricing(doctorID); err != nil {
+			return nil, err
+		}
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = s.options.DefaultTimezone
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if _, err := schedule.Location(); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, schedule.Timezone)
 	}
 
 	// Устанавливаем рабочие дни через новый метод
 	schedule.SetWorkDays(req.WorkDays)
 
+	// Несколько расписаний могут быть активны одновременно, если не пересекаются.
+	// В режиме exclusive остается только новое расписание
+	if req.Exclusive {
+		s.logInfo("Deactivating all existing schedules for single active schedule policy", map[string]interface{}{
+			"doctorID": doctorID.String(),
+		})
+
+		if err := s.deactivateOtherSchedules(doctorID); err != nil {
+			s.logError("Failed to deactivate existing schedules", map[string]interface{}{
+				"doctorID": doctorID.String(),
+				"error":    err.Error(),
+			})
+			return nil, fmt.Errorf("failed to deactivate existing schedules: %w", err)
+		}
+	} else if err := s.checkScheduleConflicts(doctorID, schedule); err != nil {
+		s.logError("Cannot create schedule due to conflicts", map[string]interface{}{
+			"doctorID": doctorID.String(),
+			"error":    err.Error(),
+		})
+		return nil, err
+	}
+
 	if err := s.repo.CreateSchedule(schedule); err != nil {
 		s.logError("Failed to create schedule in repository", map[string]interface{}{
 			"doctorID": doctorID.String(),
@@ -127,62 +328,171 @@ func (s *appointmentService) CreateSchedule(doctorID uuid.UUID, req *models.Crea
 	return s.scheduleToResponse(schedule), nil
 }
 
-// checkScheduleConflicts проверяет конфликты времени с существующими активными расписаниями
-func (s *appointmentService) checkScheduleConflicts(doctorID uuid.UUID, req *models.CreateScheduleRequest) error {
+// checkScheduleConflicts проверяет, что расписание не пересекается с другими активными расписаниями врача.
+// Расписания конфликтуют, если их часы работы пересекаются в абсолютном времени: окна расписаний
+// в разных часовых поясах сравниваются после перевода в UTC
+func (s *appointmentService) checkScheduleConflicts(doctorID uuid.UUID, schedule *models.DoctorSchedule) error {
 	existingSchedules, err := s.repo.GetDoctorSchedules(doctorID)
 	if err != nil {
 		return fmt.Errorf("failed to get existing schedules: %w", err)
 	}
 
-	startTime, err := time.Parse("15:04", req.StartTime)
+	window, err := newScheduleWindow(schedule)
 	if err != nil {
-		return fmt.Errorf("invalid start time format: %w", err)
-	}
-
-	endTime, err := time.Parse("15:04", req.EndTime)
-	if err != nil {
-		return fmt.Errorf("invalid end time format: %w", err)
+		return err
 	}
 
+	var conflicts []models.ScheduleConflict
 	for _, existing := range existingSchedules {
-		if !existing.IsActive {
-			continue // пропускаем неактивные расписания
+		// Пропускаем само расписание и неактивные расписания
+		if existing.ID == schedule.ID || !existing.IsActive {
+			continue
 		}
 
-		existingStart, _ := time.Parse("15:04", existing.StartTime)
-		existingEnd, _ := time.Parse("15:04", existing.EndTime)
+		existingWindow, err := newScheduleWindow(existing)
+		if err != nil {
+			continue
+		}
 
-		// Проверяем пересечение рабочих дней
-		if s.hasWorkDayConflict(req.WorkDays, existing.WorkDays()) {
-			// Проверяем пересечение времени
-			if s.hasTimeConflict(startTime, endTime, existingStart, existingEnd) {
-				return fmt.Errorf("schedule conflicts with existing schedule '%s' on overlapping work days and times", existing.Name)
-			}
+		// Общий интервал - в часовом поясе проверяемого расписания
+		commonDays, overlapStart, overlapEnd, ok := window.overlap(existingWindow)
+		if !ok {
+			continue
 		}
+
+		conflicts = append(conflicts, models.ScheduleConflict{
+			ScheduleID: existing.ID,
+			Name:       existing.Name,
+			WorkDays:   commonDays,
+			StartTime:  overlapStart,
+			EndTime:    overlapEnd,
+		})
+	}
+
+	if len(conflicts) > 0 {
+		return &ScheduleConflictError{Conflicts: conflicts}
 	}
 
 	return nil
 }
 
-// hasWorkDayConflict проверяет есть ли пересечения в рабочих днях
-func (s *appointmentService) hasWorkDayConflict(workDays1, workDays2 []int) bool {
-	dayMap := make(map[int]bool)
-	for _, day := range workDays1 {
-		dayMap[day] = true
+// scheduleWindow - недельное окно работы расписания: рабочие дни и часы в его часовом поясе
+type scheduleWindow struct {
+	workDays   []int
+	start, end time.Time // значимы только часы и минуты
+	location   *time.Location
+}
+
+// newScheduleWindow - окно работы расписания с проверкой времени и часового пояса
+func newScheduleWindow(schedule *models.DoctorSchedule) (scheduleWindow, error) {
+	startTime, err := time.Parse("15:04", schedule.StartTime)
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("%w: invalid start time format '%s'", ErrInvalidInput, schedule.StartTime)
 	}
 
-	for _, day := range workDays2 {
-		if dayMap[day] {
-			return true
+	endTime, err := time.Parse("15:04", schedule.EndTime)
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("%w: invalid end time format '%s'", ErrInvalidInput, schedule.EndTime)
+	}
+
+	if !startTime.Before(endTime) {
+		return scheduleWindow{}, fmt.Errorf("%w: start time must be before end time", ErrInvalidInput)
+	}
+
+	location, err := schedule.Location()
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, schedule.Timezone)
+	}
+
+	return scheduleWindow{workDays: schedule.WorkDays(), start: startTime, end: endTime, location: location}, nil
+}
+
+// scheduleReferenceWeeks - понедельники недель, на которых сравниваются окна расписаний:
+// зимняя и летняя, чтобы учесть переход на летнее время в любом из поясов
+func scheduleReferenceWeeks(year int) []time.Time {
+	var mondays []time.Time
+	for _, month := range []time.Month{time.January, time.July} {
+		date := time.Date(year, month, 10, 0, 0, 0, 0, time.UTC)
+		for date.Weekday() != time.Monday {
+			date = date.AddDate(0, 0, 1)
 		}
+		mondays = append(mondays, date)
 	}
+	return mondays
+}
 
-	return false
+// intervals - окна работы в абсолютном времени на неделе, начинающейся в понедельник monday
+func (w scheduleWindow) intervals(monday time.Time) [][2]time.Time {
+	var intervals [][2]time.Time
+	for _, day := range w.workDays {
+		date := monday.AddDate(0, 0, day-1)
+		from := time.Date(date.Year(), date.Month(), date.Day(), w.start.Hour(), w.start.Minute(), 0, 0, w.location)
+		to := time.Date(date.Year(), date.Month(), date.Day(), w.end.Hour(), w.end.Minute(), 0, 0, w.location)
+		intervals = append(intervals, [2]time.Time{from, to})
+	}
+	return intervals
 }
 
-// hasTimeConflict проверяет пересекается ли время
-func (s *appointmentService) hasTimeConflict(start1, end1, start2, end2 time.Time) bool {
-	return start1.Before(end2) && end1.After(start2)
+// overlap - общие рабочие дни и общий интервал "15:04" двух окон в часовом поясе w.
+// Если пересечения на разных днях отличаются (разные пояса), интервал охватывает их все
+func (w scheduleWindow) overlap(other scheduleWindow) ([]int, string, string, bool) {
+	days := make(map[int]bool)
+	var earliest, latest time.Time
+
+	for _, monday := range scheduleReferenceWeeks(time.Now().Year()) {
+		// Соседние недели другого окна: в другом поясе его понедельник может пересечься с нашим воскресеньем
+		var others [][2]time.Time
+		for _, shift := range []int{-7, 0, 7} {
+			others = append(others, other.intervals(monday.AddDate(0, 0, shift))...)
+		}
+
+		for _, own := range w.intervals(monday) {
+			for _, theirs := range others {
+				from, to := own[0], own[1]
+				if theirs[0].After(from) {
+					from = theirs[0]
+				}
+				if theirs[1].Before(to) {
+					to = theirs[1]
+				}
+				if !from.Before(to) {
+					continue
+				}
+
+				from, to = from.In(w.location), to.In(w.location)
+				weekday := int(from.Weekday())
+				if weekday == 0 {
+					weekday = 7 // Воскресенье = 7
+				}
+				days[weekday] = true
+
+				// Время суток для сравнения без учета даты
+				fromClock := time.Date(0, 1, 1, from.Hour(), from.Minute(), 0, 0, time.UTC)
+				toClock := time.Date(0, 1, 1, to.Hour(), to.Minute(), 0, 0, time.UTC)
+				if to.Day() != from.Day() {
+					toClock = toClock.AddDate(0, 0, 1)
+				}
+				if earliest.IsZero() || fromClock.Before(earliest) {
+					earliest = fromClock
+				}
+				if latest.IsZero() || toClock.After(latest) {
+					latest = toClock
+				}
+			}
+		}
+	}
+
+	if len(days) == 0 {
+		return nil, "", "", false
+	}
+
+	common := make([]int, 0, len(days))
+	for day := range days {
+		common = append(common, day)
+	}
+	sort.Ints(common)
+
+	return common, earliest.Format("15:04"), latest.Format("15:04"), true
 }
 
 // deactivateOtherSchedules деактивирует все другие расписания врача (кроме указанного ID, если передан)
@@ -302,12 +612,14 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 		return nil, fmt.Errorf("invalid end date: %w", err)
 	}
 
-	// Получаем исключения для периода
+	// Получаем исключения для периода (включая периоды и повторяющиеся)
 	exceptions, _ := s.repo.GetDoctorExceptions(doctorID, startDate, endDate)
-	exceptionMap := make(map[string]*models.ScheduleException)
-	for _, ex := range exceptions {
-		dateStr := ex.Date.Format("2006-01-02")
-		exceptionMap[dateStr] = ex
+
+	// Перерыв между приемами из правил записи врача разносит слоты друг от друга.
+	// Горизонт записи здесь не применяется: дальние слоты скрыты от пациентов, пока не войдут в него
+	policy, err := s.bookingPolicy(doctorID)
+	if err != nil {
+		return nil, err
 	}
 
 	s.logInfo("Retrieved exceptions for period", map[string]interface{}{
@@ -326,7 +638,7 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 		}
 
 		// Проверяем исключения
-		if exception, exists := exceptionMap[dateStr]; exists {
+		if exception := exceptionForDay(exceptions, date); exception != nil {
 			if exception.Type == "day_off" {
 				s.logInfo("Skipping day off", map[string]interface{}{
 					"doctorID": doctorID.String(),
@@ -337,7 +649,7 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 			}
 			// Для кастомных часов используем их вместо обычного расписания
 			if exception.Type == "custom_hours" && exception.CustomStartTime != nil && exception.CustomEndTime != nil {
-				daySlots := s.generateSlotsForDayCheck(date, *exception.CustomStartTime, *exception.CustomEndTime, nil, nil, schedule)
+				daySlots := s.generateSlotsForDayCheck(date, *exception.CustomStartTime, *exception.CustomEndTime, nil, nil, schedule, policy.Buffer())
 				allSlotsToCreate = append(allSlotsToCreate, daySlots...)
 				continue
 			}
@@ -357,18 +669,49 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 		}
 
 		// Собираем слоты для обычного дня
-		daySlots := s.generateSlotsForDayCheck(date, schedule.StartTime, schedule.EndTime, schedule.BreakStart, schedule.BreakEnd, schedule)
+		daySlots := s.generateSlotsForDayCheck(date, schedule.StartTime, schedule.EndTime, schedule.BreakStart, schedule.BreakEnd, schedule, policy.Buffer())
 		allSlotsToCreate = append(allSlotsToCreate, daySlots...)
 	}
 
-	// Шаг 2: Проверяем ВСЕ слоты на конфликты
-	s.logInfo("Checking all slots for conflicts", map[string]interface{}{
-		"doctorID":   doctorID.String(),
-		"totalSlots": len(allSlotsToCreate),
-	})
+	var skipped []models.SkippedSlot
+
+	// Слоты, пересекающиеся с занятым временем из внешних календарей, не создаем
+	if len(allSlotsToCreate) > 0 {
+		busy, err := s.repo.GetDoctorBusyTime(doctorID, allSlotsToCreate[0].startTime, allSlotsToCreate[len(allSlotsToCreate)-1].endTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get doctor busy time: %w", err)
+		}
+		if len(busy) > 0 {
+			freeSlots := allSlotsToCreate[:0]
+			for _, slot := range allSlotsToCreate {
+				if overlapsBusyTime(busy, slot.startTime, slot.endTime) {
+					skipped = append(skipped, models.SkippedSlot{StartTime: slot.startTime, EndTime: slot.endTime, Reason: "busy"})
+					continue
+				}
+				freeSlots = append(freeSlots, slot)
+			}
+			s.logInfo("Skipping slots overlapping busy time", map[string]interface{}{
+				"doctorID":     doctorID.String(),
+				"skippedSlots": len(allSlotsToCreate) - len(freeSlots),
+			})
+			allSlotsToCreate = freeSlots
+		}
+	}
 
+	// Шаг 2: Проверяем слоты на пересечения с существующими (неотмененными) слотами.
+	// По умолчанию генерация отменяется целиком; с SkipConflicts пересекающиеся слоты пропускаются,
+	// а остальные создаются - так автогенерация безопасно повторяет уже сгенерированный день
+	freeSlots := allSlotsToCreate[:0]
 	for _, slot := range allSlotsToCreate {
-		if s.slotExists(schedule.DoctorID, slot.startTime, slot.endTime) {
+		exists, err := s.repo.CheckSlotExists(schedule.DoctorID, slot.startTime, slot.endTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing slots: %w", err)
+		}
+		if !exists {
+			freeSlots = append(freeSlots, slot)
+			continue
+		}
+		if !req.SkipConflicts {
 			s.logError("Slot conflict detected - aborting generation", map[string]interface{}{
 				"doctorID":      doctorID.String(),
 				"conflictStart": slot.startTime.Format("2006-01-02 15:04:05"),
@@ -377,12 +720,15 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 			return nil, fmt.Errorf("cannot generate slots: slot from %s to %s already exists. Please choose a different time period or delete existing slots first",
 				slot.startTime.Format("2006-01-02 15:04"), slot.endTime.Format("15:04"))
 		}
+		skipped = append(skipped, models.SkippedSlot{StartTime: slot.startTime, EndTime: slot.endTime, Reason: "exists"})
 	}
+	allSlotsToCreate = freeSlots
 
-	// Шаг 3: Если все проверки прошли - создаем ВСЕ слоты
-	s.logInfo("No conflicts found - creating all slots", map[string]interface{}{
+	// Шаг 3: Создаем слоты, не пересекающиеся с занятым временем
+	s.logInfo("Creating slots", map[string]interface{}{
 		"doctorID":          doctorID.String(),
 		"totalSlots":        len(allSlotsToCreate),
+		"skippedSlots":      len(skipped),
 		"appointmentFormat": schedule.AppointmentFormat,
 	})
 
@@ -400,6 +746,7 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 			Status:          "available",
 			AppointmentType: appointmentType,
 			ScheduleID:      &schedule.ID,
+			Capacity:        schedule.SlotCapacity,
 		}
 
 		if err := s.repo.CreateAppointment(appointment); err != nil {
@@ -419,27 +766,62 @@ func (s *appointmentService) GenerateSlots(doctorID, scheduleID uuid.UUID, req *
 		"doctorID":          doctorID.String(),
 		"scheduleID":        scheduleID.String(),
 		"totalSlotsCreated": totalSlotsCreated,
+		"skippedSlots":      len(skipped),
 	})
 
 	message := fmt.Sprintf("Генерация завершена успешно: создано %d слотов", totalSlotsCreated)
+	if len(skipped) > 0 {
+		message += fmt.Sprintf(", пропущено %d (время уже занято)", len(skipped))
+	}
 
 	return &models.GenerateSlotsResponse{
 		SlotsCreated: totalSlotsCreated,
+		SlotsSkipped: len(skipped),
+		SkippedSlots: skipped,
 		Message:      message,
 	}, nil
 }
 
+// exceptionForDay - исключение, действующее в день date; выходной важнее измененных часов.
+// Занятое время (busy) день целиком не меняет - оно убирает только пересекающиеся слоты
+func exceptionForDay(exceptions []*models.ScheduleException, date time.Time) *models.ScheduleException {
+	var found *models.ScheduleException
+	for _, exception := range exceptions {
+		if exception.Type == "busy" || !exception.AppliesTo(date) {
+			continue
+		}
+		if exception.Type == "day_off" {
+			return exception
+		}
+		if found == nil {
+			found = exception
+		}
+	}
+	return found
+}
+
 // Структура для хранения информации о слоте, который нужно создать
 type slotToCreate struct {
 	startTime time.Time
 	endTime   time.Time
 }
 
-// generateSlotsForDayCheck - собирает слоты для дня без создания
-func (s *appointmentService) generateSlotsForDayCheck(date time.Time, startTime, endTime string, breakStart, breakEnd *string, schedule *models.DoctorSchedule) []slotToCreate {
-	location := time.Local
+// generateSlotsForDayCheck - собирает слоты для дня без создания, оставляя между ними перерыв buffer.
+// Время расписания трактуется как местное время врача, слоты возвращаются в UTC,
+// поэтому переход на летнее/зимнее время не сдвигает прием
+func (s *appointmentService) generateSlotsForDayCheck(date time.Time, startTime, endTime string, breakStart, breakEnd *string, schedule *models.DoctorSchedule, buffer time.Duration) []slotToCreate {
 	var slots []slotToCreate
 
+	location, err := schedule.Location()
+	if err != nil {
+		s.logError("Invalid schedule timezone", map[string]interface{}{
+			"doctorID": schedule.DoctorID.String(),
+			"timezone": schedule.Timezone,
+			"error":    err.Error(),
+		})
+		return slots
+	}
+
 	// Парсим время начала и конца
 	start, err := time.ParseInLocation("2006-01-02 15:04", date.Format("2006-01-02")+" "+startTime, location)
 	if err != nil {
@@ -493,150 +875,666 @@ func (s *appointmentService) generateSlotsForDayCheck(date time.Time, startTime,
 			}
 		}
 
-		slots = append(slots, slotToCreate{
-			startTime: current,
-			endTime:   slotEnd,
-		})
+		slots = append(slots, slotToCreate{
+			startTime: current.UTC(),
+			endTime:   slotEnd.UTC(),
+		})
+
+		current = slotEnd.Add(buffer)
+	}
+
+	return slots
+}
+
+// === APPOINTMENTS ===
+
+// GetAvailableSlots - свободные слоты врача на дату date в часовом поясе зрителя.
+// Пустой timezone означает UTC; время слотов возвращается в поясе зрителя
+func (s *appointmentService) GetAvailableSlots(doctorID uuid.UUID, date, timezone string) ([]*models.AvailableSlot, error) {
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, timezone)
+		}
+		location = loc
+	}
+
+	startDate, err := time.ParseInLocation("2006-01-02", date, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date format: %v", ErrInvalidInput, err)
+	}
+	// Следующая полночь по местному времени: в день перехода на летнее время сутки короче 24 часов
+	endDate := startDate.AddDate(0, 0, 1)
+
+	appointments, err := s.repo.GetAvailableSlots(doctorID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available slots: %w", err)
+	}
+
+	// Удерживаемые до оплаты слоты показываем отдельным статусом: они освободятся, если оплата не придет
+	held, err := s.repo.GetHeldSlots(doctorID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get held slots: %w", err)
+	}
+	appointments = append(appointments, held...)
+	sort.Slice(appointments, func(i, j int) bool {
+		return appointments[i].StartTime.Before(appointments[j].StartTime)
+	})
+
+	appointments, err = s.filterByBookingPolicy(doctorID, appointments)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]*models.AvailableSlot, len(appointments))
+	for i, appointment := range appointments {
+		duration := int(appointment.EndTime.Sub(appointment.StartTime).Minutes())
+		slots[i] = &models.AvailableSlot{
+			ID:              appointment.ID,
+			StartTime:       appointment.StartTime.In(location),
+			EndTime:         appointment.EndTime.In(location),
+			Duration:        duration,
+			Title:           appointment.Title,
+			AppointmentType: appointment.AppointmentType,
+			Status:          appointment.Status,
+			HeldUntil:       appointment.HeldUntil,
+			Capacity:        appointment.Capacity,
+			SeatsLeft:       appointment.SeatsLeft(),
+		}
+	}
+
+	return slots, nil
+}
+
+func (s *appointmentService) BookAppointment(patientID, appointmentID uuid.UUID, req *models.BookAppointmentRequest) (*models.AppointmentResponse, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.bookAppointment(patientID, appointment, req)
+}
+
+// bookAppointment - запись пациента на загруженный слот по правилам врача:
+// групповое занятие, удержание до оплаты или сразу бронирование
+func (s *appointmentService) bookAppointment(patientID uuid.UUID, appointment *models.Appointment, req *models.BookAppointmentRequest) (*models.AppointmentResponse, error) {
+	// Быстрый отказ без записи в БД; окончательно доступность проверяет BookSlot
+	// (для группового занятия - JoinGroupSession)
+	if appointment.SeatsLeft() <= 0 {
+		return nil, ErrSlotTaken
+	}
+
+	appointmentType, err := s.bookingType(appointment.AppointmentType, req.AppointmentType)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := s.enforceBookingPolicy(appointment.DoctorID, patientID, appointment.StartTime, appointment.EndTime, appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.IsGroup() {
+		if err := s.checkGroupPricing(appointment.DoctorID); err != nil {
+			return nil, err
+		}
+		return s.joinGroupSession(patientID, appointment, appointmentType, req.PatientNotes, limits)
+	}
+
+	// Платный прием сначала удерживается за пациентом и подтверждается оплатой
+	price, err := s.bookingPrice(appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	if price > 0 {
+		return s.holdAppointment(patientID, appointment, appointmentType, req.PatientNotes, price, limits)
+	}
+
+	if err := appointment.Book(patientID, appointmentType, req.PatientNotes); err != nil {
+		return nil, ErrSlotTaken
+	}
+	s.assignMeeting(appointment)
+
+	if err := s.repo.BookSlot(appointment, limits); err != nil {
+		if policyErr := bookingLimitsError(limits, err); policyErr != nil {
+			return nil, policyErr
+		}
+		if errors.Is(err, repository.ErrSlotTaken) {
+			s.logInfo("Slot already taken by concurrent booking", map[string]interface{}{
+				"patientID":     patientID.String(),
+				"appointmentID": appointment.ID.String(),
+			})
+			return nil, ErrSlotTaken
+		}
+		return nil, fmt.Errorf("failed to book appointment: %w", err)
+	}
+
+	s.publishEvent(models.EventAppointmentBooked, appointment, appointment.PatientID)
+
+	return s.appointmentToResponse(appointment), nil
+}
+
+// bookingType - формат записи на слот: запрошенный или по умолчанию для слота
+func (s *appointmentService) bookingType(slotType, requestedType string) (string, error) {
+	appointmentType := requestedType
+	if appointmentType == "" {
+		// Если тип не указан, выбираем по умолчанию в зависимости от слота
+		if slotType == "both" {
+			appointmentType = "offline" // По умолчанию для "both" выбираем offline
+		} else {
+			appointmentType = slotType // Используем тип слота
+		}
+	}
+
+	// Проверяем совместимость запрашиваемого типа со слотом
+	if !s.isAppointmentTypeCompatible(slotType, appointmentType) {
+		return "", fmt.Errorf("%w: appointment type '%s' is not compatible with slot type '%s'", ErrInvalidInput, appointmentType, slotType)
+	}
+	return appointmentType, nil
+}
+
+// isAppointmentTypeCompatible проверяет совместимость типа записи со слотом
+func (s *appointmentService) isAppointmentTypeCompatible(slotType, requestedType string) bool {
+	// Если слот "both", то можно забронировать любой тип
+	if slotType == "both" {
+		return requestedType == "offline" || requestedType == "online"
+	}
+
+	// Для остальных случаев типы должны совпадать
+	return slotType == requestedType
+}
+
+func (s *appointmentService) CancelAppointment(patientID, appointmentID uuid.UUID) error {
+	appointment, err := s.repo.GetAppointmentByID(appointmentID)
+	if err != nil {
+		return fmt.Errorf("appointment not found: %w", err)
+	}
+
+	if appointment.IsGroup() {
+		return s.leaveGroupSession(patientID, appointment)
+	}
+
+	// Проверяем что запись принадлежит этому пациенту
+	if appointment.PatientID == nil || *appointment.PatientID != patientID {
+		return errors.New("appointment doesn't belong to this patient or is not booked")
+	}
+
+	// Удерживаемый до оплаты слот просто освобождается
+	if appointment.Status == models.StatusHeld {
+		return s.releaseHold(appointment, &patientID, "patient", "hold canceled by patient", "canceled")
+	}
+
+	policy, err := s.bookingPolicy(appointment.DoctorID)
+	if err != nil {
+		return err
+	}
+	if err := checkCancelCutoff(policy, appointment.StartTime, time.Now()); err != nil {
+		return err
+	}
+
+	// Проверяем что запись можно отменить (не уже отменена и не завершена)
+	if err := appointment.Cancel(patientID, "patient", ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+
+	if err := s.repo.CancelBooked(appointment); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return fmt.Errorf("%w: appointment was changed concurrently", ErrInvalidStatus)
+		}
+		return fmt.Errorf("failed to cancel appointment: %w", err)
+	}
+
+	s.reopenFollowUpProposal(appointment.ID, &patientID, appointment.PreviousAppointmentID)
+
+	s.publishEvent(models.EventAppointmentCanceled, appointment, &patientID)
+
+	return nil
+}
+
+const (
+	// alternativeSlotsLimit - сколько свободных слотов предлагать пациенту при отмене врачом
+	alternativeSlotsLimit = 5
+	// alternativeSlotsHorizon - на сколько дней вперед искать альтернативные слоты
+	alternativeSlotsHorizon = 14
+)
+
+// DoctorCancelAppointment - отмена одной записи врачом с обязательной причиной
+func (s *appointmentService) DoctorCancelAppointment(doctorID, appointmentID uuid.UUID, req *models.DoctorCancelRequest) (*models.AppointmentResponse, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this doctor", ErrForbidden)
+	}
+
+	// Отменяется только запись пациента. Свободный слот закрывается исключением расписания,
+	// а удерживаемый до оплаты освободится сам, когда истечет удержание
+	if appointment.Status != models.StatusBooked && appointment.Status != models.StatusCheckedIn {
+		return nil, fmt.Errorf("%w: only booked appointments can be canceled by doctor, current status is %s", ErrInvalidStatus, appointment.Status)
+	}
+
+	if err := appointment.Cancel(doctorID, "doctor", req.Reason); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+
+	if err := s.repo.CancelBooked(appointment); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, fmt.Errorf("%w: appointment was changed concurrently", ErrInvalidStatus)
+		}
+		return nil, fmt.Errorf("failed to cancel appointment: %w", err)
+	}
+
+	s.logInfo("Appointment canceled by doctor", map[string]interface{}{
+		"doctorID":      doctorID.String(),
+		"appointmentID": appointmentID.String(),
+	})
+
+	s.publishDoctorCancellation(appointment, s.findAlternativeSlots(doctorID))
+
+	return s.appointmentToResponse(appointment), nil
+}
+
+// DoctorCancelAppointments - отмена врачом всех будущих записей за период (например, на время болезни).
+// Свободные слоты периода закрываются, чтобы на них не записались новые пациенты
+func (s *appointmentService) DoctorCancelAppointments(doctorID uuid.UUID, req *models.DoctorBulkCancelRequest) (*models.DoctorBulkCancelResponse, error) {
+	// Даты периода - дни по местному времени врача, как в его расписании
+	location := s.doctorLocation(doctorID)
+	startDate, err := time.ParseInLocation("2006-01-02", req.StartDate, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid start date: %v", ErrInvalidInput, err)
+	}
+	endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid end date: %v", ErrInvalidInput, err)
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("%w: end date is before start date", ErrInvalidInput)
+	}
+
+	// Период включительно; прошедшие записи не трогаем
+	from := startDate
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	// Следующая полночь по местному времени: в день перехода на летнее время сутки короче 24 часов
+	to := endDate.AddDate(0, 0, 1)
+
+	// Период закрывается выходным: генерация (ручная и автоматическая) не создаст в нем новых слотов
+	// взамен закрытых. Чтобы снова открыть запись, врач удаляет это исключение
+	periodStart := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+	closure := &models.ScheduleException{
+		DoctorID: doctorID,
+		Date:     periodStart,
+		EndDate:  &periodEnd,
+		Type:     "day_off",
+		Reason:   req.Reason,
+	}
+	if err := s.repo.CreateException(closure); err != nil {
+		return nil, fmt.Errorf("failed to close period: %w", err)
+	}
+
+	closed, err := s.repo.CloseAvailableSlots(doctorID, from, to, doctorID, req.Reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close available slots: %w", err)
+	}
+
+	appointments, err := s.repo.GetDoctorBookedAppointments(doctorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booked appointments: %w", err)
+	}
+
+	response := &models.DoctorBulkCancelResponse{
+		ClosedSlots:    closed,
+		ExceptionID:    closure.ID,
+		AppointmentIDs: make([]uuid.UUID, 0, len(appointments)),
+	}
+
+	// Свободные слоты периода уже закрыты, поэтому альтернативы находятся вне его
+	alternatives := s.findAlternativeSlots(doctorID)
+
+	for _, appointment := range appointments {
+		if err := appointment.Cancel(doctorID, "doctor", req.Reason); err != nil {
+			continue
+		}
+
+		if err := s.repo.CancelBooked(appointment); err != nil {
+			// Запись могли отменить параллельно - пропускаем ее, остальные продолжаем отменять
+			s.logError("Failed to cancel appointment in bulk", map[string]interface{}{
+				"doctorID":      doctorID.String(),
+				"appointmentID": appointment.ID.String(),
+				"error":         err.Error(),
+			})
+			continue
+		}
+
+		response.AppointmentIDs = append(response.AppointmentIDs, appointment.ID)
+		s.publishDoctorCancellation(appointment, alternatives)
+	}
+	response.CanceledCount = len(response.AppointmentIDs)
+
+	s.logInfo("Doctor appointments canceled for period", map[string]interface{}{
+		"doctorID":      doctorID.String(),
+		"startDate":     req.StartDate,
+		"endDate":       req.EndDate,
+		"canceledCount": response.CanceledCount,
+		"closedSlots":   closed,
+	})
+
+	return response, nil
+}
+
+// findAlternativeSlots - ближайшие свободные слоты врача, которые можно предложить пациенту взамен
+func (s *appointmentService) findAlternativeSlots(doctorID uuid.UUID) []models.SlotOption {
+	now := time.Now()
+	slots, err := s.repo.GetAvailableSlots(doctorID, now, now.AddDate(0, 0, alternativeSlotsHorizon))
+	if err != nil {
+		s.logError("Failed to get alternative slots", map[string]interface{}{
+			"doctorID": doctorID.String(),
+			"error":    err.Error(),
+		})
+		return nil
+	}
+
+	options := make([]models.SlotOption, 0, alternativeSlotsLimit)
+	for _, slot := range slots {
+		if len(options) == alternativeSlotsLimit {
+			break
+		}
+		options = append(options, models.SlotOption{
+			ID:        slot.ID,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+		})
+	}
+	return options
+}
+
+// publishDoctorCancellation - уведомляет пациента об отмене врачом с причиной и альтернативными слотами
+func (s *appointmentService) publishDoctorCancellation(appointment *models.Appointment, alternatives []models.SlotOption) {
+	if s.messages == nil {
+		return
+	}
+
+	patientIDs := []uuid.UUID{}
+	if appointment.PatientID != nil {
+		patientIDs = append(patientIDs, *appointment.PatientID)
+	} else if appointment.IsGroup() {
+		patientIDs = s.participantIDs(appointment)
+	}
+
+	for i := range patientIDs {
+		event := newAppointmentEvent(models.EventAppointmentCanceled, appointment, &patientIDs[i])
+		event.AlternativeSlots = alternatives
+		s.sendEvent(event)
+	}
+}
+
+// RescheduleAppointment - атомарно переносит запись пациента на другой свободный слот того же врача.
+// Жалобы пациента и формат приема сохраняются, старый слот снова становится доступным
+func (s *appointmentService) RescheduleAppointment(patientID, appointmentID uuid.UUID, req *models.RescheduleAppointmentRequest) (*models.AppointmentResponse, error) {
+	current, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.IsGroup() {
+		return nil, fmt.Errorf("%w: a seat in a group session cannot be rescheduled, cancel it and book another session", ErrInvalidInput)
+	}
+
+	if current.PatientID == nil || *current.PatientID != patientID {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this patient", ErrForbidden)
+	}
+
+	if current.Status != models.StatusBooked {
+		return nil, fmt.Errorf("%w: cannot reschedule appointment with status '%s'", ErrInvalidStatus, current.Status)
+	}
 
-		current = slotEnd
+	if req.TargetSlotID == current.ID {
+		return nil, fmt.Errorf("%w: target slot is the current appointment", ErrInvalidInput)
 	}
 
-	return slots
-}
+	now := time.Now()
+	if current.StartTime.Sub(now) < s.options.RescheduleMinNotice {
+		return nil, fmt.Errorf("%w: appointment can be rescheduled no later than %s before it starts",
+			ErrNoticeTooShort, s.options.RescheduleMinNotice)
+	}
 
-// slotExists простая проверка существования слота
-func (s *appointmentService) slotExists(doctorID uuid.UUID, startTime, endTime time.Time) bool {
-	exists, err := s.repo.CheckSlotExists(doctorID, startTime, endTime)
+	target, err := s.getAppointment(req.TargetSlotID)
 	if err != nil {
-		s.logError("Error checking slot existence", map[string]interface{}{
-			"doctorID":  doctorID.String(),
-			"startTime": startTime.Format("2006-01-02 15:04:05"),
-			"endTime":   endTime.Format("2006-01-02 15:04:05"),
-			"error":     err.Error(),
-		})
-		return true // В случае ошибки считаем что слот существует (безопасно)
+		return nil, fmt.Errorf("target slot: %w", err)
 	}
-	return exists
-}
 
-// === APPOINTMENTS ===
+	if target.DoctorID != current.DoctorID {
+		return nil, fmt.Errorf("%w: target slot belongs to another doctor", ErrInvalidInput)
+	}
 
-func (s *appointmentService) GetAvailableSlots(doctorID uuid.UUID, date string) ([]*models.AvailableSlot, error) {
-	startDate, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+	if target.IsGroup() {
+		return nil, fmt.Errorf("%w: appointment cannot be rescheduled to a group session", ErrInvalidInput)
 	}
-	endDate := startDate.AddDate(0, 0, 1)
 
-	appointments, err := s.repo.GetAvailableSlots(doctorID, startDate, endDate)
+	if !target.StartTime.After(now) {
+		return nil, fmt.Errorf("%w: target slot is in the past", ErrInvalidInput)
+	}
+
+	if !target.IsAvailable() {
+		return nil, ErrSlotTaken
+	}
+
+	if !s.isAppointmentTypeCompatible(target.AppointmentType, current.AppointmentType) {
+		return nil, fmt.Errorf("%w: appointment type '%s' is not compatible with slot type '%s'",
+			ErrInvalidInput, current.AppointmentType, target.AppointmentType)
+	}
+
+	// Перенос не добавляет пациенту записей, поэтому лимит предстоящих записей не проверяется;
+	// освобождаемая запись не мешает перерыву вокруг новой
+	policy, err := s.bookingPolicy(target.DoctorID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get available slots: %w", err)
+		return nil, err
+	}
+	if err := checkBookingTime(policy, target.StartTime, now); err != nil {
+		return nil, err
+	}
+	if err := s.checkBuffer(policy, target.StartTime, target.EndTime, target.ID, current.ID); err != nil {
+		return nil, err
 	}
 
-	slots := make([]*models.AvailableSlot, len(appointments))
-	for i, appointment := range appointments {
-		duration := int(appointment.EndTime.Sub(appointment.StartTime).Minutes())
-		slots[i] = &models.AvailableSlot{
-			ID:              appointment.ID,
-			StartTime:       appointment.StartTime,
-			EndTime:         appointment.EndTime,
-			Duration:        duration,
-			Title:           appointment.Title,
-			AppointmentType: appointment.AppointmentType,
+	previousStart, previousEnd := current.StartTime, current.EndTime
+
+	if err := target.Book(patientID, current.AppointmentType, current.PatientNotes); err != nil {
+		return nil, ErrSlotTaken
+	}
+	// Перенесенный повторный прием остается в цепочке визитов
+	target.PreviousAppointmentID = current.PreviousAppointmentID
+	s.assignMeeting(target)
+	if err := current.Release(patientID, "patient", "rescheduled to "+target.ID.String()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+
+	if err := s.repo.RescheduleAppointment(current, target, patientID); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, ErrSlotTaken
 		}
+		s.logError("Failed to reschedule appointment", map[string]interface{}{
+			"patientID":     patientID.String(),
+			"appointmentID": appointmentID.String(),
+			"targetSlotID":  req.TargetSlotID.String(),
+			"error":         err.Error(),
+		})
+		return nil, fmt.Errorf("failed to reschedule appointment: %w", err)
 	}
 
-	return slots, nil
+	s.logInfo("Appointment rescheduled", map[string]interface{}{
+		"patientID":     patientID.String(),
+		"appointmentID": appointmentID.String(),
+		"targetSlotID":  target.ID.String(),
+	})
+
+	if s.messages != nil {
+		event := newAppointmentEvent(models.EventAppointmentRescheduled, target, &patientID)
+		event.PreviousAppointmentID = &current.ID
+		event.PreviousStartTime = &previousStart
+		event.PreviousEndTime = &previousEnd
+		s.sendEvent(event)
+	}
+
+	return s.appointmentToResponse(target), nil
 }
 
-func (s *appointmentService) BookAppointment(patientID, appointmentID uuid.UUID, req *models.BookAppointmentRequest) (*models.AppointmentResponse, error) {
-	appointment, err := s.repo.GetAppointmentByID(appointmentID)
+func (s *appointmentService) CompleteAppointment(doctorID, appointmentID uuid.UUID, req *models.CompleteAppointmentRequest) (*models.AppointmentResponse, error) {
+	appointment, err := s.getAppointment(appointmentID)
 	if err != nil {
-		return nil, fmt.Errorf("appointment not found: %w", err)
+		return nil, err
 	}
 
-	if !appointment.IsAvailable() {
-		return nil, errors.New("appointment is not available")
+	// Завершить прием может только врач, к которому записан пациент
+	if appointment.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this doctor", ErrForbidden)
 	}
 
-	appointmentType := req.AppointmentType
-	if appointmentType == "" {
-		// Если тип не указан, выбираем по умолчанию в зависимости от слота
-		if appointment.AppointmentType == "both" {
-			appointmentType = "offline" // По умолчанию для "both" выбираем offline
-		} else {
-			appointmentType = appointment.AppointmentType // Используем тип слота
-		}
+	if !models.CanTransition(appointment.Status, models.StatusCompleted) {
+		return nil, fmt.Errorf("%w: cannot complete appointment with status '%s'", ErrInvalidStatus, appointment.Status)
 	}
 
-	// Проверяем совместимость запрашиваемого типа со слотом
-	if !s.isAppointmentTypeCompatible(appointment.AppointmentType, appointmentType) {
-		return nil, fmt.Errorf("appointment type '%s' is not compatible with slot type '%s'", appointmentType, appointment.AppointmentType)
+	// Нельзя завершить прием, который еще не начался
+	if appointment.StartTime.After(time.Now()) {
+		return nil, fmt.Errorf("%w: appointment starts at %s", ErrAppointmentNotStarted, appointment.StartTime.Format("2006-01-02 15:04"))
 	}
 
-	appointment.Book(patientID, appointmentType, req.PatientNotes)
+	visit := models.VisitNotes{
+		Diagnosis:       req.Diagnosis,
+		Recommendations: req.Recommendations,
+	}
 
-	if err := s.repo.UpdateAppointment(appointment); err != nil {
-		return nil, fmt.Errorf("failed to book appointment: %w", err)
+	if req.FollowUpDate != nil && *req.FollowUpDate != "" {
+		followUp, err := time.Parse("2006-01-02", *req.FollowUpDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid follow-up date: %v", ErrInvalidInput, err)
+		}
+		if !followUp.After(appointment.StartTime) {
+			return nil, fmt.Errorf("%w: follow-up date must be after the appointment date", ErrInvalidInput)
+		}
+		visit.FollowUpDate = &followUp
 	}
 
-	return s.appointmentToResponse(appointment), nil
-}
+	if err := appointment.Complete(doctorID, req.DoctorNotes, visit); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
 
-// isAppointmentTypeCompatible проверяет совместимость типа записи со слотом
-func (s *appointmentService) isAppointmentTypeCompatible(slotType, requestedType string) bool {
-	// Если слот "both", то можно забронировать любой тип
-	if slotType == "both" {
-		return requestedType == "offline" || requestedType == "online"
+	if err := s.repo.ChangeStatus(appointment, "doctor_notes", "diagnosis", "recommendations", "follow_up_date", "completed_at"); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, fmt.Errorf("%w: appointment was changed concurrently", ErrInvalidStatus)
+		}
+		s.logError("Failed to complete appointment", map[string]interface{}{
+			"doctorID":      doctorID.String(),
+			"appointmentID": appointmentID.String(),
+			"error":         err.Error(),
+		})
+		return nil, fmt.Errorf("failed to complete appointment: %w", err)
 	}
 
-	// Для остальных случаев типы должны совпадать
-	return slotType == requestedType
+	s.logInfo("Appointment completed", map[string]interface{}{
+		"doctorID":      doctorID.String(),
+		"appointmentID": appointmentID.String(),
+		"hasFollowUp":   visit.FollowUpDate != nil,
+	})
+
+	s.publishEvent(models.EventAppointmentCompleted, appointment, appointment.PatientID)
+
+	return s.appointmentToResponse(appointment), nil
 }
 
-func (s *appointmentService) CancelAppointment(patientID, appointmentID uuid.UUID) error {
-	appointment, err := s.repo.GetAppointmentByID(appointmentID)
+func (s *appointmentService) GetDoctorAppointments(doctorID uuid.UUID, req *models.AppointmentListRequest) ([]*models.AppointmentResponse, int64, error) {
+	filter, err := s.appointmentListFilter(req)
 	if err != nil {
-		return fmt.Errorf("appointment not found: %w", err)
+		return nil, 0, err
 	}
+	filter.DoctorID = &doctorID
 
-	// Проверяем что запись принадлежит этому пациенту
-	if appointment.PatientID == nil || *appointment.PatientID != patientID {
-		return errors.New("appointment doesn't belong to this patient or is not booked")
-	}
+	return s.listAppointments(filter, "failed to get doctor appointments")
+}
 
-	// Проверяем что запись можно отменить (не уже отменена и не завершена)
-	if appointment.Status != "booked" {
-		return fmt.Errorf("cannot cancel appointment with status '%s'", appointment.Status)
+func (s *appointmentService) GetPatientAppointments(patientID uuid.UUID, req *models.AppointmentListRequest) ([]*models.AppointmentResponse, int64, error) {
+	filter, err := s.appointmentListFilter(req)
+	if err != nil {
+		return nil, 0, err
 	}
+	filter.PatientID = &patientID
 
-	appointment.Cancel()
+	return s.listAppointments(filter, "failed to get patient appointments")
+}
 
-	if err := s.repo.UpdateAppointment(appointment); err != nil {
-		return fmt.Errorf("failed to cancel appointment: %w", err)
+// appointmentListFilter переводит параметры запроса списка в условия выборки.
+// req.Page и req.Limit нормализуются, чтобы обработчик вернул их в ответе
+func (s *appointmentService) appointmentListFilter(req *models.AppointmentListRequest) (repository.AppointmentListFilter, error) {
+	var filter repository.AppointmentListFilter
+
+	for _, status := range strings.Split(req.Status, ",") {
+		status = strings.TrimSpace(status)
+		if status == "" {
+			continue
+		}
+		if !models.IsKnownStatus(status) {
+			return filter, fmt.Errorf("%w: unknown status '%s'", ErrInvalidInput, status)
+		}
+		filter.Statuses = append(filter.Statuses, status)
 	}
 
-	return nil
-}
+	location := time.UTC
+	if req.Timezone != "" {
+		loc, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return filter, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, req.Timezone)
+		}
+		location = loc
+	}
+	if req.DateFrom != "" {
+		from, err := time.ParseInLocation("2006-01-02", req.DateFrom, location)
+		if err != nil {
+			return filter, fmt.Errorf("%w: invalid date_from: %v", ErrInvalidInput, err)
+		}
+		filter.From = &from
+	}
+	if req.DateTo != "" {
+		dateTo, err := time.ParseInLocation("2006-01-02", req.DateTo, location)
+		if err != nil {
+			return filter, fmt.Errorf("%w: invalid date_to: %v", ErrInvalidInput, err)
+		}
+		to := dateTo.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("%w: date_to must not be before date_from", ErrInvalidInput)
+	}
 
-func (s *appointmentService) GetDoctorAppointments(doctorID uuid.UUID) ([]*models.AppointmentResponse, error) {
-	appointments, err := s.repo.GetDoctorAppointments(doctorID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get doctor appointments: %w", err)
+	if req.When != "" {
+		upcoming := req.When == "upcoming"
+		filter.Upcoming = &upcoming
+		filter.Now = time.Now()
 	}
+	// Прошедшие записи по умолчанию показываем от последних
+	filter.Desc = req.Sort == "desc" || (req.Sort == "" && req.When == "past")
 
-	responses := make([]*models.AppointmentResponse, len(appointments))
-	for i, appointment := range appointments {
-		responses[i] = s.appointmentToResponse(appointment)
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = defaultPageLimit
 	}
+	filter.Offset = (req.Page - 1) * req.Limit
+	filter.Limit = req.Limit
 
-	return responses, nil
+	return filter, nil
 }
 
-func (s *appointmentService) GetPatientAppointments(patientID uuid.UUID) ([]*models.AppointmentResponse, error) {
-	appointments, err := s.repo.GetPatientAppointments(patientID)
+// listAppointments выбирает страницу записей и общее их количество
+func (s *appointmentService) listAppointments(filter repository.AppointmentListFilter, errContext string) ([]*models.AppointmentResponse, int64, error) {
+	appointments, total, err := s.repo.ListAppointments(filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get patient appointments: %w", err)
+		return nil, 0, fmt.Errorf("%s: %w", errContext, err)
 	}
 
 	responses := make([]*models.AppointmentResponse, len(appointments))
@@ -644,7 +1542,7 @@ func (s *appointmentService) GetPatientAppointments(patientID uuid.UUID) ([]*mod
 		responses[i] = s.appointmentToResponse(appointment)
 	}
 
-	return responses, nil
+	return responses, total, nil
 }
 
 // === EXCEPTIONS ===
@@ -652,23 +1550,244 @@ func (s *appointmentService) GetPatientAppointments(patientID uuid.UUID) ([]*mod
 func (s *appointmentService) AddException(doctorID uuid.UUID, req *models.AddExceptionRequest) (*models.ExceptionResponse, error) {
 	date, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, fmt.Errorf("%w: invalid date format: %v", ErrInvalidInput, err)
 	}
 
 	exception := &models.ScheduleException{
 		DoctorID:        doctorID,
 		Date:            date,
 		Type:            req.Type,
+		Recurrence:      req.Recurrence,
 		CustomStartTime: req.CustomStartTime,
 		CustomEndTime:   req.CustomEndTime,
 		Reason:          req.Reason,
 	}
 
+	if req.EndDate != nil && *req.EndDate != "" {
+		endDate, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid end date format: %v", ErrInvalidInput, err)
+		}
+		exception.EndDate = &endDate
+	}
+
+	if err := validateException(exception); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.CreateException(exception); err != nil {
 		return nil, fmt.Errorf("failed to create exception: %w", err)
 	}
 
-	return s.exceptionToResponse(exception), nil
+	return s.applyExceptionToSlots(exception)
+}
+
+// UpdateException - изменение исключения; слоты, попавшие под новые условия, обрабатываются так же, как при создании
+func (s *appointmentService) UpdateException(doctorID, exceptionID uuid.UUID, req *models.UpdateExceptionRequest) (*models.ExceptionResponse, error) {
+	exception, err := s.repo.GetExceptionByID(exceptionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExceptionNotFound, err)
+	}
+
+	if exception.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: exception doesn't belong to this doctor", ErrForbidden)
+	}
+
+	if exception.Source != "" {
+		return nil, fmt.Errorf("%w: imported busy time is updated from its calendar", ErrInvalidInput)
+	}
+
+	if req.Date != nil {
+		date, err := time.Parse("2006-01-02", *req.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date format: %v", ErrInvalidInput, err)
+		}
+		exception.Date = date
+	}
+	if req.EndDate != nil {
+		if *req.EndDate == "" {
+			exception.EndDate = nil
+		} else {
+			endDate, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid end date format: %v", ErrInvalidInput, err)
+			}
+			exception.EndDate = &endDate
+		}
+	}
+	if req.Recurrence != nil {
+		exception.Recurrence = *req.Recurrence
+		if exception.Recurrence == "none" {
+			exception.Recurrence = models.RecurrenceNone
+		}
+	}
+	if req.Type != nil {
+		exception.Type = *req.Type
+	}
+	if req.CustomStartTime != nil {
+		exception.CustomStartTime = req.CustomStartTime
+	}
+	if req.CustomEndTime != nil {
+		exception.CustomEndTime = req.CustomEndTime
+	}
+	if req.Reason != nil {
+		exception.Reason = *req.Reason
+	}
+
+	if err := validateException(exception); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateException(exception); err != nil {
+		return nil, fmt.Errorf("failed to update exception: %w", err)
+	}
+
+	return s.applyExceptionToSlots(exception)
+}
+
+// DeleteException - удаление исключения. Удаленные ранее слоты не восстанавливаются,
+// их можно сгенерировать заново через generate-slots
+func (s *appointmentService) DeleteException(doctorID, exceptionID uuid.UUID) error {
+	exception, err := s.repo.GetExceptionByID(exceptionID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExceptionNotFound, err)
+	}
+
+	if exception.DoctorID != doctorID {
+		return fmt.Errorf("%w: exception doesn't belong to this doctor", ErrForbidden)
+	}
+
+	if err := s.repo.DeleteException(exceptionID); err != nil {
+		return fmt.Errorf("failed to delete exception: %w", err)
+	}
+
+	s.logInfo("Schedule exception deleted", map[string]interface{}{
+		"doctorID":    doctorID.String(),
+		"exceptionID": exceptionID.String(),
+	})
+
+	return nil
+}
+
+// validateException - проверка согласованности полей исключения
+func validateException(exception *models.ScheduleException) error {
+	if exception.EndDate != nil && exception.EndDate.Before(exception.Date) {
+		return fmt.Errorf("%w: end date is before start date", ErrInvalidInput)
+	}
+
+	if exception.Type == "custom_hours" {
+		if exception.CustomStartTime == nil || exception.CustomEndTime == nil {
+			return fmt.Errorf("%w: custom_hours exception requires custom_start_time and custom_end_time", ErrInvalidInput)
+		}
+		start, err := time.Parse("15:04", *exception.CustomStartTime)
+		if err != nil {
+			return fmt.Errorf("%w: invalid custom start time: %v", ErrInvalidInput, err)
+		}
+		end, err := time.Parse("15:04", *exception.CustomEndTime)
+		if err != nil {
+			return fmt.Errorf("%w: invalid custom end time: %v", ErrInvalidInput, err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("%w: custom end time must be after start time", ErrInvalidInput)
+		}
+	}
+
+	return nil
+}
+
+// applyExceptionToSlots - приводит уже сгенерированные будущие слоты в соответствие с исключением:
+// свободные слоты, попавшие в выходной или за пределы измененных часов, удаляются,
+// а забронированные возвращаются врачу в отчете - их нужно отменить или перенести
+func (s *appointmentService) applyExceptionToSlots(exception *models.ScheduleException) (*models.ExceptionResponse, error) {
+	response := s.exceptionToResponse(exception)
+
+	// Запас в сутки с обеих сторон: слоты хранятся в UTC, а даты исключения - местные даты врача
+	from := exception.Date.AddDate(0, 0, -1)
+	if now := time.Now(); from.Before(now) {
+		from = now
+	}
+	var until *time.Time
+	if exception.EndDate != nil {
+		end := exception.EndDate.AddDate(0, 0, 2)
+		until = &end
+	}
+
+	slots, err := s.repo.GetDoctorSlotsFrom(exception.DoctorID, from, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slots affected by exception: %w", err)
+	}
+
+	// Местный день слота определяется по часовому поясу его расписания
+	locations := make(map[uuid.UUID]*time.Location)
+	schedules, err := s.repo.GetDoctorSchedules(exception.DoctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor schedules: %w", err)
+	}
+	for _, schedule := range schedules {
+		if location, err := schedule.Location(); err == nil {
+			locations[schedule.ID] = location
+		}
+	}
+
+	var freeSlotIDs []uuid.UUID
+	for _, slot := range slots {
+		location := time.UTC
+		if slot.ScheduleID != nil {
+			if scheduleLocation, ok := locations[*slot.ScheduleID]; ok {
+				location = scheduleLocation
+			}
+		}
+
+		localStart := slot.StartTime.In(location)
+		if !exception.AppliesTo(localStart) || !exceptionBlocksSlot(exception, localStart, slot.EndTime.In(location)) {
+			continue
+		}
+
+		switch slot.Status {
+		case models.StatusAvailable:
+			freeSlotIDs = append(freeSlotIDs, slot.ID)
+		case models.StatusHeld:
+			response.HeldAppointments = append(response.HeldAppointments, s.appointmentToResponse(slot))
+		default:
+			response.BookedAppointments = append(response.BookedAppointments, s.appointmentToResponse(slot))
+		}
+	}
+
+	removed, err := s.repo.DeleteAvailableSlots(freeSlotIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove slots affected by exception: %w", err)
+	}
+	response.RemovedSlots = int(removed)
+
+	s.logInfo("Schedule exception applied to generated slots", map[string]interface{}{
+		"doctorID":           exception.DoctorID.String(),
+		"exceptionID":        exception.ID.String(),
+		"removedSlots":       removed,
+		"bookedAppointments": len(response.BookedAppointments),
+		"heldAppointments":   len(response.HeldAppointments),
+	})
+
+	return response, nil
+}
+
+// exceptionBlocksSlot - попадает ли слот (в местном времени) под действие исключения в его день
+func exceptionBlocksSlot(exception *models.ScheduleException, localStart, localEnd time.Time) bool {
+	if exception.Type != "custom_hours" || exception.CustomStartTime == nil || exception.CustomEndTime == nil {
+		return true
+	}
+
+	day := localStart.Format("2006-01-02")
+	windowStart, err := time.ParseInLocation("2006-01-02 15:04", day+" "+*exception.CustomStartTime, localStart.Location())
+	if err != nil {
+		return false
+	}
+	windowEnd, err := time.ParseInLocation("2006-01-02 15:04", day+" "+*exception.CustomEndTime, localStart.Location())
+	if err != nil {
+		return false
+	}
+
+	// В измененные часы слот должен целиком помещаться в новое окно
+	return localStart.Before(windowStart) || localEnd.After(windowEnd)
 }
 
 func (s *appointmentService) GetDoctorExceptions(doctorID uuid.UUID, startDate, endDate string) ([]*models.ExceptionResponse, error) {
@@ -710,7 +1829,11 @@ func (s *appointmentService) scheduleToResponse(schedule *models.DoctorSchedule)
 		SlotDuration:      schedule.SlotDuration,
 		SlotTitle:         schedule.SlotTitle,
 		AppointmentFormat: schedule.AppointmentFormat,
+		SlotCapacity:      schedule.SlotCapacity,
 		IsActive:          schedule.IsActive,
+		Timezone:          schedule.Timezone,
+		GenerateDaysAhead: schedule.GenerateDaysAhead,
+		GeneratedUntil:    schedule.GeneratedUntil,
 		CreatedAt:         schedule.CreatedAt,
 		UpdatedAt:         schedule.UpdatedAt,
 	}
@@ -718,20 +1841,35 @@ func (s *appointmentService) scheduleToResponse(schedule *models.DoctorSchedule)
 
 func (s *appointmentService) appointmentToResponse(appointment *models.Appointment) *models.AppointmentResponse {
 	return &models.AppointmentResponse{
-		ID:              appointment.ID,
-		StartTime:       appointment.StartTime,
-		EndTime:         appointment.EndTime,
-		DoctorID:        appointment.DoctorID,
-		PatientID:       appointment.PatientID,
-		Title:           appointment.Title,
-		Status:          appointment.Status,
-		AppointmentType: appointment.AppointmentType,
-		MeetingLink:     appointment.MeetingLink,
-		MeetingID:       appointment.MeetingID,
-		PatientNotes:    appointment.PatientNotes,
-		DoctorNotes:     appointment.DoctorNotes,
-		CreatedAt:       appointment.CreatedAt,
-		UpdatedAt:       appointment.UpdatedAt,
+		ID:                    appointment.ID,
+		StartTime:             appointment.StartTime,
+		EndTime:               appointment.EndTime,
+		DoctorID:              appointment.DoctorID,
+		PatientID:             appointment.PatientID,
+		Title:                 appointment.Title,
+		Status:                appointment.Status,
+		AppointmentType:       appointment.AppointmentType,
+		MeetingLink:           appointment.MeetingLink,
+		MeetingID:             appointment.MeetingID,
+		PatientNotes:          appointment.PatientNotes,
+		DoctorNotes:           appointment.DoctorNotes,
+		Diagnosis:             appointment.Diagnosis,
+		Recommendations:       appointment.Recommendations,
+		FollowUpDate:          appointment.FollowUpDate,
+		CompletedAt:           appointment.CompletedAt,
+		CanceledByRole:        appointment.CanceledByRole,
+		CancelReason:          appointment.CancelReason,
+		CanceledAt:            appointment.CanceledAt,
+		HeldUntil:             appointment.HeldUntil,
+		PaymentAmount:         appointment.PaymentAmount,
+		PaymentStatus:         appointment.PaymentStatus,
+		PaymentURL:            appointment.PaymentURL,
+		VisitTypeID:           appointment.VisitTypeID,
+		Capacity:              appointment.Capacity,
+		SeatsTaken:            appointment.SeatsTaken,
+		PreviousAppointmentID: appointment.PreviousAppointmentID,
+		CreatedAt:             appointment.CreatedAt,
+		UpdatedAt:             appointment.UpdatedAt,
 	}
 }
 
@@ -740,10 +1878,15 @@ func (s *appointmentService) exceptionToResponse(exception *models.ScheduleExcep
 		ID:              exception.ID,
 		DoctorID:        exception.DoctorID,
 		Date:            exception.Date,
+		EndDate:         exception.EndDate,
+		Recurrence:      exception.Recurrence,
 		Type:            exception.Type,
 		CustomStartTime: exception.CustomStartTime,
 		CustomEndTime:   exception.CustomEndTime,
 		Reason:          exception.Reason,
+		BusyStart:       exception.BusyStart,
+		BusyEnd:         exception.BusyEnd,
+		Source:          exception.Source,
 		CreatedAt:       exception.CreatedAt,
 	}
 }
@@ -789,6 +1932,24 @@ func (s *appointmentService) UpdateSchedule(doctorID, scheduleID uuid.UUID, req
 	if req.AppointmentFormat != nil {
 		schedule.AppointmentFormat = *req.AppointmentFormat
 	}
+	if req.GenerateDaysAhead != nil {
+		schedule.GenerateDaysAhead = *req.GenerateDaysAhead
+	}
+	// Новое число мест действует для слотов, сгенерированных после изменения
+	if req.SlotCapacity != nil {
+		schedule.SlotCapacity = *req.SlotCapacity
+		if schedule.SlotCapacity > 1 {
+			if err := s.checkGroupPricing(doctorID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.Timezone != nil {
+		schedule.Timezone = *req.Timezone
+		if _, err := schedule.Location(); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, schedule.Timezone)
+		}
+	}
 
 	// Проверяем изменение типа записи для обновления слотов
 	appointmentFormatChanged := req.AppointmentFormat != nil && originalSchedule.AppointmentFormat != schedule.AppointmentFormat
@@ -797,16 +1958,18 @@ func (s *appointmentService) UpdateSchedule(doctorID, scheduleID uuid.UUID, req
 	if schedule.IsActive {
 		timeChanged := originalSchedule.StartTime != schedule.StartTime || originalSchedule.EndTime != schedule.EndTime
 		daysChanged := !s.workDaysEqual(originalSchedule.WorkDays(), schedule.WorkDays())
+		timezoneChanged := originalSchedule.Timezone != schedule.Timezone
 
-		if timeChanged || daysChanged {
+		if timeChanged || daysChanged || timezoneChanged {
 			s.logInfo("Checking conflicts after schedule update", map[string]interface{}{
-				"doctorID":    doctorID.String(),
-				"scheduleID":  scheduleID.String(),
-				"timeChanged": timeChanged,
-				"daysChanged": daysChanged,
+				"doctorID":        doctorID.String(),
+				"scheduleID":      scheduleID.String(),
+				"timeChanged":     timeChanged,
+				"daysChanged":     daysChanged,
+				"timezoneChanged": timezoneChanged,
 			})
 
-			if err := s.checkScheduleConflictsForExisting(doctorID, schedule); err != nil {
+			if err := s.checkScheduleConflicts(doctorID, schedule); err != nil {
 				s.logError("Cannot update schedule due to conflicts", map[string]interface{}{
 					"doctorID":   doctorID.String(),
 					"scheduleID": scheduleID.String(),
@@ -959,22 +2122,31 @@ func (s *appointmentService) ToggleSchedule(doctorID, scheduleID uuid.UUID, req
 		"actionType":        actionType,
 	})
 
-	// Если активируем расписание - деактивируем все остальные
+	// При активации расписание не должно пересекаться с другими активными,
+	// а в режиме exclusive все остальные расписания деактивируются
 	if targetIsActive && !schedule.IsActive {
-		s.logInfo("Activating schedule - deactivating all other schedules", map[string]interface{}{
-			"doctorID":   doctorID.String(),
-			"scheduleID": scheduleID.String(),
-			"name":       schedule.Name,
-		})
+		if req.Exclusive {
+			s.logInfo("Activating schedule - deactivating all other schedules", map[string]interface{}{
+				"doctorID":   doctorID.String(),
+				"scheduleID": scheduleID.String(),
+				"name":       schedule.Name,
+			})
 
-		// Деактивируем все другие активные расписания
-		if err := s.deactivateOtherSchedules(doctorID, scheduleID); err != nil {
-			s.logError("Failed to deactivate other schedules", map[string]interface{}{
+			if err := s.deactivateOtherSchedules(doctorID, scheduleID); err != nil {
+				s.logError("Failed to deactivate other schedules", map[string]interface{}{
+					"doctorID":   doctorID.String(),
+					"scheduleID": scheduleID.String(),
+					"error":      err.Error(),
+				})
+				return nil, fmt.Errorf("failed to deactivate other schedules: %w", err)
+			}
+		} else if err := s.checkScheduleConflicts(doctorID, schedule); err != nil {
+			s.logError("Cannot activate schedule due to conflicts", map[string]interface{}{
 				"doctorID":   doctorID.String(),
 				"scheduleID": scheduleID.String(),
 				"error":      err.Error(),
 			})
-			return nil, fmt.Errorf("failed to deactivate other schedules: %w", err)
+			return nil, err
 		}
 	}
 
@@ -1000,44 +2172,6 @@ func (s *appointmentService) ToggleSchedule(doctorID, scheduleID uuid.UUID, req
 	return s.scheduleToResponse(schedule), nil
 }
 
-// checkScheduleConflictsForExisting проверяет конфликты для существующего расписания
-func (s *appointmentService) checkScheduleConflictsForExisting(doctorID uuid.UUID, schedule *models.DoctorSchedule) error {
-	existingSchedules, err := s.repo.GetDoctorSchedules(doctorID)
-	if err != nil {
-		return fmt.Errorf("failed to get existing schedules: %w", err)
-	}
-
-	startTime, err := time.Parse("15:04", schedule.StartTime)
-	if err != nil {
-		return fmt.Errorf("invalid start time format: %w", err)
-	}
-
-	endTime, err := time.Parse("15:04", schedule.EndTime)
-	if err != nil {
-		return fmt.Errorf("invalid end time format: %w", err)
-	}
-
-	for _, existing := range existingSchedules {
-		// Пропускаем само расписание и неактивные расписания
-		if existing.ID == schedule.ID || !existing.IsActive {
-			continue
-		}
-
-		existingStart, _ := time.Parse("15:04", existing.StartTime)
-		existingEnd, _ := time.Parse("15:04", existing.EndTime)
-
-		// Проверяем пересечение рабочих дней
-		if s.hasWorkDayConflict(schedule.WorkDays(), existing.WorkDays()) {
-			// Проверяем пересечение времени
-			if s.hasTimeConflict(startTime, endTime, existingStart, existingEnd) {
-				return fmt.Errorf("schedule conflicts with active schedule '%s' on overlapping work days and times", existing.Name)
-			}
-		}
-	}
-
-	return nil
-}
-
 // New method for forcing clean slots of schedule
 func (s *appointmentService) DeleteScheduleSlots(doctorID, scheduleID uuid.UUID) error {
 	schedule, err := s.repo.GetScheduleByID(scheduleID)
@@ -1142,13 +2276,15 @@ func (s *appointmentService) GetGeneratedSlots(doctorID, scheduleID uuid.UUID, s
 			PatientID:       appointment.PatientID,
 			PatientNotes:    appointment.PatientNotes,
 			BookedAt:        bookedAt,
+			Capacity:        appointment.Capacity,
+			SeatsTaken:      appointment.SeatsTaken,
 		}
 
 		// Подсчет статистики
 		switch appointment.Status {
 		case "available":
 			availableCount++
-		case "booked":
+		case "booked", models.StatusCheckedIn, models.StatusInProgress:
 			bookedCount++
 		case "canceled":
 			canceledCount++
@@ -1224,7 +2360,7 @@ func (s *appointmentService) GetPatientAppointmentByID(patientID, appointmentID
 		return nil, fmt.Errorf("appointment not found: %w", err)
 	}
 
-	if appointment.PatientID == nil || *appointment.PatientID != patientID {
+	if !s.isAppointmentPatient(appointment, patientID) {
 		return nil, errors.New("appointment doesn't belong to this patient")
 	}
 
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+	"github.com/printprince/vitalem/logger_service/pkg/logger"
+)
+
+// SlotGenerationService - фоновое поддержание слотов активных расписаний на N дней вперед
+type SlotGenerationService struct {
+	repo     repository.AppointmentRepository
+	service  AppointmentService
+	logger   *logger.Client
+	interval time.Duration
+}
+
+// SlotGenerationReport - итоги одного прохода автогенерации
+type SlotGenerationReport struct {
+	SchedulesProcessed int `json:"schedules_processed"`
+	DaysProcessed      int `json:"days_processed"`
+	DaysFailed         int `json:"days_failed"` // дни с ошибками генерации; повторяются следующим проходом
+	SlotsCreated       int `json:"slots_created"`
+}
+
+// NewSlotGenerationService - создание планировщика автогенерации слотов
+func NewSlotGenerationService(repo repository.AppointmentRepository, appointmentService AppointmentService, loggerClient *logger.Client, interval time.Duration) *SlotGenerationService {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &SlotGenerationService{
+		repo:     repo,
+		service:  appointmentService,
+		logger:   loggerClient,
+		interval: interval,
+	}
+}
+
+// Start - запускает планировщик, работает до отмены контекста
+func (g *SlotGenerationService) Start(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	g.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Run(ctx)
+		}
+	}
+}
+
+// Run - догенерирует слоты всех расписаний с включенной автогенерацией
+func (g *SlotGenerationService) Run(ctx context.Context) *SlotGenerationReport {
+	report := &SlotGenerationReport{}
+
+	schedules, err := g.repo.GetAutoGenerateSchedules()
+	if err != nil {
+		g.logError("Failed to get schedules for slot generation", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return report
+	}
+
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			break
+		}
+		g.generateForSchedule(ctx, schedule, report)
+		report.SchedulesProcessed++
+	}
+
+	g.logInfo("Automatic slot generation finished", map[string]interface{}{
+		"schedulesProcessed": report.SchedulesProcessed,
+		"daysProcessed":      report.DaysProcessed,
+		"daysFailed":         report.DaysFailed,
+		"slotsCreated":       report.SlotsCreated,
+	})
+
+	return report
+}
+
+// generateForSchedule - генерирует слоты расписания по одному дню от последнего обработанного до горизонта.
+// Сначала день генерируется, затем generated_until условно сдвигается на него: после сбоя между
+// этими шагами день просто сгенерируется еще раз, а уже созданные слоты будут пропущены (SkipConflicts).
+// Если отметку сдвинула другая реплика, расписание оставляется ей. Если генерация дня не удалась,
+// расписание ждет следующего прохода: generated_until не может перескочить несгенерированный день.
+// Генерация идет через GenerateSlots, так что исключения расписания работают так же, как при ручном вызове
+func (g *SlotGenerationService) generateForSchedule(ctx context.Context, schedule *models.DoctorSchedule, report *SlotGenerationReport) {
+	location, err := schedule.Location()
+	if err != nil {
+		g.logError("Invalid schedule timezone, automatic generation skipped", map[string]interface{}{
+			"scheduleID": schedule.ID.String(),
+			"timezone":   schedule.Timezone,
+			"error":      err.Error(),
+		})
+		return
+	}
+
+	// Даты считаем по местному календарю врача
+	localNow := time.Now().In(location)
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.UTC)
+	horizon := today.AddDate(0, 0, schedule.GenerateDaysAhead)
+
+	day := today
+	if schedule.GeneratedUntil != nil && !schedule.GeneratedUntil.Before(today) {
+		day = schedule.GeneratedUntil.AddDate(0, 0, 1)
+	}
+
+	previous := schedule.GeneratedUntil
+	created := 0
+
+	for ; !day.After(horizon); day = day.AddDate(0, 0, 1) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		date := day.Format("2006-01-02")
+		result, err := g.service.GenerateSlots(schedule.DoctorID, schedule.ID, &models.GenerateSlotsRequest{
+			StartDate:     date,
+			EndDate:       date,
+			SkipConflicts: true,
+		})
+		report.DaysProcessed++
+		if err != nil {
+			report.DaysFailed++
+			g.logError("Slot generation failed, day will be retried", map[string]interface{}{
+				"scheduleID": schedule.ID.String(),
+				"date":       date,
+				"error":      err.Error(),
+			})
+			break
+		}
+		created += result.SlotsCreated
+
+		advanced, err := g.repo.AdvanceScheduleGeneratedUntil(schedule.ID, previous, day)
+		if err != nil {
+			g.logError("Failed to advance generated_until after slot generation", map[string]interface{}{
+				"scheduleID": schedule.ID.String(),
+				"date":       date,
+				"error":      err.Error(),
+			})
+			break
+		}
+		if !advanced {
+			// Расписание обрабатывает другая реплика
+			break
+		}
+		generatedDay := day
+		previous = &generatedDay
+	}
+
+	report.SlotsCreated += created
+	if created > 0 {
+		g.logInfo("Slots generated automatically", map[string]interface{}{
+			"doctorID":     schedule.DoctorID.String(),
+			"scheduleID":   schedule.ID.String(),
+			"slotsCreated": created,
+			"horizon":      horizon.Format("2006-01-02"),
+		})
+	}
+}
+
+func (g *SlotGenerationService) logInfo(message string, metadata map[string]interface{}) {
+	if g.logger != nil {
+		g.logger.Info(message, metadata)
+	}
+}
+
+func (g *SlotGenerationService) logError(message string, metadata map[string]interface{}) {
+	if g.logger != nil {
+		g.logger.Error(message, metadata)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// generationRepository - отметка автогенерации одного расписания в памяти
+type generationRepository struct {
+	repository.AppointmentRepository
+
+	generatedUntil *time.Time
+}
+
+func (r *generationRepository) AdvanceScheduleGeneratedUntil(scheduleID uuid.UUID, previous *time.Time, next time.Time) (bool, error) {
+	if (previous == nil) != (r.generatedUntil == nil) || (previous != nil && !previous.Equal(*r.generatedUntil)) {
+		return false, nil
+	}
+	r.generatedUntil = &next
+	return true, nil
+}
+
+// failingGenerator - генерация, которая падает на заданный день
+type failingGenerator struct {
+	AppointmentService
+
+	failOn    string
+	generated []string
+}
+
+func (g *failingGenerator) GenerateSlots(doctorID, scheduleID uuid.UUID, req *models.GenerateSlotsRequest) (*models.GenerateSlotsResponse, error) {
+	if req.StartDate == g.failOn {
+		return nil, errors.New("database unavailable")
+	}
+	g.generated = append(g.generated, req.StartDate)
+	return &models.GenerateSlotsResponse{SlotsCreated: 1}, nil
+}
+
+func TestGenerateForScheduleAdvancesOnlyPastGeneratedDays(t *testing.T) {
+	today := time.Now().UTC()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	failDay := today.AddDate(0, 0, 2)
+
+	repo := &generationRepository{}
+	generator := &failingGenerator{failOn: failDay.Format("2006-01-02")}
+	schedule := &models.DoctorSchedule{ID: uuid.New(), DoctorID: uuid.New(), GenerateDaysAhead: 5}
+
+	worker := NewSlotGenerationService(repo, generator, nil, time.Hour)
+	result := &SlotGenerationReport{}
+	worker.generateForSchedule(context.Background(), schedule, result)
+
+	if result.DaysFailed != 1 || len(generator.generated) != 2 {
+		t.Fatalf("expected 2 generated days and 1 failure, got %+v (generated %v)", result, generator.generated)
+	}
+	if repo.generatedUntil == nil || !repo.generatedUntil.Equal(failDay.AddDate(0, 0, -1)) {
+		t.Fatalf("generated_until must stop before the failed day %s, got %v", failDay.Format("2006-01-02"), repo.generatedUntil)
+	}
+
+	// Следующий проход продолжает с несгенерированного дня
+	generator.failOn = ""
+	schedule.GeneratedUntil = repo.generatedUntil
+	worker.generateForSchedule(context.Background(), schedule, result)
+	if !repo.generatedUntil.Equal(today.AddDate(0, 0, 5)) {
+		t.Fatalf("expected generation up to the horizon, got %v", repo.generatedUntil)
+	}
+	if generator.generated[2] != failDay.Format("2006-01-02") {
+		t.Fatalf("expected the failed day to be retried first, got %v", generator.generated)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// === STATUS TRANSITIONS ===
+
+// defaultCheckInWindow - за сколько до начала приема можно отметить приход, если в настройках не задано
+const defaultCheckInWindow = 30 * time.Minute
+
+// checkInWindow - за сколько до начала приема врач может отметить приход пациента
+func (s *appointmentService) checkInWindow() time.Duration {
+	if s.options.CheckInWindow <= 0 {
+		return defaultCheckInWindow
+	}
+	return s.options.CheckInWindow
+}
+
+// CheckInAppointment - врач отмечает, что пациент пришел (подключился). Отметка ставится не раньше
+// CheckInWindow до начала приема; после окончания приема - уже нет: прием завершается или отмечается неявка
+func (s *appointmentService) CheckInAppointment(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error) {
+	appointment, err := s.getDoctorAppointment(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if opensAt := appointment.StartTime.Add(-s.checkInWindow()); now.Before(opensAt) {
+		return nil, fmt.Errorf("%w: check-in opens at %s", ErrAppointmentNotStarted, opensAt.Format("2006-01-02 15:04"))
+	}
+	if !appointment.EndTime.After(now) {
+		return nil, fmt.Errorf("%w: appointment ended at %s", ErrInvalidStatus, appointment.EndTime.Format("2006-01-02 15:04"))
+	}
+
+	if err := appointment.CheckIn(doctorID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+
+	return s.saveStatusChange(appointment)
+}
+
+// StartAppointment - врач начинает прием
+func (s *appointmentService) StartAppointment(doctorID, appointmentID uuid.UUID) (*models.AppointmentResponse, error) {
+	appointment, err := s.getDoctorAppointment(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appointment.Start(doctorID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+
+	return s.saveStatusChange(appointment)
+}
+
+// MarkNoShow - врач отмечает неявку пациента; возможно только после начала приема
+func (s *appointmentService) MarkNoShow(doctorID, appointmentID uuid.UUID, req *models.MarkNoShowRequest) (*models.AppointmentResponse, error) {
+	appointment, err := s.getDoctorAppointment(doctorID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.StartTime.After(time.Now()) {
+		return nil, fmt.Errorf("%w: appointment starts at %s", ErrAppointmentNotStarted, appointment.StartTime.Format("2006-01-02 15:04"))
+	}
+
+	if err := appointment.MarkNoShow(doctorID, req.Reason); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+
+	return s.saveStatusChange(appointment, "meeting_id", "meeting_link")
+}
+
+// GetAppointmentStatusHistory - журнал статусов записи; доступен врачу и пациенту записи
+func (s *appointmentService) GetAppointmentStatusHistory(userID uuid.UUID, role string, appointmentID uuid.UUID) ([]*models.AppointmentStatusHistory, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	isDoctor := role == "doctor" && appointment.DoctorID == userID
+	isPatient := role == "patient" && s.isAppointmentPatient(appointment, userID)
+	if !isDoctor && !isPatient {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this user", ErrForbidden)
+	}
+
+	history, err := s.repo.GetAppointmentStatusHistory(appointmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+
+	return history, nil
+}
+
+func (s *appointmentService) getDoctorAppointment(doctorID, appointmentID uuid.UUID) (*models.Appointment, error) {
+	appointment, err := s.getAppointment(appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if appointment.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: appointment doesn't belong to this doctor", ErrForbidden)
+	}
+
+	return appointment, nil
+}
+
+// saveStatusChange - сохраняет переход статуса; параллельное изменение записи - конфликт
+func (s *appointmentService) saveStatusChange(appointment *models.Appointment, columns ...string) (*models.AppointmentResponse, error) {
+	previous := appointment.PersistedStatus()
+
+	if err := s.repo.ChangeStatus(appointment, columns...); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, fmt.Errorf("%w: appointment was changed concurrently", ErrInvalidStatus)
+		}
+		return nil, fmt.Errorf("failed to update appointment status: %w", err)
+	}
+
+	s.logInfo("Appointment status changed", map[string]interface{}{
+		"doctorID":      appointment.DoctorID.String(),
+		"appointmentID": appointment.ID.String(),
+		"from":          previous,
+		"to":            appointment.Status,
+	})
+
+	return s.appointmentToResponse(appointment), nil
+}
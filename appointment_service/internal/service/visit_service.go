@@ -0,0 +1,486 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/printprince/vitalem/appointment_service/internal/models"
+	"github.com/printprince/vitalem/appointment_service/internal/repository"
+)
+
+// visitSlotStep - шаг, с которым из свободного времени нарезаются варианты начала приема
+const visitSlotStep = 15 * time.Minute
+
+// workWindow - интервал рабочего времени врача по одному из активных расписаний
+type workWindow struct {
+	start      time.Time
+	end        time.Time
+	format     string
+	scheduleID uuid.UUID
+}
+
+// === КАТАЛОГ ВИДОВ ПРИЕМА ===
+
+func (s *appointmentService) CreateVisitType(doctorID uuid.UUID, req *models.CreateVisitTypeRequest) (*models.VisitTypeResponse, error) {
+	visitType := &models.VisitType{
+		DoctorID: doctorID,
+		Title:    req.Title,
+		Duration: req.Duration,
+		Price:    req.Price,
+		Format:   req.Format,
+		IsActive: true,
+	}
+
+	if err := s.repo.CreateVisitType(visitType); err != nil {
+		s.logError("Failed to create visit type", map[string]interface{}{
+			"doctorID": doctorID.String(),
+			"error":    err.Error(),
+		})
+		return nil, fmt.Errorf("failed to create visit type: %w", err)
+	}
+
+	s.logInfo("Visit type created", map[string]interface{}{
+		"doctorID":    doctorID.String(),
+		"visitTypeID": visitType.ID.String(),
+		"title":       visitType.Title,
+		"duration":    visitType.Duration,
+	})
+
+	return visitTypeToResponse(visitType), nil
+}
+
+// GetVisitTypes - каталог видов приема врача; пациентам показываются только доступные для записи
+func (s *appointmentService) GetVisitTypes(doctorID uuid.UUID, activeOnly bool) ([]*models.VisitTypeResponse, error) {
+	visitTypes, err := s.repo.GetDoctorVisitTypes(doctorID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get visit types: %w", err)
+	}
+
+	responses := make([]*models.VisitTypeResponse, len(visitTypes))
+	for i, visitType := range visitTypes {
+		responses[i] = visitTypeToResponse(visitType)
+	}
+	return responses, nil
+}
+
+func (s *appointmentService) UpdateVisitType(doctorID, visitTypeID uuid.UUID, req *models.UpdateVisitTypeRequest) (*models.VisitTypeResponse, error) {
+	visitType, err := s.getVisitType(doctorID, visitTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Уже созданные записи сохраняют свои время и цену - меняются только будущие
+	if req.Title != nil {
+		visitType.Title = *req.Title
+	}
+	if req.Duration != nil {
+		visitType.Duration = *req.Duration
+	}
+	if req.Price != nil {
+		visitType.Price = *req.Price
+	}
+	if req.Format != nil {
+		visitType.Format = *req.Format
+	}
+	if req.IsActive != nil {
+		visitType.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.UpdateVisitType(visitType); err != nil {
+		return nil, fmt.Errorf("failed to update visit type: %w", err)
+	}
+
+	return visitTypeToResponse(visitType), nil
+}
+
+// DeleteVisitType - удаляет вид приема из каталога; записи по нему остаются
+func (s *appointmentService) DeleteVisitType(doctorID, visitTypeID uuid.UUID) error {
+	if _, err := s.getVisitType(doctorID, visitTypeID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteVisitType(visitTypeID); err != nil {
+		return fmt.Errorf("failed to delete visit type: %w", err)
+	}
+
+	s.logInfo("Visit type deleted", map[string]interface{}{
+		"doctorID":    doctorID.String(),
+		"visitTypeID": visitTypeID.String(),
+	})
+
+	return nil
+}
+
+// getVisitType - вид приема врача doctorID
+func (s *appointmentService) getVisitType(doctorID, visitTypeID uuid.UUID) (*models.VisitType, error) {
+	visitType, err := s.repo.GetVisitTypeByID(visitTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVisitTypeNotFound, err)
+	}
+	// Чужой вид приема неотличим от несуществующего: пациент выбирает его из каталога конкретного врача
+	if visitType.DoctorID != doctorID {
+		return nil, ErrVisitTypeNotFound
+	}
+	return visitType, nil
+}
+
+// getBookableVisitType - вид приема, на который пациент может записаться
+func (s *appointmentService) getBookableVisitType(doctorID, visitTypeID uuid.UUID) (*models.VisitType, error) {
+	visitType, err := s.getVisitType(doctorID, visitTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if !visitType.IsActive {
+		return nil, ErrVisitTypeNotFound
+	}
+	return visitType, nil
+}
+
+// === ЗАПИСЬ ПО ВИДУ ПРИЕМА ===
+
+// GetVisitSlots - варианты начала приема visitTypeID на дату date (в часовом поясе зрителя),
+// нарезанные из свободного рабочего времени врача: без перерывов, выходных, занятого времени
+// из внешних календарей и уже существующих записей
+func (s *appointmentService) GetVisitSlots(doctorID, visitTypeID uuid.UUID, date, timezone string) ([]*models.VisitSlot, error) {
+	visitType, err := s.getBookableVisitType(doctorID, visitTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidInput, timezone)
+		}
+		location = loc
+	}
+
+	dayStart, err := time.ParseInLocation("2006-01-02", date, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date format: %v", ErrInvalidInput, err)
+	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	duration := visitType.DurationTime()
+	windows, err := s.workWindows(doctorID, dayStart, dayEnd.Add(duration))
+	if err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		return []*models.VisitSlot{}, nil
+	}
+
+	// Вокруг существующих записей оставляем перерыв из правил записи врача
+	policy, err := s.bookingPolicy(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	buffer := policy.Buffer()
+
+	occupied, err := s.repo.GetDoctorOccupiedTime(doctorID, dayStart.Add(-buffer), dayEnd.Add(duration+buffer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor appointments: %w", err)
+	}
+	busy, err := s.repo.GetDoctorBusyTime(doctorID, dayStart, dayEnd.Add(duration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor busy time: %w", err)
+	}
+
+	now := time.Now()
+	seen := make(map[int64]bool)
+	slots := []*models.VisitSlot{}
+	for _, window := range windows {
+		format, ok := visitFormat(window.format, visitType.Format)
+		if !ok {
+			continue
+		}
+
+		for start := window.start; !start.Add(duration).After(window.end); start = start.Add(visitSlotStep) {
+			end := start.Add(duration)
+			if start.Before(dayStart) || !start.Before(dayEnd) || !start.After(now) || seen[start.Unix()] {
+				continue
+			}
+			if checkBookingTime(policy, start, now) != nil {
+				continue
+			}
+			if overlapsBusyTime(busy, start, end) || overlapsAppointments(occupied, start.Add(-buffer), end.Add(buffer)) {
+				continue
+			}
+
+			seen[start.Unix()] = true
+			slots = append(slots, &models.VisitSlot{
+				StartTime:       start.In(location),
+				EndTime:         end.In(location),
+				AppointmentType: format,
+			})
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].StartTime.Before(slots[j].StartTime)
+	})
+
+	return slots, nil
+}
+
+// BookVisit - записывает пациента на прием по виду приема, вырезая его время из свободного времени врача.
+// Платный вид приема сначала удерживается за пациентом до оплаты, как и платный слот
+func (s *appointmentService) BookVisit(patientID, doctorID uuid.UUID, req *models.BookVisitRequest) (*models.AppointmentResponse, error) {
+	visitType, err := s.getBookableVisitType(doctorID, req.VisitTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := req.StartTime.UTC().Truncate(time.Minute)
+	end := start.Add(visitType.DurationTime())
+	if !start.After(time.Now()) {
+		return nil, fmt.Errorf("%w: visit start time must be in the future", ErrInvalidInput)
+	}
+
+	// Прием должен целиком помещаться в рабочее время одного из расписаний
+	windows, err := s.workWindows(doctorID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var window *workWindow
+	var format string
+	for i := range windows {
+		if start.Before(windows[i].start) || end.After(windows[i].end) {
+			continue
+		}
+		if f, ok := visitFormat(windows[i].format, visitType.Format); ok {
+			window, format = &windows[i], f
+			break
+		}
+	}
+	if window == nil {
+		return nil, fmt.Errorf("%w: requested time is outside the doctor's working hours for this visit type", ErrInvalidInput)
+	}
+
+	appointmentType := req.AppointmentType
+	if appointmentType == "" {
+		appointmentType = format
+		if format == "both" {
+			appointmentType = "offline" // По умолчанию для "both" выбираем offline
+		}
+	}
+	if !s.isAppointmentTypeCompatible(format, appointmentType) {
+		return nil, fmt.Errorf("%w: appointment type '%s' is not compatible with visit format '%s'", ErrInvalidInput, appointmentType, format)
+	}
+
+	limits, err := s.enforceBookingPolicy(doctorID, patientID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	appointment := &models.Appointment{
+		ID:              uuid.New(),
+		StartTime:       start,
+		EndTime:         end,
+		DoctorID:        doctorID,
+		Title:           visitType.Title,
+		Status:          models.StatusAvailable,
+		AppointmentType: format,
+		ScheduleID:      &window.scheduleID,
+		VisitTypeID:     &visitType.ID,
+	}
+
+	paid := visitType.Price > 0 && s.options.Payments != nil
+	heldUntil := s.holdDeadline()
+	if paid {
+		err = appointment.Hold(patientID, appointmentType, req.PatientNotes, visitType.Price, heldUntil)
+	} else {
+		err = appointment.Book(patientID, appointmentType, req.PatientNotes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+	}
+	if !paid {
+		s.assignMeeting(appointment)
+	}
+
+	if err := s.repo.CreateVisit(appointment, limits); err != nil {
+		if policyErr := bookingLimitsError(limits, err); policyErr != nil {
+			return nil, policyErr
+		}
+		if errors.Is(err, repository.ErrSlotTaken) {
+			s.logInfo("Visit time already taken", map[string]interface{}{
+				"patientID": patientID.String(),
+				"doctorID":  doctorID.String(),
+				"startTime": start.Format(time.RFC3339),
+			})
+			return nil, ErrSlotTaken
+		}
+		return nil, fmt.Errorf("failed to book visit: %w", err)
+	}
+
+	s.logInfo("Visit booked", map[string]interface{}{
+		"patientID":     patientID.String(),
+		"doctorID":      doctorID.String(),
+		"appointmentID": appointment.ID.String(),
+		"visitTypeID":   visitType.ID.String(),
+		"status":        appointment.Status,
+	})
+
+	if paid {
+		return s.requestPayment(patientID, appointment, visitType.Price, heldUntil)
+	}
+
+	s.publishEvent(models.EventAppointmentBooked, appointment, appointment.PatientID)
+
+	return s.appointmentToResponse(appointment), nil
+}
+
+// workWindows - рабочие интервалы активных расписаний врача, пересекающие [from, to).
+// Интервалы не обрезаются по границам, чтобы варианты начала приема отсчитывались от начала работы
+func (s *appointmentService) workWindows(doctorID uuid.UUID, from, to time.Time) ([]workWindow, error) {
+	schedules, err := s.repo.GetDoctorSchedules(doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	// Местная дата врача может отличаться от даты UTC на сутки в обе стороны
+	exceptions, err := s.repo.GetDoctorExceptions(doctorID, from.UTC().AddDate(0, 0, -1), to.UTC().AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exceptions: %w", err)
+	}
+
+	var windows []workWindow
+	for _, schedule := range schedules {
+		if !schedule.IsActive {
+			continue
+		}
+		location, err := schedule.Location()
+		if err != nil {
+			continue
+		}
+
+		localFrom, localTo := from.In(location), to.In(location)
+		firstDay := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day(), 0, 0, 0, 0, time.UTC)
+		lastDay := time.Date(localTo.Year(), localTo.Month(), localTo.Day(), 0, 0, 0, 0, time.UTC)
+
+		for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+			for _, window := range scheduleDayWindows(schedule, exceptionForDay(exceptions, day), day, location) {
+				if window.start.Before(to) && window.end.After(from) {
+					windows = append(windows, window)
+				}
+			}
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].start.Before(windows[j].start)
+	})
+
+	return windows, nil
+}
+
+// scheduleDayWindows - рабочее время расписания в местный день day с учетом исключения и перерыва
+func scheduleDayWindows(schedule *models.DoctorSchedule, exception *models.ScheduleException, day time.Time, location *time.Location) []workWindow {
+	startTime, endTime := schedule.StartTime, schedule.EndTime
+	breakStart, breakEnd := schedule.BreakStart, schedule.BreakEnd
+
+	switch {
+	case exception != nil && exception.Type == "day_off":
+		return nil
+	case exception != nil && exception.Type == "custom_hours" && exception.CustomStartTime != nil && exception.CustomEndTime != nil:
+		// Измененные часы заменяют обычное расписание дня вместе с перерывом
+		startTime, endTime = *exception.CustomStartTime, *exception.CustomEndTime
+		breakStart, breakEnd = nil, nil
+	default:
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7 // Воскресенье = 7
+		}
+		isWorkDay := false
+		for _, workDay := range schedule.WorkDays() {
+			if workDay == weekday {
+				isWorkDay = true
+				break
+			}
+		}
+		if !isWorkDay {
+			return nil
+		}
+	}
+
+	at := func(clock string) (time.Time, bool) {
+		t, err := time.ParseInLocation("2006-01-02 15:04", day.Format("2006-01-02")+" "+clock, location)
+		return t.UTC(), err == nil
+	}
+
+	start, ok := at(startTime)
+	if !ok {
+		return nil
+	}
+	end, ok := at(endTime)
+	if !ok || !start.Before(end) {
+		return nil
+	}
+
+	window := workWindow{start: start, end: end, format: schedule.AppointmentFormat, scheduleID: schedule.ID}
+	if breakStart == nil || breakEnd == nil {
+		return []workWindow{window}
+	}
+
+	bStart, okStart := at(*breakStart)
+	bEnd, okEnd := at(*breakEnd)
+	if !okStart || !okEnd || !bStart.Before(bEnd) || !bStart.Before(end) || !bEnd.After(start) {
+		return []workWindow{window}
+	}
+
+	var windows []workWindow
+	if start.Before(bStart) {
+		before := window
+		before.end = bStart
+		windows = append(windows, before)
+	}
+	if bEnd.Before(end) {
+		after := window
+		after.start = bEnd
+		windows = append(windows, after)
+	}
+	return windows
+}
+
+// visitFormat - формат приема, доступный и в расписании, и в виде приема ("both" совместим с любым)
+func visitFormat(scheduleFormat, visitTypeFormat string) (string, bool) {
+	switch {
+	case scheduleFormat == visitTypeFormat:
+		return scheduleFormat, true
+	case scheduleFormat == "both":
+		return visitTypeFormat, true
+	case visitTypeFormat == "both":
+		return scheduleFormat, true
+	default:
+		return "", false
+	}
+}
+
+// overlapsAppointments проверяет, пересекает ли интервал [start, end) одну из записей
+func overlapsAppointments(appointments []*models.Appointment, start, end time.Time) bool {
+	for _, appointment := range appointments {
+		if appointment.StartTime.Before(end) && appointment.EndTime.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+func visitTypeToResponse(visitType *models.VisitType) *models.VisitTypeResponse {
+	return &models.VisitTypeResponse{
+		ID:        visitType.ID,
+		DoctorID:  visitType.DoctorID,
+		Title:     visitType.Title,
+		Duration:  visitType.Duration,
+		Price:     visitType.Price,
+		Format:    visitType.Format,
+		IsActive:  visitType.IsActive,
+		CreatedAt: visitType.CreatedAt,
+		UpdatedAt: visitType.UpdatedAt,
+	}
+}
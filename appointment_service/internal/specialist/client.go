@@ -0,0 +1,78 @@
+// Package specialist - клиент specialist_service
+package specialist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrDoctorNotFound - у пользователя нет профиля врача
+var ErrDoctorNotFound = errors.New("doctor profile not found")
+
+// Doctor - публичный профиль врача
+type Doctor struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Roles     []string  `json:"roles"`
+	Price     float64   `json:"price"`
+}
+
+// Client - HTTP клиент specialist_service
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient - создание клиента; baseURL например http://specialist_service:8803
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// GetDoctorByUserID - профиль врача по ID пользователя (в appointment_service doctor_id - это ID пользователя)
+func (c *Client) GetDoctorByUserID(ctx context.Context, userID uuid.UUID) (*Doctor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/doctors/by-user/"+userID.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("specialist service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrDoctorNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("specialist service responded with %s", resp.Status)
+	}
+
+	var doctor Doctor
+	if err := json.NewDecoder(resp.Body).Decode(&doctor); err != nil {
+		return nil, fmt.Errorf("failed to decode doctor profile: %w", err)
+	}
+
+	return &doctor, nil
+}
+
+// DoctorPrice - цена приема врача
+func (c *Client) DoctorPrice(ctx context.Context, doctorID uuid.UUID) (float64, error) {
+	doctor, err := c.GetDoctorByUserID(ctx, doctorID)
+	if err != nil {
+		return 0, err
+	}
+	return doctor.Price, nil
+}
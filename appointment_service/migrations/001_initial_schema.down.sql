@@ -0,0 +1,15 @@
+-- Удаляет только таблицы, созданные миграцией 001. Таблицы, которые до перехода на миграции
+-- создавали database.RunMigrations или AutoMigrate, остаются вместе с данными
+DO $$
+DECLARE
+    created TEXT;
+BEGIN
+    IF to_regclass('schema_initial_tables') IS NULL THEN
+        RETURN;
+    END IF;
+    FOR created IN SELECT table_name FROM schema_initial_tables LOOP
+        EXECUTE format('DROP TABLE IF EXISTS %I', created);
+    END LOOP;
+END $$;
+
+DROP TABLE IF EXISTS schema_initial_tables;
@@ -0,0 +1,230 @@
+-- Схема appointment_service на момент перехода на версионные миграции.
+-- Все операции идемпотентны: миграция применяется и к пустой базе,
+-- и к базе, которую раньше создавал database.RunMigrations или AutoMigrate.
+--
+-- Проверка перед уникальными индексами: в старых базах их не было, поэтому дубли сначала убираются
+-- (повторные напоминания и события календаря, лишние свободные копии слотов, номера платежей у освобожденных слотов).
+-- Если на одно время врача остались две активные записи с пациентами или один платеж у двух записей,
+-- миграция откатывается с ошибкой - такие записи нужно разобрать вручную и запустить сервис снова
+
+-- Таблицы, которые создает эта миграция; уже существовавшие откат не удаляет
+CREATE TABLE IF NOT EXISTS schema_initial_tables (
+    table_name VARCHAR(64) PRIMARY KEY
+);
+INSERT INTO schema_initial_tables (table_name)
+SELECT t.table_name FROM unnest(ARRAY[
+    'doctor_schedules', 'schedule_exceptions', 'appointments', 'appointment_reminders',
+    'calendar_feed_tokens', 'appointment_status_history', 'external_calendars', 'visit_types'
+]) AS t(table_name)
+WHERE to_regclass(t.table_name) IS NULL
+ON CONFLICT (table_name) DO NOTHING;
+
+-- Расписания врачей
+CREATE TABLE IF NOT EXISTS doctor_schedules (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    work_days_json TEXT NOT NULL,
+    start_time VARCHAR(5) NOT NULL,
+    end_time VARCHAR(5) NOT NULL,
+    break_start VARCHAR(5),
+    break_end VARCHAR(5),
+    slot_duration BIGINT NOT NULL DEFAULT 30,
+    slot_title VARCHAR(255),
+    appointment_format VARCHAR(10) NOT NULL DEFAULT 'offline',
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Старые базы хранили рабочие дни в integer[] work_days - переносим в work_days_json
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'doctor_schedules' AND column_name = 'work_days') THEN
+        ALTER TABLE doctor_schedules ADD COLUMN IF NOT EXISTS work_days_json TEXT;
+        UPDATE doctor_schedules
+        SET work_days_json = COALESCE((SELECT json_agg(day)::text FROM unnest(work_days) AS day), '[]')
+        WHERE work_days_json IS NULL;
+        ALTER TABLE doctor_schedules ALTER COLUMN work_days_json SET NOT NULL;
+        ALTER TABLE doctor_schedules DROP COLUMN work_days;
+    END IF;
+END $$;
+
+-- Автоматическая генерация слотов и часовой пояс расписания (существующие расписания генерировались в UTC)
+ALTER TABLE doctor_schedules ADD COLUMN IF NOT EXISTS generate_days_ahead INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE doctor_schedules ADD COLUMN IF NOT EXISTS generated_until DATE;
+ALTER TABLE doctor_schedules ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Исключения в расписании
+CREATE TABLE IF NOT EXISTS schedule_exceptions (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    custom_start_time VARCHAR(5),
+    custom_end_time VARCHAR(5),
+    reason VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Периоды, повторяющиеся исключения и занятое время из внешних календарей
+ALTER TABLE schedule_exceptions ADD COLUMN IF NOT EXISTS end_date DATE;
+ALTER TABLE schedule_exceptions ADD COLUMN IF NOT EXISTS recurrence VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE schedule_exceptions ADD COLUMN IF NOT EXISTS busy_start TIMESTAMP WITH TIME ZONE;
+ALTER TABLE schedule_exceptions ADD COLUMN IF NOT EXISTS busy_end TIMESTAMP WITH TIME ZONE;
+ALTER TABLE schedule_exceptions ADD COLUMN IF NOT EXISTS source VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE schedule_exceptions ADD COLUMN IF NOT EXISTS external_uid VARCHAR(512) NOT NULL DEFAULT '';
+
+-- Записи и свободные слоты
+CREATE TABLE IF NOT EXISTS appointments (
+    id UUID PRIMARY KEY,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    doctor_id UUID NOT NULL,
+    patient_id UUID,
+    title VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'available',
+    appointment_type VARCHAR(10) DEFAULT 'offline',
+    meeting_link TEXT,
+    meeting_id VARCHAR(100),
+    patient_notes TEXT,
+    doctor_notes TEXT,
+    schedule_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Удержание слота до оплаты
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS held_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS payment_id VARCHAR(100);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS payment_url TEXT;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS payment_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS payment_status VARCHAR(20);
+-- Итоги приема
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS diagnosis TEXT;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS recommendations TEXT;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS follow_up_date DATE;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP WITH TIME ZONE;
+-- Отмена записи
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS canceled_by UUID;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS canceled_by_role VARCHAR(20);
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS canceled_at TIMESTAMP WITH TIME ZONE;
+-- Запись по виду приема
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS visit_type_id UUID;
+
+-- Отправленные напоминания о приемах
+CREATE TABLE IF NOT EXISTS appointment_reminders (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL,
+    patient_id UUID NOT NULL,
+    offset_minutes INTEGER NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Секретные ссылки на ICS-ленты
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+    user_id UUID PRIMARY KEY,
+    role VARCHAR(20) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Журнал переходов статуса записей
+CREATE TABLE IF NOT EXISTS appointment_status_history (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    actor_id UUID,
+    actor_role VARCHAR(20),
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Подключенные врачами внешние календари
+CREATE TABLE IF NOT EXISTS external_calendars (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Каталог видов приема
+CREATE TABLE IF NOT EXISTS visit_types (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    duration BIGINT NOT NULL,
+    price DECIMAL(10,2) NOT NULL DEFAULT 0,
+    format VARCHAR(10) NOT NULL DEFAULT 'offline',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Дубли, мешающие уникальным индексам.
+-- Напоминание уже отправлено - оставляем самую раннюю отметку
+DELETE FROM appointment_reminders r
+USING appointment_reminders d
+WHERE r.appointment_id = d.appointment_id AND r.patient_id = d.patient_id AND r.offset_minutes = d.offset_minutes
+  AND (COALESCE(r.sent_at, 'epoch'), r.id) > (COALESCE(d.sent_at, 'epoch'), d.id);
+
+-- Событие внешнего календаря импортировано дважды - оставляем последнюю версию
+DELETE FROM schedule_exceptions e
+USING schedule_exceptions d
+WHERE e.source <> '' AND e.doctor_id = d.doctor_id AND e.source = d.source AND e.external_uid = d.external_uid
+  AND (COALESCE(e.updated_at, 'epoch'), e.id) < (COALESCE(d.updated_at, 'epoch'), d.id);
+
+-- Слот сгенерирован дважды - закрываем свободные копии, запись с пациентом остается
+UPDATE appointments a
+SET status = 'canceled', cancel_reason = 'duplicate slot', canceled_at = NOW(), updated_at = NOW()
+FROM appointments d
+WHERE a.status = 'available' AND d.status <> 'canceled' AND a.id <> d.id
+  AND a.doctor_id = d.doctor_id AND a.start_time = d.start_time AND a.end_time = d.end_time
+  AND (d.status <> 'available' OR d.id < a.id);
+
+-- Освобожденный слот хранил платеж прежнего пациента
+UPDATE appointments SET payment_id = NULL
+WHERE payment_id IS NOT NULL AND patient_id IS NULL
+  AND payment_id IN (SELECT payment_id FROM appointments WHERE payment_id IS NOT NULL GROUP BY payment_id HAVING COUNT(*) > 1);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM appointments WHERE status <> 'canceled'
+               GROUP BY doctor_id, start_time, end_time HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'appointments: several active bookings share the same doctor and time; resolve them manually before migrating';
+    END IF;
+    IF EXISTS (SELECT 1 FROM appointments WHERE payment_id IS NOT NULL
+               GROUP BY payment_id HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'appointments: one payment_id belongs to several bookings; resolve them manually before migrating';
+    END IF;
+END $$;
+
+-- Индексы
+CREATE INDEX IF NOT EXISTS idx_schedules_doctor_active ON doctor_schedules(doctor_id, is_active);
+CREATE INDEX IF NOT EXISTS idx_exceptions_doctor_date ON schedule_exceptions(doctor_id, date);
+-- Повторный импорт одного календаря обновляет исключения, а не дублирует их
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exceptions_external_uid ON schedule_exceptions(doctor_id, source, external_uid) WHERE source <> '';
+
+CREATE INDEX IF NOT EXISTS idx_appointments_doctor_start_time ON appointments(doctor_id, start_time);
+CREATE INDEX IF NOT EXISTS idx_appointments_status_start_time ON appointments(status, start_time);
+CREATE INDEX IF NOT EXISTS idx_appointments_patient_start_time ON appointments(patient_id, start_time) WHERE patient_id IS NOT NULL;
+-- Слоты не дублируются; на время закрытого слота или отмененной записи можно записаться по виду приема
+DROP INDEX IF EXISTS idx_appointments_doctor_time_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_doctor_time_active ON appointments(doctor_id, start_time, end_time) WHERE status <> 'canceled';
+CREATE INDEX IF NOT EXISTS idx_appointments_held_until ON appointments(held_until) WHERE status = 'held';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_payment_id ON appointments(payment_id) WHERE payment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_appointments_visit_type ON appointments(visit_type_id) WHERE visit_type_id IS NOT NULL;
+
+-- Без уникальности возможны дубли напоминаний
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminders_unique ON appointment_reminders(appointment_id, patient_id, offset_minutes);
+CREATE INDEX IF NOT EXISTS idx_status_history_appointment ON appointment_status_history(appointment_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_visit_types_doctor ON visit_types(doctor_id, is_active);
@@ -0,0 +1,12 @@
+-- Правила записи врачей: минимальное время до приема, горизонт записи,
+-- срок отмены, лимит предстоящих записей пациента и перерыв между приемами
+CREATE TABLE IF NOT EXISTS booking_policies (
+    doctor_id UUID PRIMARY KEY,
+    min_lead_minutes INTEGER NOT NULL DEFAULT 0,
+    max_horizon_days INTEGER NOT NULL DEFAULT 0,
+    cancel_cutoff_minutes INTEGER NOT NULL DEFAULT 0,
+    max_active_bookings INTEGER NOT NULL DEFAULT 0,
+    buffer_minutes INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS appointment_participants;
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS chk_appointments_seats;
+ALTER TABLE appointments DROP COLUMN IF EXISTS seats_taken;
+ALTER TABLE appointments DROP COLUMN IF EXISTS capacity;
+ALTER TABLE doctor_schedules DROP COLUMN IF EXISTS slot_capacity;
@@ -0,0 +1,22 @@
+-- Групповые занятия: у слота несколько мест, записавшиеся пациенты хранятся отдельно
+ALTER TABLE doctor_schedules ADD COLUMN IF NOT EXISTS slot_capacity INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS capacity INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS seats_taken INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE appointments ADD CONSTRAINT chk_appointments_seats CHECK (seats_taken >= 0 AND seats_taken <= capacity);
+
+CREATE TABLE IF NOT EXISTS appointment_participants (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL,
+    patient_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'booked',
+    appointment_type VARCHAR(10) NOT NULL DEFAULT 'offline',
+    patient_notes TEXT,
+    canceled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_participants_appointment ON appointment_participants(appointment_id);
+CREATE INDEX IF NOT EXISTS idx_participants_patient ON appointment_participants(patient_id);
+-- Пациент занимает не больше одного места на занятии
+CREATE UNIQUE INDEX IF NOT EXISTS idx_participants_active ON appointment_participants(appointment_id, patient_id) WHERE status = 'booked';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS follow_up_proposals;
+DROP INDEX IF EXISTS idx_appointments_previous;
+ALTER TABLE appointments DROP COLUMN IF EXISTS previous_appointment_id;
@@ -0,0 +1,21 @@
+-- Повторные приемы: запись ссылается на прием, по итогам которого ее назначил врач
+ALTER TABLE appointments ADD COLUMN IF NOT EXISTS previous_appointment_id UUID;
+CREATE INDEX IF NOT EXISTS idx_appointments_previous ON appointments(previous_appointment_id);
+
+-- Предложения повторного приема: врач выбирает несколько своих слотов, пациент записывается на один
+CREATE TABLE IF NOT EXISTS follow_up_proposals (
+    id UUID PRIMARY KEY,
+    doctor_id UUID NOT NULL,
+    patient_id UUID NOT NULL,
+    previous_appointment_id UUID NOT NULL,
+    slot_ids_json TEXT NOT NULL,
+    appointment_type VARCHAR(10),
+    message TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    appointment_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_up_proposals_patient ON follow_up_proposals(patient_id, status);
+CREATE INDEX IF NOT EXISTS idx_follow_up_proposals_previous ON follow_up_proposals(previous_appointment_id);
@@ -0,0 +1,13 @@
+-- Файлы FileServer, прикрепленные к записи участниками приема
+CREATE TABLE IF NOT EXISTS appointment_attachments (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL,
+    file_id UUID NOT NULL,
+    attached_by UUID NOT NULL,
+    attached_by_role VARCHAR(20) NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Файл прикрепляется к записи один раз
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachments_appointment_file ON appointment_attachments(appointment_id, file_id);
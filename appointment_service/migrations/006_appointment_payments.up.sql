@@ -0,0 +1,27 @@
+-- Платежи за удержание слотов: переживают снятие удержания, чтобы позднее уведомление
+-- провайдера сопоставлялось с оплатившим пациентом, а не с тем, кто занял слот после него
+CREATE TABLE IF NOT EXISTS appointment_payments (
+    id UUID PRIMARY KEY,
+    payment_id VARCHAR(100) NOT NULL,
+    appointment_id UUID NOT NULL,
+    patient_id UUID NOT NULL,
+    amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointment_payments_payment_id ON appointment_payments(payment_id);
+CREATE INDEX IF NOT EXISTS idx_appointment_payments_appointment_id ON appointment_payments(appointment_id);
+CREATE INDEX IF NOT EXISTS idx_appointment_payments_patient_id ON appointment_payments(patient_id);
+
+-- Платежи, созданные до появления таблицы: пациент известен, пока слот за ним
+INSERT INTO appointment_payments (id, payment_id, appointment_id, patient_id, amount, status, created_at, updated_at)
+SELECT gen_random_uuid(), payment_id, id, patient_id, payment_amount, COALESCE(NULLIF(payment_status, ''), 'pending'), updated_at, updated_at
+FROM appointments
+WHERE payment_id IS NOT NULL AND patient_id IS NOT NULL
+ON CONFLICT (payment_id) DO NOTHING;
+
+-- У освобожденных слотов платеж прежнего пациента больше не висит на записи
+UPDATE appointments SET payment_id = NULL, payment_url = ''
+WHERE payment_id IS NOT NULL AND patient_id IS NULL;
@@ -0,0 +1,11 @@
+// Package migrations - версионные SQL-миграции appointment_service.
+// Файлы NNN_name.up.sql применяют изменение схемы, NNN_name.down.sql откатывают его;
+// миграции встраиваются в бинарник, поэтому он знает, до какой версии схемы доведен
+package migrations
+
+import "embed"
+
+// FS - все файлы миграций
+//
+//go:embed *.sql
+var FS embed.FS
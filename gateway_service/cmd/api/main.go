@@ -77,6 +77,12 @@ func main() {
 	publicFiles := e.Group("/public")
 	publicFiles.GET("/:id", swaggerHandlers.GetPublicFile) // Публичный доступ к файлам
 
+	// ICS-ленты записей (доступ по секретному токену в ссылке)
+	e.GET("/appointments/calendar/feeds/:token", proxyHandler.ProxyToAppointment)
+
+	// Уведомления платежного провайдера (подлинность проверяет appointment_service по подписи)
+	e.POST("/appointments/payments/webhook", proxyHandler.ProxyToAppointment)
+
 	// ===== ЗАЩИЩЕННЫЕ РОУТЫ (требуют JWT) =====
 
 	protected := e.Group("")
@@ -114,10 +120,20 @@ func main() {
 	// Appointment Service - основные операции
 	appointments := protected.Group("/appointments")
 	appointments.GET("", swaggerHandlers.GetAppointments)
+	appointments.GET("/search", swaggerHandlers.SearchAvailableSlots) // Поиск слотов по нескольким врачам
 	appointments.GET("/:id", swaggerHandlers.GetAppointment)
 	appointments.POST("/:id/book", swaggerHandlers.BookAppointment)
 	appointments.POST("/:id/cancel", swaggerHandlers.CancelAppointment)
+	appointments.POST("/:id/follow-up", swaggerHandlers.BookFollowUp)
+	appointments.POST("/:id/follow-up/proposals", swaggerHandlers.ProposeFollowUp)
+	appointments.POST("/follow-ups/:id/accept", swaggerHandlers.AcceptFollowUpProposal)
+	appointments.POST("/:id/attachments", swaggerHandlers.AttachAppointmentFile)
+	appointments.GET("/:id/attachments", swaggerHandlers.GetAppointmentAttachments)
+	appointments.DELETE("/:id/attachments/:fileId", swaggerHandlers.DetachAppointmentFile)
 	appointments.GET("/doctors/:id/available-slots", swaggerHandlers.GetAvailableSlots)
+	appointments.GET("/analytics", swaggerHandlers.GetDoctorAnalytics)
+	appointments.GET("/policy", swaggerHandlers.GetBookingPolicy)
+	appointments.PUT("/policy", swaggerHandlers.UpdateBookingPolicy)
 
 	// Appointment schedules (для врачей)
 	appointments.GET("/schedules", swaggerHandlers.GetSchedules)
@@ -167,6 +183,8 @@ func main() {
 		log.Printf("     POST /auth/login, /auth/register")
 		log.Printf("     GET  /doctors, /doctors/:id")
 		log.Printf("     GET  /public/:id")
+		log.Printf("     GET  /appointments/calendar/feeds/:token")
+		log.Printf("     POST /appointments/payments/webhook")
 		log.Printf("   Защищенные (JWT):")
 		log.Printf("     /auth/*, /patients/*, /doctors/*, /appointments/*")
 		log.Printf("     /notifications/*, /files/*, /logs/*")
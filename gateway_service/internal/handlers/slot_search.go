@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// doctorProfile - часть профиля врача из Specialist Service, нужная для поиска слотов
+type doctorProfile struct {
+	UserID     string   `json:"user_id"`
+	FirstName  string   `json:"first_name"`
+	MiddleName string   `json:"middle_name"`
+	LastName   string   `json:"last_name"`
+	Roles      []string `json:"roles"`
+}
+
+// fullName собирает ФИО врача в привычном порядке
+func (d *doctorProfile) fullName() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{d.LastName, d.FirstName, d.MiddleName} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// slotSearchResponse - постраничный ответ Appointment Service на поиск слотов
+type slotSearchResponse struct {
+	Success bool                     `json:"success"`
+	Data    []map[string]interface{} `json:"data"`
+	Error   string                   `json:"error,omitempty"`
+	Total   int64                    `json:"total"`
+	Page    int                      `json:"page"`
+	Limit   int                      `json:"limit"`
+}
+
+// SearchSlots ищет ближайшие свободные слоты сразу по нескольким врачам.
+// Специальность (specialty) переводится в список врачей через Specialist Service,
+// сам поиск выполняет Appointment Service, а найденные слоты дополняются ФИО
+// и специальностями врачей
+func (p *ProxyHandler) SearchSlots(c echo.Context) error {
+	query := c.Request().URL.Query()
+	specialty := strings.TrimSpace(query.Get("specialty"))
+	query.Del("specialty")
+
+	// Без специальности профили нужны всем врачам из выдачи - берем общий список одним запросом
+	doctors, err := p.fetchDoctors(specialty)
+	if err != nil {
+		if specialty != "" {
+			return echo.NewHTTPError(http.StatusBadGateway,
+				fmt.Sprintf("Failed to resolve specialty: %v", err))
+		}
+		// Имена врачей - лишь дополнение, поиск работает и без них
+		log.Printf("Slot search: doctor profiles unavailable: %v", err)
+	}
+
+	if specialty != "" {
+		doctorIDs := intersectDoctorIDs(doctors, query.Get("doctor_ids"))
+		if len(doctorIDs) == 0 {
+			return c.JSON(http.StatusOK, slotSearchResponse{
+				Success: true,
+				Data:    []map[string]interface{}{},
+				Page:    queryInt(query, "page", 1),
+				Limit:   queryInt(query, "limit", 20),
+			})
+		}
+		query.Set("doctor_ids", strings.Join(doctorIDs, ","))
+	}
+
+	serviceURL := p.config.GetServiceURL("appointment")
+	if serviceURL == "" {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Service appointment not configured")
+	}
+
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%s/appointments/search?%s", serviceURL, query.Encode()), nil)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("Failed to create request: %v", err))
+	}
+	req.Header.Set("Authorization", c.Request().Header.Get("Authorization"))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway,
+			fmt.Sprintf("Failed to proxy request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	var result slotSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway,
+			fmt.Sprintf("Invalid response from appointment service: %v", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return c.JSON(resp.StatusCode, result)
+	}
+
+	profiles := make(map[string]*doctorProfile, len(doctors))
+	for _, doctor := range doctors {
+		profiles[doctor.UserID] = doctor
+	}
+	for _, slot := range result.Data {
+		doctorID, _ := slot["doctor_id"].(string)
+		if doctor, ok := profiles[doctorID]; ok {
+			slot["doctor_name"] = doctor.fullName()
+			slot["doctor_specialties"] = doctor.Roles
+		}
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// fetchDoctors получает публичные профили врачей, при указанной специальности - только ее врачей
+func (p *ProxyHandler) fetchDoctors(specialty string) ([]*doctorProfile, error) {
+	serviceURL := p.config.GetServiceURL("specialist")
+	if serviceURL == "" {
+		return nil, fmt.Errorf("service specialist not configured")
+	}
+
+	target := serviceURL + "/api/doctors"
+	if specialty != "" {
+		target += "?role=" + url.QueryEscape(specialty)
+	}
+
+	resp, err := p.httpClient.Get(target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("specialist service returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Doctors []*doctorProfile `json:"doctors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode doctors: %w", err)
+	}
+
+	return body.Doctors, nil
+}
+
+// intersectDoctorIDs оставляет врачей специальности, а если клиент сам перечислил врачей - только их
+func intersectDoctorIDs(doctors []*doctorProfile, requested string) []string {
+	wanted := make(map[string]bool)
+	for _, id := range strings.Split(requested, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			wanted[strings.ToLower(id)] = true
+		}
+	}
+
+	ids := make([]string, 0, len(doctors))
+	for _, doctor := range doctors {
+		if len(wanted) > 0 && !wanted[strings.ToLower(doctor.UserID)] {
+			continue
+		}
+		ids = append(ids, doctor.UserID)
+	}
+	return ids
+}
+
+// queryInt читает целый query-параметр, подставляя значение по умолчанию
+func queryInt(query url.Values, name string, fallback int) int {
+	value, err := strconv.Atoi(query.Get(name))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
+}
@@ -342,14 +342,21 @@ func (h *SwaggerHandlers) UpdatePatientProfile(c echo.Context) error {
 
 // GetAppointments godoc
 // @Summary      Мои записи
-// @Description  Получение списка записей текущего пользователя
+// @Description  Постраничный список записей текущего пользователя с фильтрами по статусу, датам и времени
 // @Tags         Записи
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        limit   query     int     false  "Количество записей"  default(10)
-// @Param        offset  query     int     false  "Смещение"            default(0)
-// @Success      200     {object}  object{appointments=array,total=integer}  "Список записей"
+// @Param        status     query     string  false  "Статусы через запятую"  example("booked,completed")
+// @Param        date_from  query     string  false  "Прием не раньше дня, YYYY-MM-DD"
+// @Param        date_to    query     string  false  "Прием не позже дня включительно, YYYY-MM-DD"
+// @Param        when       query     string  false  "upcoming - предстоящие, past - прошедшие"
+// @Param        sort       query     string  false  "asc или desc по времени начала (для past по умолчанию desc)"
+// @Param        timezone   query     string  false  "Часовой пояс для дат (IANA), по умолчанию UTC"
+// @Param        page       query     int     false  "Страница"  default(1)
+// @Param        limit      query     int     false  "Размер страницы (до 100)"  default(20)
+// @Success      200     {object}  object{success=boolean,data=array,total=integer,page=integer,limit=integer}  "Список записей"
+// @Failure      400     {object}  object{error=string}    "Неверные параметры"
 // @Failure      401     {object}  object{error=string}    "Неавторизован"
 // @Failure      500     {object}  object{error=string}    "Внутренняя ошибка"
 // @Router       /appointments [get]
@@ -357,6 +364,84 @@ func (h *SwaggerHandlers) GetAppointments(c echo.Context) error {
 	return h.proxyHandler.ProxyToAppointment(c)
 }
 
+// SearchAvailableSlots godoc
+// @Summary      Поиск ближайших свободных слотов
+// @Description  Свободные слоты сразу по нескольким врачам или по специальности, от ближайших к поздним. Каждый слот дополнен ФИО и специальностями врача
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        specialty   query     string  false  "Специальность врача"  example("Кардиолог")
+// @Param        doctor_ids  query     string  false  "ID врачей (user_id) через запятую"
+// @Param        date_from   query     string  true   "Начало периода, YYYY-MM-DD"  example("2024-06-15")
+// @Param        date_to     query     string  true   "Конец периода включительно, YYYY-MM-DD (не больше 31 дня)"  example("2024-06-21")
+// @Param        format      query     string  false  "Формат приема: online или offline"
+// @Param        time_from   query     string  false  "Прием начинается не раньше, HH:MM"  example("09:00")
+// @Param        time_to     query     string  false  "Прием заканчивается не позже, HH:MM"  example("13:00")
+// @Param        timezone    query     string  false  "Часовой пояс пациента (IANA), по умолчанию UTC"  example("Asia/Almaty")
+// @Param        page        query     int     false  "Страница"  default(1)
+// @Param        limit       query     int     false  "Размер страницы (до 100)"  default(20)
+// @Success      200         {object}  object{success=boolean,data=array,total=integer,page=integer,limit=integer}  "Найденные слоты"
+// @Failure      400         {object}  object{error=string} "Неверные параметры"
+// @Failure      401         {object}  object{error=string} "Неавторизован"
+// @Failure      502         {object}  object{error=string} "Сервис врачей или записей недоступен"
+// @Router       /appointments/search [get]
+func (h *SwaggerHandlers) SearchAvailableSlots(c echo.Context) error {
+	return h.proxyHandler.SearchSlots(c)
+}
+
+// GetDoctorAnalytics godoc
+// @Summary      Аналитика приема врача
+// @Description  Загрузка слотов, доли отмен и неявок, онлайн/очно, самые загруженные дни недели и часы и оценка выручки за период с разбивкой по дням, неделям или месяцам. Только для врачей
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        date_from  query     string  true   "Начало периода, YYYY-MM-DD"  example("2024-06-01")
+// @Param        date_to    query     string  true   "Конец периода включительно, YYYY-MM-DD (не больше года)"  example("2024-06-30")
+// @Param        bucket     query     string  false  "Разбивка: day, week или month"  default(day)
+// @Param        timezone   query     string  false  "Часовой пояс отчета (IANA), по умолчанию UTC"  example("Asia/Almaty")
+// @Success      200        {object}  object{success=boolean,data=object}  "Аналитика за период"
+// @Failure      400        {object}  object{error=string} "Неверные параметры"
+// @Failure      401        {object}  object{error=string} "Неавторизован"
+// @Failure      403        {object}  object{error=string} "Только для врачей"
+// @Router       /appointments/analytics [get]
+func (h *SwaggerHandlers) GetDoctorAnalytics(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// GetBookingPolicy godoc
+// @Summary      Правила записи врача
+// @Description  Правила записи к текущему врачу: минимальное время до приема, горизонт записи, срок отмены, лимит предстоящих записей пациента и перерыв между приемами. 0 - правило не действует
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{success=boolean,data=object}  "Правила записи"
+// @Failure      401  {object}  object{error=string} "Неавторизован"
+// @Failure      403  {object}  object{error=string} "Только для врачей"
+// @Router       /appointments/policy [get]
+func (h *SwaggerHandlers) GetBookingPolicy(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// UpdateBookingPolicy godoc
+// @Summary      Изменить правила записи
+// @Description  Заменяет правила записи к текущему врачу. Уже сделанные записи не затрагиваются; перерыв между приемами учитывается при следующей генерации слотов
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        policy  body      object{min_lead_minutes=integer,max_horizon_days=integer,cancel_cutoff_minutes=integer,max_active_bookings=integer,buffer_minutes=integer}  true  "Правила записи"
+// @Success      200     {object}  object{success=boolean,data=object}  "Правила сохранены"
+// @Failure      400     {object}  object{error=string} "Неверные данные"
+// @Failure      401     {object}  object{error=string} "Неавторизован"
+// @Failure      403     {object}  object{error=string} "Только для врачей"
+// @Router       /appointments/policy [put]
+func (h *SwaggerHandlers) UpdateBookingPolicy(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
 // GetAvailableSlots godoc
 // @Summary      Доступные слоты врача
 // @Description  Получение доступных временных слотов для записи к врачу на конкретную дату
@@ -389,7 +474,7 @@ func (h *SwaggerHandlers) GetAvailableSlots(c echo.Context) error {
 // @Failure      400             {object}  object{error=string} "Неверные данные"
 // @Failure      401             {object}  object{error=string} "Неавторизован"
 // @Failure      403             {object}  object{error=string} "Только пациенты могут бронировать"
-// @Failure      409             {object}  object{error=string} "Слот уже занят"
+// @Failure      409             {object}  object{error=string,code=string} "Слот уже занят или нарушено правило записи врача (code: lead_time_too_short, beyond_booking_horizon, active_booking_limit_reached, buffer_conflict)"
 // @Failure      500             {object}  object{error=string} "Внутренняя ошибка"
 // @Router       /appointments/{id}/book [post]
 func (h *SwaggerHandlers) BookAppointment(c echo.Context) error {
@@ -408,12 +493,116 @@ func (h *SwaggerHandlers) BookAppointment(c echo.Context) error {
 // @Failure      401  {object}  object{error=string}    "Неавторизован"
 // @Failure      403  {object}  object{error=string}    "Нет прав на отмену"
 // @Failure      404  {object}  object{error=string}    "Запись не найдена"
+// @Failure      409  {object}  object{error=string,code=string}  "Срок отмены по правилам врача прошел (code: cancellation_cutoff_passed)"
 // @Failure      500  {object}  object{error=string}    "Внутренняя ошибка"
 // @Router       /appointments/{id}/cancel [post]
 func (h *SwaggerHandlers) CancelAppointment(c echo.Context) error {
 	return h.proxyHandler.ProxyToAppointment(c)
 }
 
+// BookFollowUp godoc
+// @Summary      Записать пациента на повторный прием
+// @Description  Врач записывает пациента прошедшего (или идущего) приема на свой свободный слот. Новая запись ссылается на прием и попадает в цепочку визитов
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path  string  true  "ID приема, по итогам которого назначается повторный"
+// @Param        followUp  body  object{slot_id=string,appointment_type=string,doctor_notes=string}  true  "Слот и формат повторного приема"
+// @Success      201       {object}  object{success=boolean,data=object}  "Пациент записан"
+// @Failure      400       {object}  object{error=string} "Неверные данные"
+// @Failure      403       {object}  object{error=string} "Прием или слот другого врача"
+// @Failure      409       {object}  object{error=string} "Слот уже занят"
+// @Router       /appointments/{id}/follow-up [post]
+func (h *SwaggerHandlers) BookFollowUp(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// ProposeFollowUp godoc
+// @Summary      Предложить слоты повторного приема
+// @Description  Врач предлагает пациенту приема до 10 своих свободных слотов; пациент выбирает один через POST /appointments/follow-ups/{id}/accept. Слоты не удерживаются
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path  string  true  "ID приема, по итогам которого назначается повторный"
+// @Param        proposal  body  object{slot_ids=[]string,appointment_type=string,message=string}  true  "Предлагаемые слоты"
+// @Success      201       {object}  object{success=boolean,data=object}  "Предложение создано"
+// @Failure      400       {object}  object{error=string} "Неверные данные"
+// @Failure      403       {object}  object{error=string} "Прием или слот другого врача"
+// @Failure      409       {object}  object{error=string} "Один из слотов уже занят"
+// @Router       /appointments/{id}/follow-up/proposals [post]
+func (h *SwaggerHandlers) ProposeFollowUp(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// AcceptFollowUpProposal godoc
+// @Summary      Записаться на предложенный повторный прием
+// @Description  Пациент выбирает слот из предложения врача. Запись идет по обычным правилам (оплата, правила записи врача)
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string  true  "ID предложения"
+// @Param        choice  body  object{slot_id=string,appointment_type=string,patient_notes=string}  true  "Выбранный слот"
+// @Success      200     {object}  object{success=boolean,data=object}  "Запись создана"
+// @Failure      400     {object}  object{error=string} "Слот не входит в предложение"
+// @Failure      403     {object}  object{error=string} "Предложение другому пациенту"
+// @Failure      409     {object}  object{error=string,code=string} "Слот занят, предложение уже принято или нарушено правило записи врача"
+// @Router       /appointments/follow-ups/{id}/accept [post]
+func (h *SwaggerHandlers) AcceptFollowUpProposal(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// AttachAppointmentFile godoc
+// @Summary      Прикрепить файл к записи
+// @Description  Врач или пациент прикрепляет к активной записи свой файл из FileServer. Другой участник может читать его, даже если файл не публичный
+// @Tags         Записи
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path  string  true  "ID записи"
+// @Param        file  body  object{file_id=string,note=string}  true  "Файл"
+// @Success      201   {object}  object{success=boolean,data=object}  "Файл прикреплен"
+// @Failure      400   {object}  object{error=string} "Неверные данные или групповое занятие"
+// @Failure      403   {object}  object{error=string} "Пользователь не участник приема"
+// @Failure      409   {object}  object{error=string} "Файл уже прикреплен или прием не активен"
+// @Router       /appointments/{id}/attachments [post]
+func (h *SwaggerHandlers) AttachAppointmentFile(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// GetAppointmentAttachments godoc
+// @Summary      Файлы записи
+// @Description  Файлы, прикрепленные к записи. Ссылка на файл другого участника содержит разрешение с ограниченным сроком и выдается, пока прием активен
+// @Tags         Записи
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "ID записи"
+// @Success      200  {object}  object{success=boolean,data=[]object}  "Список файлов"
+// @Failure      403  {object}  object{error=string} "Пользователь не участник приема"
+// @Failure      404  {object}  object{error=string} "Запись не найдена"
+// @Router       /appointments/{id}/attachments [get]
+func (h *SwaggerHandlers) GetAppointmentAttachments(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
+// DetachAppointmentFile godoc
+// @Summary      Открепить файл от записи
+// @Description  Открепить можно только свой файл и только пока прием активен. Сам файл в FileServer не удаляется
+// @Tags         Записи
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path  string  true  "ID записи"
+// @Param        fileId  path  string  true  "ID файла"
+// @Success      200     {object}  object{success=boolean,data=string}  "Файл откреплен"
+// @Failure      403     {object}  object{error=string} "Файл прикрепил другой участник"
+// @Failure      404     {object}  object{error=string} "Файл не прикреплен к записи"
+// @Router       /appointments/{id}/attachments/{fileId} [delete]
+func (h *SwaggerHandlers) DetachAppointmentFile(c echo.Context) error {
+	return h.proxyHandler.ProxyToAppointment(c)
+}
+
 // GetAppointment godoc
 // @Summary      Информация о записи
 // @Description  Получение детальной информации о конкретной записи
@@ -506,14 +695,14 @@ func (h *SwaggerHandlers) DeleteSchedule(c echo.Context) error {
 
 // GenerateSlots godoc
 // @Summary      Генерировать слоты
-// @Description  Автоматическая генерация временных слотов для расписания врача
+// @Description  Автоматическая генерация временных слотов для расписания врача. Слоты, пересекающиеся с занятым временем из внешних календарей, пропускаются и перечисляются в skipped_slots. При пересечении с существующими (неотмененными) слотами генерация отменяется целиком, а с skip_conflicts=true такие слоты тоже пропускаются
 // @Tags         Расписание
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        id         path  string  true   "ID расписания"
-// @Param        dateRange  body  object{start_date=string,end_date=string}  true  "Диапазон дат для генерации"
-// @Success      200        {object}  object{slots_created=integer,message=string}  "Слоты сгенерированы"
+// @Param        dateRange  body  object{start_date=string,end_date=string,skip_conflicts=boolean}  true  "Диапазон дат для генерации"
+// @Success      200        {object}  object{slots_created=integer,slots_skipped=integer,skipped_slots=[]object{start_time=string,end_time=string,reason=string},message=string}  "Слоты сгенерированы"
 // @Failure      400        {object}  object{error=string} "Неверные параметры"
 // @Failure      401        {object}  object{error=string} "Неавторизован"
 // @Failure      403        {object}  object{error=string} "Нет прав доступа"
@@ -37,6 +37,8 @@ func (h *DoctorHandlers) RegisterPublicRoutes(g *echo.Group) {
 	doctors := g.Group("/api/doctors")
 	doctors.GET("", h.GetAllDoctors)
 	doctors.GET("/:id", h.GetDoctorByID)
+	// Профиль врача по ID пользователя - по нему другие сервисы узнают, например, цену приема
+	doctors.GET("/by-user/:userID", h.GetDoctorByUserID)
 }
 
 // RegisterProtectedRoutes регистрирует защищенные маршруты, требующие аутентификации